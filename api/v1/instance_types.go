@@ -0,0 +1,375 @@
+/*
+Copyright 2020 Well Played Games Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ArtifactsSpec configures where the queue stores artifacts.
+type ArtifactsSpec struct {
+	// +optional
+	PublicBucket string `json:"publicBucket,omitempty"`
+	// +optional
+	PrivateBucket string `json:"privateBucket,omitempty"`
+	// SignPublicURLs signs the URLs of public artifacts.
+	// +optional
+	SignPublicURLs bool `json:"signPublicURLs,omitempty"`
+	// +optional
+	Region string `json:"region,omitempty"`
+	// AWSSecretRef references the access-key-id and secret-access-key used
+	// for artifacts and by notify to send email.
+	// +optional
+	AWSSecretRef *corev1.LocalObjectReference `json:"awsSecretRef,omitempty"`
+}
+
+// DatabaseSpec configures the Postgres database.
+type DatabaseSpec struct {
+	// Ref references the SQLInstance TaskCluster uses.
+	// +optional
+	Ref *corev1.LocalObjectReference `json:"ref,omitempty"`
+	// PostgresUserPrefix is prepended to the name of each service's
+	// Postgres user. It cannot be changed once set.
+	// +optional
+	PostgresUserPrefix string `json:"postgresUserPrefix,omitempty"`
+}
+
+// PulseSpec contains the pulse connection details.
+type PulseSpec struct {
+	// +optional
+	AdminSecretRef *corev1.LocalObjectReference `json:"adminSecretRef,omitempty"`
+	// +optional
+	Host string `json:"host,omitempty"`
+	// Vhost cannot be changed once set.
+	// +optional
+	Vhost string `json:"vhost,omitempty"`
+
+	// UseGuestCredentials connects to RabbitMQ as guest/guest when
+	// AdminSecretRef is unset. Defaults to true when AdminSecretRef is unset.
+	// +optional
+	UseGuestCredentials *bool `json:"useGuestCredentials,omitempty"`
+}
+
+// AccessTokenPolicy restricts the AccessTokens an Instance accepts.
+type AccessTokenPolicy struct {
+	// AllowedClientIDPrefixes lists the prefixes AccessToken client IDs must
+	// start with. All client IDs are allowed when empty.
+	// +optional
+	AllowedClientIDPrefixes []string `json:"allowedClientIDPrefixes,omitempty"`
+}
+
+// AuthSpec configures the auth service.
+type AuthSpec struct {
+	// +optional
+	SecretRef *corev1.LocalObjectReference `json:"secretRef,omitempty"`
+	// StaticClientsSecretRef references a Secret of JSON encoded static
+	// clients to add to those of the operator.
+	// +optional
+	StaticClientsSecretRef *corev1.LocalObjectReference `json:"staticClientsSecretRef,omitempty"`
+	// AzureSecretRef references the Azure accounts the auth service issues
+	// credentials for.
+	// +optional
+	AzureSecretRef *corev1.LocalObjectReference `json:"azureSecretRef,omitempty"`
+	// +optional
+	AzureAccountID string `json:"azureAccountId,omitempty"`
+	// AccessTokens restricts the AccessTokens this Instance accepts.
+	// +optional
+	AccessTokens AccessTokenPolicy `json:"accessTokens,omitempty"`
+}
+
+// UISpec configures the web UI.
+type UISpec struct {
+	// +optional
+	ApplicationName string `json:"applicationName,omitempty"`
+	// +optional
+	BannerMessage string `json:"bannerMessage,omitempty"`
+	// LoginStrategies lists the ways users can sign in. Only github is
+	// supported.
+	// +optional
+	LoginStrategies []string `json:"loginStrategies,omitempty"`
+	// +optional
+	AdditionalAllowedCORSOrigin string `json:"additionalAllowedCorsOrigin,omitempty"`
+}
+
+// NotifySpec configures the notify service.
+type NotifySpec struct {
+	// EmailSourceAddress is the address email is sent from.
+	// +optional
+	EmailSourceAddress string `json:"emailSourceAddress,omitempty"`
+	// +optional
+	MatrixSecretRef *corev1.LocalObjectReference `json:"matrixSecretRef,omitempty"`
+	// +optional
+	SlackSecretRef *corev1.LocalObjectReference `json:"slackSecretRef,omitempty"`
+}
+
+// GitHubSpec contains the desired GitHub integration configuration.
+type GitHubSpec struct {
+	// +optional
+	BotUsername string `json:"botUsername,omitempty"`
+	// +optional
+	SecretRef *corev1.LocalObjectReference `json:"secretRef,omitempty"`
+}
+
+// IngressController selects the ingress controller an Instance's Ingress is
+// written for.
+// +kubebuilder:validation:Enum=gce;nginx;traefik
+type IngressController string
+
+const (
+	// IngressControllerGCE uses GCE wildcard paths and a global static IP.
+	IngressControllerGCE IngressController = "gce"
+	// IngressControllerNginx uses prefix paths and long proxy timeouts for
+	// websocket subscriptions.
+	IngressControllerNginx IngressController = "nginx"
+	// IngressControllerTraefik uses prefix paths.
+	IngressControllerTraefik IngressController = "traefik"
+)
+
+// IngressSpec contains the desired ingress configuration.
+type IngressSpec struct {
+	// Controller selects the path syntax and annotations of the Ingress.
+	// Defaults to gce.
+	// +optional
+	Controller IngressController `json:"controller,omitempty"`
+	// ClassName is set as the ingressClassName of the Ingress. Defaults to
+	// the controller for nginx and traefik.
+	// +optional
+	ClassName string `json:"className,omitempty"`
+	// Annotations are added to the Ingress, overriding those of the
+	// controller.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// StaticIPName is the name of a global static IP. It is only supported
+	// by the gce controller.
+	// +optional
+	StaticIPName string `json:"staticIpName,omitempty"`
+	// +optional
+	ExternalDNSName string `json:"externalDNSName,omitempty"`
+	// +optional
+	TLSSecretRef *corev1.LocalObjectReference `json:"tlsSecretRef,omitempty"`
+	// +optional
+	IssuerRef corev1.ObjectReference `json:"issuerRef,omitempty"`
+}
+
+// WorkerManagerSpec configures the worker manager.
+type WorkerManagerSpec struct {
+	// ProvidersSecretRef references a Secret with one provider per key.
+	// +optional
+	ProvidersSecretRef *corev1.LocalObjectReference `json:"providersSecretRef,omitempty"`
+}
+
+// WebSockTunnelSpec selects the websocktunnel workers connect through.
+type WebSockTunnelSpec struct {
+	// Ref references a WebSockTunnel in the same namespace. It takes
+	// precedence over SecretRef.
+	// +optional
+	Ref *corev1.LocalObjectReference `json:"ref,omitempty"`
+	// +optional
+	SecretRef *corev1.LocalObjectReference `json:"secretRef,omitempty"`
+}
+
+// RotationPolicy describes how often a generated credential is replaced.
+type RotationPolicy struct {
+	// Interval is the maximum age of a credential before it is regenerated.
+	Interval metav1.Duration `json:"interval"`
+}
+
+// CryptoKeyRotationPolicy describes how often database crypto keys are
+// replaced and when superseded keys are retired.
+type CryptoKeyRotationPolicy struct {
+	RotationPolicy `json:",inline"`
+
+	// RetireAfter is how long a superseded key is kept for decryption. Once
+	// it has elapsed, values still encrypted with the key are re-encrypted
+	// with the active key and the key is removed. Superseded keys are kept
+	// forever when unset.
+	// +optional
+	RetireAfter *metav1.Duration `json:"retireAfter,omitempty"`
+}
+
+// RotationSpec contains the desired credential rotation policies.
+type RotationSpec struct {
+	// ServiceCredentials rotates the Postgres and Pulse passwords generated
+	// for each TaskCluster service.
+	// +optional
+	ServiceCredentials *RotationPolicy `json:"serviceCredentials,omitempty"`
+
+	// DBCryptoKeys rotates the keys used to encrypt database columns.
+	// +optional
+	DBCryptoKeys *CryptoKeyRotationPolicy `json:"dbCryptoKeys,omitempty"`
+
+	// AccessTokens rotates the static access tokens of the TaskCluster
+	// services. A rotation can also be requested by changing the
+	// taskcluster.wellplayed.games/rotate-access-tokens annotation.
+	// +optional
+	AccessTokens *RotationPolicy `json:"accessTokens,omitempty"`
+}
+
+// VaultTransitSpec contains the details of a HashiCorp Vault transit key.
+type VaultTransitSpec struct {
+	Address string `json:"address"`
+	// Mount is the path the transit secrets engine is mounted at. Defaults
+	// to transit.
+	// +optional
+	Mount          string                   `json:"mount,omitempty"`
+	KeyName        string                   `json:"keyName"`
+	TokenSecretRef corev1.SecretKeySelector `json:"tokenSecretRef"`
+}
+
+// GCPKMSSpec contains the details of a Google Cloud KMS key.
+type GCPKMSSpec struct {
+	// KeyName is the resource name of the key, of the form
+	// projects/*/locations/*/keyRings/*/cryptoKeys/*.
+	KeyName string `json:"keyName"`
+}
+
+// StateEncryptionSpec configures envelope encryption of the Secret holding
+// the operator's generated credentials. Exactly one provider should be set.
+type StateEncryptionSpec struct {
+	// SecretKeyRef references a base64 encoded 32 byte key.
+	// +optional
+	SecretKeyRef *corev1.SecretKeySelector `json:"secretKeyRef,omitempty"`
+	// +optional
+	VaultTransit *VaultTransitSpec `json:"vaultTransit,omitempty"`
+	// +optional
+	GCPKMS *GCPKMSSpec `json:"gcpKms,omitempty"`
+}
+
+// InstanceSpec defines the desired state of Instance
+type InstanceSpec struct {
+	// RootURL is the https URL TaskCluster is served on.
+	// +optional
+	RootURL string `json:"rootUrl,omitempty"`
+	// DockerImage is the TaskCluster image. It defaults to
+	// taskcluster/taskcluster, and the version the operator was built with
+	// is added when no tag is given. The version is pinned when the Instance
+	// is created, so upgrading the operator does not upgrade TaskCluster.
+	// +optional
+	DockerImage string `json:"dockerImage,omitempty"`
+
+	// +optional
+	Database DatabaseSpec `json:"database,omitempty"`
+	// +optional
+	Pulse PulseSpec `json:"pulse,omitempty"`
+	// +optional
+	Artifacts ArtifactsSpec `json:"artifacts,omitempty"`
+	// +optional
+	Auth AuthSpec `json:"auth,omitempty"`
+	// +optional
+	UI UISpec `json:"ui,omitempty"`
+	// +optional
+	Notify NotifySpec `json:"notify,omitempty"`
+	// +optional
+	GitHub GitHubSpec `json:"github,omitempty"`
+	// +optional
+	Ingress IngressSpec `json:"ingress,omitempty"`
+	// +optional
+	WorkerManager WorkerManagerSpec `json:"workerManager,omitempty"`
+	// +optional
+	WebSockTunnel WebSockTunnelSpec `json:"webSockTunnel,omitempty"`
+
+	// +optional
+	Rotation RotationSpec `json:"rotation,omitempty"`
+	// +optional
+	StateEncryption *StateEncryptionSpec `json:"stateEncryption,omitempty"`
+}
+
+// InstanceConditionType represents the type enum of a condition.
+type InstanceConditionType string
+
+const (
+	// InstanceProgressing is used when the instance is not blocked by an
+	// external dependency or reconcile error.
+	InstanceProgressing InstanceConditionType = "Progressing"
+)
+
+// InstanceCondition represents a condition of an Instance
+type InstanceCondition struct {
+	Type   InstanceConditionType  `json:"type"`
+	Status corev1.ConditionStatus `json:"status"`
+	// Last time the condition transitioned from one status to another.
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+	// Unique, this should be a short, machine understandable string that gives the reason
+	// for condition's last transition. If it reports "ResizeStarted" that means the underlying
+	// persistent volume is being resized.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// Human-readable message indicating details about last transition.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// CryptoKeyStatus reports the database crypto keys held by a service.
+type CryptoKeyStatus struct {
+	Service string `json:"service"`
+	// KeyIDs lists the keys available for decryption. The first is used to
+	// encrypt new values.
+	KeyIDs []string `json:"keyIds,omitempty"`
+}
+
+// AccessTokenRotationStatus reports the progress of a static access token
+// rotation.
+type AccessTokenRotationStatus struct {
+	// Service is the service whose access token is being replaced.
+	// +optional
+	Service string `json:"service,omitempty"`
+	// Stage is Auth while the auth service picks up the new token and
+	// Service while the service itself does.
+	// +optional
+	Stage string `json:"stage,omitempty"`
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// +optional
+	LastCompletionTime *metav1.Time `json:"lastCompletionTime,omitempty"`
+}
+
+// InstanceStatus defines the observed state of Instance
+type InstanceStatus struct {
+	Conditions          []InstanceCondition       `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+	CryptoKeys          []CryptoKeyStatus         `json:"cryptoKeys,omitempty"`
+	AccessTokenRotation AccessTokenRotationStatus `json:"accessTokenRotation,omitempty"`
+	WebSockTunnelURL    string                    `json:"webSockTunnelUrl,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:storageversion
+
+// Instance is the Schema for the instances API
+type Instance struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   InstanceSpec   `json:"spec,omitempty"`
+	Status InstanceStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// InstanceList contains a list of Instance
+type InstanceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Instance `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Instance{}, &InstanceList{})
+}
@@ -21,6 +21,92 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// AccessTokenMode selects how an AccessToken is issued.
+// +kubebuilder:validation:Enum=Static;Dynamic
+type AccessTokenMode string
+
+const (
+	// AccessTokenStatic tokens are added to the static clients of the auth
+	// service, which restarts auth whenever they change.
+	AccessTokenStatic AccessTokenMode = "Static"
+	// AccessTokenDynamic tokens are created through the auth API using the
+	// root credentials of the Instance.
+	AccessTokenDynamic AccessTokenMode = "Dynamic"
+)
+
+// AccessTokenExpiredSecretPolicy selects what happens to the Secret of an
+// expired AccessToken.
+// +kubebuilder:validation:Enum=Delete;Disable
+type AccessTokenExpiredSecretPolicy string
+
+const (
+	// AccessTokenDeleteSecret deletes the Secret.
+	AccessTokenDeleteSecret AccessTokenExpiredSecretPolicy = "Delete"
+	// AccessTokenDisableSecret keeps the Secret but removes the credentials
+	// from it.
+	AccessTokenDisableSecret AccessTokenExpiredSecretPolicy = "Disable"
+)
+
+// AccessTokenRotationSpec describes when an AccessToken is regenerated. A
+// rotation can also be requested by changing the
+// taskcluster.wellplayed.games/rotate annotation of the AccessToken.
+type AccessTokenRotationSpec struct {
+	// Interval is the maximum age of the token before it is regenerated.
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+
+	// RestartSelector selects Deployments in the AccessToken's namespace
+	// which are restarted when the token is regenerated.
+	// +optional
+	RestartSelector *metav1.LabelSelector `json:"restartSelector,omitempty"`
+	// RestartAnnotation is the pod template annotation changed to restart
+	// the selected Deployments. Defaults to
+	// taskcluster.wellplayed.games/access-token-rotated.
+	// +optional
+	RestartAnnotation string `json:"restartAnnotation,omitempty"`
+}
+
+// AccessTokenSecretKeys names the keys an AccessToken's credentials are
+// stored under.
+type AccessTokenSecretKeys struct {
+	// ClientID defaults to client-id.
+	// +optional
+	ClientID string `json:"clientID,omitempty"`
+	// AccessToken defaults to access-token.
+	// +optional
+	AccessToken string `json:"accessToken,omitempty"`
+	// RootURL defaults to root-url. It is only written when IncludeRootURL
+	// is set.
+	// +optional
+	RootURL string `json:"rootURL,omitempty"`
+	// Credentials, if set, also stores the credentials as a JSON document
+	// with clientId, accessToken and, if included, rootUrl properties.
+	// +optional
+	Credentials string `json:"credentials,omitempty"`
+}
+
+// AccessTokenSecretTemplate describes the Secret an AccessToken is written
+// to.
+type AccessTokenSecretTemplate struct {
+	// Name of the Secret. Defaults to the name of the AccessToken.
+	// +optional
+	Name string `json:"name,omitempty"`
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Type of the Secret. Defaults to Opaque.
+	// +optional
+	Type corev1.SecretType `json:"type,omitempty"`
+
+	// Keys names the keys the credentials are stored under.
+	// +optional
+	Keys AccessTokenSecretKeys `json:"keys,omitempty"`
+	// IncludeRootURL also stores the root URL of the Instance.
+	// +optional
+	IncludeRootURL bool `json:"includeRootURL,omitempty"`
+}
+
 // AccessTokenSpec defines the desired state of AccessToken
 type AccessTokenSpec struct {
 	InstanceRef corev1.ObjectReference `json:"instanceRef"`
@@ -28,16 +114,77 @@ type AccessTokenSpec struct {
 	ClientID    string   `json:"clientID"`
 	Description string   `json:"description"`
 	Scopes      []string `json:"scopes"`
+
+	// Mode selects how the token is issued. Dynamic client IDs must not
+	// start with static/. Defaults to Static.
+	// +optional
+	Mode AccessTokenMode `json:"mode,omitempty"`
+
+	// Rotation regenerates the token periodically or on request.
+	// +optional
+	Rotation *AccessTokenRotationSpec `json:"rotation,omitempty"`
+
+	// SecretTemplate describes the Secret the token is written to.
+	// +optional
+	SecretTemplate AccessTokenSecretTemplate `json:"secretTemplate,omitempty"`
+
+	// Expires is when the token stops being issued.
+	// +optional
+	Expires *metav1.Time `json:"expires,omitempty"`
+	// ExpiresAfter is used when Expires is unset, and is measured from the
+	// creation of the AccessToken.
+	// +optional
+	ExpiresAfter *metav1.Duration `json:"expiresAfter,omitempty"`
+	// ExpiredSecretPolicy selects what happens to the Secret once the token
+	// expires. Defaults to Delete.
+	// +optional
+	ExpiredSecretPolicy AccessTokenExpiredSecretPolicy `json:"expiredSecretPolicy,omitempty"`
 }
 
 // AccessTokenStatus defines the observed state of AccessToken
 type AccessTokenStatus struct {
-	Created            bool   `json:"created,omitempty"`
-	ObservedGeneration *int64 `json:"observedGeneration,omitempty"`
+	// Conditions has a Ready condition which is True once the token is
+	// usable. Static tokens only become Ready once the auth service has
+	// been rolled out with them.
+	Conditions         []SyncCondition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+	Created            bool            `json:"created,omitempty"`
+	ObservedGeneration *int64          `json:"observedGeneration,omitempty"`
+
+	// InstanceRef is the Instance which issued the token.
+	// +optional
+	InstanceRef *corev1.ObjectReference `json:"instanceRef,omitempty"`
+
+	// ClientID is the dynamic client last created in TaskCluster.
+	// +optional
+	ClientID string `json:"clientID,omitempty"`
+
+	// LastRotationTime is when the current token was issued.
+	// +optional
+	LastRotationTime *metav1.Time `json:"lastRotationTime,omitempty"`
+	// RotationRequest is the last value of the rotate annotation which was
+	// acted on.
+	// +optional
+	RotationRequest string `json:"rotationRequest,omitempty"`
+	// SecretName is the Secret the token was last written to.
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+
+	// Expires is when the token expires.
+	// +optional
+	Expires *metav1.Time `json:"expires,omitempty"`
+	// ExpiryWarningSent is set once an Event has warned that the token is
+	// about to expire.
+	// +optional
+	ExpiryWarningSent bool `json:"expiryWarningSent,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Client",type=string,JSONPath=`.spec.clientID`
+// +kubebuilder:printcolumn:name="Mode",type=string,JSONPath=`.spec.mode`
+// +kubebuilder:printcolumn:name="Expires",type=date,JSONPath=`.status.expires`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // AccessToken is the Schema for the accesstokens API
 type AccessToken struct {
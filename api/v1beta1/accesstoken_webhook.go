@@ -0,0 +1,111 @@
+/*
+Copyright 2020 Well Played Games Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"strings"
+
+	"github.com/wellplayedgames/taskcluster-operator/pkg/tcclient"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+const (
+	// StaticClientIDPrefix is reserved by the auth service for static
+	// clients.
+	StaticClientIDPrefix = "static/"
+
+	// ServiceClientIDPrefix is used by the static clients of the TaskCluster
+	// services, so may not be used by AccessTokens.
+	ServiceClientIDPrefix = StaticClientIDPrefix + "taskcluster/"
+)
+
+// SetupWebhookWithManager registers the AccessToken webhooks.
+func (r *AccessToken) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-taskcluster-wellplayed-games-v1beta1-accesstoken,mutating=false,failurePolicy=fail,groups=taskcluster.wellplayed.games,resources=accesstokens,versions=v1beta1,name=vaccesstoken.taskcluster.wellplayed.games
+
+var _ webhook.Validator = &AccessToken{}
+
+// ValidateCreate implements webhook.Validator
+func (r *AccessToken) ValidateCreate() error {
+	return r.validate()
+}
+
+// ValidateUpdate implements webhook.Validator
+func (r *AccessToken) ValidateUpdate(old runtime.Object) error {
+	return r.validate()
+}
+
+// ValidateDelete implements webhook.Validator
+func (r *AccessToken) ValidateDelete() error {
+	return nil
+}
+
+func (r *AccessToken) validate() error {
+	var errs field.ErrorList
+	spec := &r.Spec
+	path := field.NewPath("spec")
+
+	if spec.InstanceRef.Name == "" {
+		errs = append(errs, field.Required(path.Child("instanceRef", "name"), ""))
+	}
+
+	clientIDPath := path.Child("clientID")
+	static := strings.HasPrefix(spec.ClientID, StaticClientIDPrefix)
+	if err := tcclient.ValidateClientID(spec.ClientID); err != nil {
+		errs = append(errs, field.Invalid(clientIDPath, spec.ClientID, err.Error()))
+	} else if strings.HasPrefix(spec.ClientID, ServiceClientIDPrefix) {
+		errs = append(errs, field.Invalid(clientIDPath, spec.ClientID, "must not start with "+ServiceClientIDPrefix+", which is reserved for the TaskCluster services"))
+	} else if spec.Mode == AccessTokenDynamic && static {
+		errs = append(errs, field.Invalid(clientIDPath, spec.ClientID, "dynamic client IDs must not start with "+StaticClientIDPrefix))
+	} else if spec.Mode != AccessTokenDynamic && !static {
+		errs = append(errs, field.Invalid(clientIDPath, spec.ClientID, "static client IDs must start with "+StaticClientIDPrefix))
+	}
+
+	for i, scope := range spec.Scopes {
+		if err := tcclient.ValidateScope(scope); err != nil {
+			errs = append(errs, field.Invalid(path.Child("scopes").Index(i), scope, err.Error()))
+		}
+	}
+
+	if rotation := spec.Rotation; rotation != nil {
+		rotationPath := path.Child("rotation")
+		errs = append(errs, validatePositiveDuration(rotation.Interval, rotationPath.Child("interval"))...)
+		if selector := rotation.RestartSelector; selector != nil {
+			if _, err := metav1.LabelSelectorAsSelector(selector); err != nil {
+				errs = append(errs, field.Invalid(rotationPath.Child("restartSelector"), selector.String(), err.Error()))
+			}
+		}
+	}
+
+	errs = append(errs, validatePositiveDuration(spec.ExpiresAfter, path.Child("expiresAfter"))...)
+
+	if len(errs) > 0 {
+		return apierrors.NewInvalid(GroupVersion.WithKind("AccessToken").GroupKind(), r.Name, errs)
+	}
+
+	return nil
+}
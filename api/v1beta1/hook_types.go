@@ -0,0 +1,123 @@
+/*
+Copyright 2020 Well Played Games Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// HookBinding is a pulse binding which triggers a hook.
+type HookBinding struct {
+	Exchange          string `json:"exchange"`
+	RoutingKeyPattern string `json:"routingKeyPattern"`
+}
+
+// HookSpec defines the desired state of Hook
+type HookSpec struct {
+	// InstanceRef is the Instance the hook is created in. The namespace
+	// defaults to that of the Hook.
+	InstanceRef corev1.ObjectReference `json:"instanceRef"`
+
+	// +kubebuilder:validation:MinLength=1
+	HookGroupID string `json:"hookGroupId"`
+	// +kubebuilder:validation:MinLength=1
+	HookID string `json:"hookId"`
+
+	// Name is a human readable name for the hook. Defaults to the hook ID.
+	// +optional
+	Name         string `json:"name,omitempty"`
+	Description  string `json:"description,omitempty"`
+	Owner        string `json:"owner"`
+	EmailOnError bool   `json:"emailOnError,omitempty"`
+
+	// Schedule is a list of cron expressions, in UTC, at which the hook
+	// fires.
+	// +optional
+	Schedule []string `json:"schedule,omitempty"`
+	// Task is the JSON-e template of the task created when the hook fires.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Task runtime.RawExtension `json:"task"`
+	// TriggerSchema is the JSON schema trigger payloads must match. Defaults
+	// to accepting only an empty object.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +optional
+	TriggerSchema *runtime.RawExtension `json:"triggerSchema,omitempty"`
+	// Bindings are pulse messages which fire the hook.
+	// +optional
+	Bindings []HookBinding `json:"bindings,omitempty"`
+}
+
+// HookStatus defines the observed state of Hook
+type HookStatus struct {
+	Conditions         []SyncCondition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+	ObservedGeneration *int64          `json:"observedGeneration,omitempty"`
+
+	// HookGroupID and HookID identify the hook last created in TaskCluster.
+	// +optional
+	HookGroupID string `json:"hookGroupId,omitempty"`
+	// +optional
+	HookID string `json:"hookId,omitempty"`
+
+	// +optional
+	LastFireTime *metav1.Time `json:"lastFireTime,omitempty"`
+	// LastFireResult is success, error or no-fire.
+	// +optional
+	LastFireResult string `json:"lastFireResult,omitempty"`
+	// +optional
+	LastFireTaskID string `json:"lastFireTaskId,omitempty"`
+	// +optional
+	NextScheduledTime *metav1.Time `json:"nextScheduledTime,omitempty"`
+
+	// LastTriggerRequest is the value of the trigger annotation last acted
+	// upon.
+	// +optional
+	LastTriggerRequest string `json:"lastTriggerRequest,omitempty"`
+	// +optional
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Group",type=string,JSONPath=`.spec.hookGroupId`
+// +kubebuilder:printcolumn:name="Hook",type=string,JSONPath=`.spec.hookId`
+// +kubebuilder:printcolumn:name="Last Fire",type=date,JSONPath=`.status.lastFireTime`
+// +kubebuilder:printcolumn:name="Next Fire",type=date,JSONPath=`.status.nextScheduledTime`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+
+// Hook is the Schema for the hooks API
+type Hook struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HookSpec   `json:"spec,omitempty"`
+	Status HookStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// HookList contains a list of Hook
+type HookList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Hook `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Hook{}, &HookList{})
+}
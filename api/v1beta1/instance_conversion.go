@@ -0,0 +1,262 @@
+/*
+Copyright 2020 Well Played Games Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	v1 "github.com/wellplayedgames/taskcluster-operator/api/v1"
+)
+
+var _ conversion.Convertible = &Instance{}
+
+// ConvertTo converts this Instance to the Hub version (v1).
+func (r *Instance) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1.Instance)
+	src := r.DeepCopy()
+
+	dst.ObjectMeta = src.ObjectMeta
+	dst.Spec = v1.InstanceSpec{
+		RootURL:     src.Spec.RootURL,
+		DockerImage: src.Spec.DockerImage,
+		Database: v1.DatabaseSpec{
+			Ref:                src.Spec.DatabaseRef,
+			PostgresUserPrefix: src.Spec.PostgresUserPrefix,
+		},
+		Pulse: v1.PulseSpec{
+			AdminSecretRef:      src.Spec.Pulse.AdminSecretRef,
+			Host:                src.Spec.Pulse.Host,
+			Vhost:               src.Spec.Pulse.Vhost,
+			UseGuestCredentials: src.Spec.Pulse.UseGuestCredentials,
+		},
+		Artifacts: v1.ArtifactsSpec{
+			PublicBucket:   src.Spec.PublicArtifactBucket,
+			PrivateBucket:  src.Spec.PrivateArtifactBucket,
+			SignPublicURLs: src.Spec.SignPublicArtifactURLs,
+			Region:         src.Spec.ArtifactRegion,
+			AWSSecretRef:   src.Spec.AWSSecretRef,
+		},
+		Auth: v1.AuthSpec{
+			SecretRef:              src.Spec.AuthSecretRef,
+			StaticClientsSecretRef: src.Spec.AccessTokensSecretRef,
+			AzureSecretRef:         src.Spec.AzureSecretRef,
+			AzureAccountID:         src.Spec.AzureAccountID,
+			AccessTokens: v1.AccessTokenPolicy{
+				AllowedClientIDPrefixes: src.Spec.AccessTokens.AllowedClientIDPrefixes,
+			},
+		},
+		UI: v1.UISpec{
+			ApplicationName:             src.Spec.ApplicationName,
+			BannerMessage:               src.Spec.BannerMessage,
+			LoginStrategies:             src.Spec.LoginStrategies,
+			AdditionalAllowedCORSOrigin: src.Spec.AdditionalAllowedCORSOrigin,
+		},
+		Notify: v1.NotifySpec{
+			EmailSourceAddress: src.Spec.EmailSourceAddress,
+			MatrixSecretRef:    src.Spec.MatrixSecretRef,
+			SlackSecretRef:     src.Spec.SlackSecretRef,
+		},
+		GitHub: v1.GitHubSpec{
+			BotUsername: src.Spec.GitHub.BotUsername,
+			SecretRef:   src.Spec.GitHub.SecretRef,
+		},
+		Ingress: v1.IngressSpec{
+			Controller:      v1.IngressController(src.Spec.Ingress.Controller),
+			ClassName:       src.Spec.Ingress.ClassName,
+			Annotations:     src.Spec.Ingress.Annotations,
+			StaticIPName:    src.Spec.Ingress.StaticIPName,
+			ExternalDNSName: src.Spec.Ingress.ExternalDNSName,
+			TLSSecretRef:    src.Spec.Ingress.TLSSecretRef,
+			IssuerRef:       src.Spec.Ingress.IssuerRef,
+		},
+		WorkerManager: v1.WorkerManagerSpec{
+			ProvidersSecretRef: src.Spec.WorkerManagerProvidersSecretRef,
+		},
+		WebSockTunnel: v1.WebSockTunnelSpec{
+			Ref:       src.Spec.WebSockTunnelRef,
+			SecretRef: src.Spec.WebSockTunnelSecretRef,
+		},
+		Rotation: v1.RotationSpec{
+			ServiceCredentials: convertRotationPolicyTo(src.Spec.Rotation.ServiceCredentials),
+			AccessTokens:       convertRotationPolicyTo(src.Spec.Rotation.AccessTokens),
+		},
+	}
+
+	if policy := src.Spec.Rotation.DBCryptoKeys; policy != nil {
+		dst.Spec.Rotation.DBCryptoKeys = &v1.CryptoKeyRotationPolicy{
+			RotationPolicy: v1.RotationPolicy{Interval: policy.Interval},
+			RetireAfter:    policy.RetireAfter,
+		}
+	}
+
+	if encryption := src.Spec.StateEncryption; encryption != nil {
+		dst.Spec.StateEncryption = &v1.StateEncryptionSpec{
+			SecretKeyRef: encryption.SecretKeyRef,
+		}
+		if vault := encryption.VaultTransit; vault != nil {
+			dst.Spec.StateEncryption.VaultTransit = &v1.VaultTransitSpec{
+				Address:        vault.Address,
+				Mount:          vault.Mount,
+				KeyName:        vault.KeyName,
+				TokenSecretRef: vault.TokenSecretRef,
+			}
+		}
+		if kms := encryption.GCPKMS; kms != nil {
+			dst.Spec.StateEncryption.GCPKMS = &v1.GCPKMSSpec{KeyName: kms.KeyName}
+		}
+	}
+
+	dst.Status = v1.InstanceStatus{
+		AccessTokenRotation: v1.AccessTokenRotationStatus(src.Status.AccessTokenRotation),
+		WebSockTunnelURL:    src.Status.WebSockTunnelURL,
+	}
+	for _, condition := range src.Status.Conditions {
+		dst.Status.Conditions = append(dst.Status.Conditions, v1.InstanceCondition{
+			Type:               v1.InstanceConditionType(condition.Type),
+			Status:             condition.Status,
+			LastTransitionTime: condition.LastTransitionTime,
+			Reason:             condition.Reason,
+			Message:            condition.Message,
+		})
+	}
+	for _, keys := range src.Status.CryptoKeys {
+		dst.Status.CryptoKeys = append(dst.Status.CryptoKeys, v1.CryptoKeyStatus(keys))
+	}
+
+	return nil
+}
+
+// ConvertFrom converts from the Hub version (v1) to this version.
+func (r *Instance) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1.Instance).DeepCopy()
+
+	r.ObjectMeta = src.ObjectMeta
+	r.Spec = InstanceSpec{
+		WebSockTunnelRef:                src.Spec.WebSockTunnel.Ref,
+		WebSockTunnelSecretRef:          src.Spec.WebSockTunnel.SecretRef,
+		AWSSecretRef:                    src.Spec.Artifacts.AWSSecretRef,
+		AzureSecretRef:                  src.Spec.Auth.AzureSecretRef,
+		WorkerManagerProvidersSecretRef: src.Spec.WorkerManager.ProvidersSecretRef,
+		DatabaseRef:                     src.Spec.Database.Ref,
+		AuthSecretRef:                   src.Spec.Auth.SecretRef,
+		AccessTokensSecretRef:           src.Spec.Auth.StaticClientsSecretRef,
+
+		MatrixSecretRef: src.Spec.Notify.MatrixSecretRef,
+		SlackSecretRef:  src.Spec.Notify.SlackSecretRef,
+
+		GitHub: GitHubSpec{
+			BotUsername: src.Spec.GitHub.BotUsername,
+			SecretRef:   src.Spec.GitHub.SecretRef,
+		},
+		Pulse: PulseSpec{
+			AdminSecretRef:      src.Spec.Pulse.AdminSecretRef,
+			Host:                src.Spec.Pulse.Host,
+			Vhost:               src.Spec.Pulse.Vhost,
+			UseGuestCredentials: src.Spec.Pulse.UseGuestCredentials,
+		},
+		Ingress: InstanceIngressSpec{
+			StaticIPName:    src.Spec.Ingress.StaticIPName,
+			ExternalDNSName: src.Spec.Ingress.ExternalDNSName,
+			TLSSecretRef:    src.Spec.Ingress.TLSSecretRef,
+			IssuerRef:       src.Spec.Ingress.IssuerRef,
+			Controller:      IngressController(src.Spec.Ingress.Controller),
+			ClassName:       src.Spec.Ingress.ClassName,
+			Annotations:     src.Spec.Ingress.Annotations,
+		},
+
+		Rotation: RotationSpec{
+			ServiceCredentials: convertRotationPolicyFrom(src.Spec.Rotation.ServiceCredentials),
+			AccessTokens:       convertRotationPolicyFrom(src.Spec.Rotation.AccessTokens),
+		},
+		AccessTokens: AccessTokenPolicy{
+			AllowedClientIDPrefixes: src.Spec.Auth.AccessTokens.AllowedClientIDPrefixes,
+		},
+
+		RootURL:                     src.Spec.RootURL,
+		ApplicationName:             src.Spec.UI.ApplicationName,
+		BannerMessage:               src.Spec.UI.BannerMessage,
+		EmailSourceAddress:          src.Spec.Notify.EmailSourceAddress,
+		PublicArtifactBucket:        src.Spec.Artifacts.PublicBucket,
+		PrivateArtifactBucket:       src.Spec.Artifacts.PrivateBucket,
+		SignPublicArtifactURLs:      src.Spec.Artifacts.SignPublicURLs,
+		ArtifactRegion:              src.Spec.Artifacts.Region,
+		AdditionalAllowedCORSOrigin: src.Spec.UI.AdditionalAllowedCORSOrigin,
+		LoginStrategies:             src.Spec.UI.LoginStrategies,
+		AzureAccountID:              src.Spec.Auth.AzureAccountID,
+		PostgresUserPrefix:          src.Spec.Database.PostgresUserPrefix,
+		DockerImage:                 src.Spec.DockerImage,
+	}
+
+	if policy := src.Spec.Rotation.DBCryptoKeys; policy != nil {
+		r.Spec.Rotation.DBCryptoKeys = &CryptoKeyRotationPolicy{
+			RotationPolicy: RotationPolicy{Interval: policy.Interval},
+			RetireAfter:    policy.RetireAfter,
+		}
+	}
+
+	if encryption := src.Spec.StateEncryption; encryption != nil {
+		r.Spec.StateEncryption = &StateEncryptionSpec{
+			SecretKeyRef: encryption.SecretKeyRef,
+		}
+		if vault := encryption.VaultTransit; vault != nil {
+			r.Spec.StateEncryption.VaultTransit = &VaultTransitSpec{
+				Address:        vault.Address,
+				Mount:          vault.Mount,
+				KeyName:        vault.KeyName,
+				TokenSecretRef: vault.TokenSecretRef,
+			}
+		}
+		if kms := encryption.GCPKMS; kms != nil {
+			r.Spec.StateEncryption.GCPKMS = &GCPKMSSpec{KeyName: kms.KeyName}
+		}
+	}
+
+	r.Status = InstanceStatus{
+		AccessTokenRotation: AccessTokenRotationStatus(src.Status.AccessTokenRotation),
+		WebSockTunnelURL:    src.Status.WebSockTunnelURL,
+	}
+	for _, condition := range src.Status.Conditions {
+		r.Status.Conditions = append(r.Status.Conditions, InstanceCondition{
+			Type:               InstanceConditionType(condition.Type),
+			Status:             condition.Status,
+			LastTransitionTime: condition.LastTransitionTime,
+			Reason:             condition.Reason,
+			Message:            condition.Message,
+		})
+	}
+	for _, keys := range src.Status.CryptoKeys {
+		r.Status.CryptoKeys = append(r.Status.CryptoKeys, CryptoKeyStatus(keys))
+	}
+
+	return nil
+}
+
+func convertRotationPolicyTo(policy *RotationPolicy) *v1.RotationPolicy {
+	if policy == nil {
+		return nil
+	}
+
+	return &v1.RotationPolicy{Interval: policy.Interval}
+}
+
+func convertRotationPolicyFrom(policy *v1.RotationPolicy) *RotationPolicy {
+	if policy == nil {
+		return nil
+	}
+
+	return &RotationPolicy{Interval: policy.Interval}
+}
@@ -0,0 +1,62 @@
+package v1beta1
+
+import (
+	"testing"
+
+	fuzz "github.com/google/gofuzz"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/diff"
+
+	v1 "github.com/wellplayedgames/taskcluster-operator/api/v1"
+)
+
+func TestInstanceConversionRoundTrip(t *testing.T) {
+	f := fuzz.New().NilChance(0.2)
+
+	for i := 0; i < 1000; i++ {
+		var original Instance
+		f.Fuzz(&original)
+		// The type is set by whoever serializes the converted object.
+		original.TypeMeta = metav1.TypeMeta{}
+
+		var hub v1.Instance
+		if err := original.DeepCopy().ConvertTo(&hub); err != nil {
+			t.Fatal(err)
+		}
+
+		var converted Instance
+		if err := converted.ConvertFrom(&hub); err != nil {
+			t.Fatal(err)
+		}
+
+		if !apiequality.Semantic.DeepEqual(&original, &converted) {
+			t.Fatalf("Instance changed in a round trip through v1:\n%s", diff.ObjectReflectDiff(&original, &converted))
+		}
+	}
+}
+
+func TestInstanceHubConversionRoundTrip(t *testing.T) {
+	f := fuzz.New().NilChance(0.2)
+
+	for i := 0; i < 1000; i++ {
+		var original v1.Instance
+		f.Fuzz(&original)
+		// The type is set by whoever serializes the converted object.
+		original.TypeMeta = metav1.TypeMeta{}
+
+		var spoke Instance
+		if err := spoke.ConvertFrom(original.DeepCopy()); err != nil {
+			t.Fatal(err)
+		}
+
+		var converted v1.Instance
+		if err := spoke.ConvertTo(&converted); err != nil {
+			t.Fatal(err)
+		}
+
+		if !apiequality.Semantic.DeepEqual(&original, &converted) {
+			t.Fatalf("Instance changed in a round trip through v1beta1:\n%s", diff.ObjectReflectDiff(&original, &converted))
+		}
+	}
+}
@@ -34,6 +34,11 @@ type PulseSpec struct {
 	AdminSecretRef *corev1.LocalObjectReference `json:"adminSecretRef,omitempty"`
 	Host           string                       `json:"host,omitempty"`
 	Vhost          string                       `json:"vhost,omitempty"`
+
+	// UseGuestCredentials connects to RabbitMQ as guest/guest when
+	// AdminSecretRef is unset. Defaults to true when AdminSecretRef is unset.
+	// +optional
+	UseGuestCredentials *bool `json:"useGuestCredentials,omitempty"`
 }
 
 // GitHubSpec contains the desired GitHub integration configuration.
@@ -42,16 +47,124 @@ type GitHubSpec struct {
 	SecretRef   *corev1.LocalObjectReference `json:"secretRef,omitempty"`
 }
 
+// IngressController selects the ingress controller an Instance's Ingress is
+// written for.
+// +kubebuilder:validation:Enum=gce;nginx;traefik
+type IngressController string
+
+const (
+	// IngressControllerGCE uses GCE wildcard paths and a global static IP.
+	IngressControllerGCE IngressController = "gce"
+	// IngressControllerNginx uses prefix paths and long proxy timeouts for
+	// websocket subscriptions.
+	IngressControllerNginx IngressController = "nginx"
+	// IngressControllerTraefik uses prefix paths.
+	IngressControllerTraefik IngressController = "traefik"
+)
+
 // InstanceIngressSpec contains the desired ingress configuration.
 type InstanceIngressSpec struct {
+	// StaticIPName is the name of a global static IP. It is only supported
+	// by the gce controller.
 	StaticIPName    string                       `json:"staticIpName,omitempty"`
 	ExternalDNSName string                       `json:"externalDNSName,omitempty"`
 	TLSSecretRef    *corev1.LocalObjectReference `json:"tlsSecretRef,omitempty"`
 	IssuerRef       corev1.ObjectReference       `json:"issuerRef,omitempty"`
+
+	// Controller selects the path syntax and annotations of the Ingress.
+	// Defaults to gce.
+	// +optional
+	Controller IngressController `json:"controller,omitempty"`
+	// ClassName is set as the ingressClassName of the Ingress. Defaults to
+	// the controller for nginx and traefik.
+	// +optional
+	ClassName string `json:"className,omitempty"`
+	// Annotations are added to the Ingress, overriding those of the
+	// controller.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// RotationPolicy describes how often a generated credential is replaced.
+type RotationPolicy struct {
+	// Interval is the maximum age of a credential before it is regenerated.
+	Interval metav1.Duration `json:"interval"`
+}
+
+// CryptoKeyRotationPolicy describes how often database crypto keys are
+// replaced and when superseded keys are retired.
+type CryptoKeyRotationPolicy struct {
+	RotationPolicy `json:",inline"`
+
+	// RetireAfter is how long a superseded key is kept for decryption. Once
+	// it has elapsed, values still encrypted with the key are re-encrypted
+	// with the active key and the key is removed. Superseded keys are kept
+	// forever when unset.
+	// +optional
+	RetireAfter *metav1.Duration `json:"retireAfter,omitempty"`
+}
+
+// RotationSpec contains the desired credential rotation policies.
+type RotationSpec struct {
+	// ServiceCredentials rotates the Postgres and Pulse passwords generated
+	// for each TaskCluster service.
+	// +optional
+	ServiceCredentials *RotationPolicy `json:"serviceCredentials,omitempty"`
+
+	// DBCryptoKeys rotates the keys used to encrypt database columns.
+	// +optional
+	DBCryptoKeys *CryptoKeyRotationPolicy `json:"dbCryptoKeys,omitempty"`
+
+	// AccessTokens rotates the static access tokens of the TaskCluster
+	// services. A rotation can also be requested by changing the
+	// taskcluster.wellplayed.games/rotate-access-tokens annotation.
+	// +optional
+	AccessTokens *RotationPolicy `json:"accessTokens,omitempty"`
+}
+
+// VaultTransitSpec contains the details of a HashiCorp Vault transit key.
+type VaultTransitSpec struct {
+	Address string `json:"address"`
+	// Mount is the path the transit secrets engine is mounted at. Defaults
+	// to transit.
+	// +optional
+	Mount          string                   `json:"mount,omitempty"`
+	KeyName        string                   `json:"keyName"`
+	TokenSecretRef corev1.SecretKeySelector `json:"tokenSecretRef"`
+}
+
+// GCPKMSSpec contains the details of a Google Cloud KMS key.
+type GCPKMSSpec struct {
+	// KeyName is the resource name of the key, of the form
+	// projects/*/locations/*/keyRings/*/cryptoKeys/*.
+	KeyName string `json:"keyName"`
+}
+
+// StateEncryptionSpec configures envelope encryption of the Secret holding
+// the operator's generated credentials. Exactly one provider should be set.
+type StateEncryptionSpec struct {
+	// SecretKeyRef references a base64 encoded 32 byte key.
+	// +optional
+	SecretKeyRef *corev1.SecretKeySelector `json:"secretKeyRef,omitempty"`
+	// +optional
+	VaultTransit *VaultTransitSpec `json:"vaultTransit,omitempty"`
+	// +optional
+	GCPKMS *GCPKMSSpec `json:"gcpKms,omitempty"`
+}
+
+// AccessTokenPolicy restricts the AccessTokens an Instance accepts.
+type AccessTokenPolicy struct {
+	// AllowedClientIDPrefixes lists the prefixes AccessToken client IDs must
+	// start with. All client IDs are allowed when empty.
+	// +optional
+	AllowedClientIDPrefixes []string `json:"allowedClientIDPrefixes,omitempty"`
 }
 
 // InstanceSpec defines the desired state of Instance
 type InstanceSpec struct {
+	// WebSockTunnelRef references a WebSockTunnel in the same namespace.
+	// It takes precedence over WebSockTunnelSecretRef.
+	WebSockTunnelRef                *corev1.LocalObjectReference `json:"webSockTunnelRef,omitempty"`
 	WebSockTunnelSecretRef          *corev1.LocalObjectReference `json:"webSockTunnelSecretRef,omitempty"`
 	AWSSecretRef                    *corev1.LocalObjectReference `json:"awsSecretRef,omitempty"`
 	AzureSecretRef                  *corev1.LocalObjectReference `json:"azureSecretRef,omitempty"`
@@ -68,6 +181,10 @@ type InstanceSpec struct {
 	Pulse   PulseSpec           `json:"pulse,omitempty"`
 	Ingress InstanceIngressSpec `json:"ingress,omitempty"`
 
+	Rotation        RotationSpec         `json:"rotation,omitempty"`
+	StateEncryption *StateEncryptionSpec `json:"stateEncryption,omitempty"`
+	AccessTokens    AccessTokenPolicy    `json:"accessTokens,omitempty"`
+
 	RootURL                     string   `json:"rootUrl,omitempty"`
 	ApplicationName             string   `json:"applicationName,omitempty"`
 	BannerMessage               string   `json:"bannerMessage,omitempty"`
@@ -79,8 +196,14 @@ type InstanceSpec struct {
 	AdditionalAllowedCORSOrigin string   `json:"additionalAllowedCorsOrigin,omitempty"`
 	LoginStrategies             []string `json:"loginStrategies,omitempty"`
 	AzureAccountID              string   `json:"azureAccountId,omitempty"`
-	DockerImage                 string   `json:"dockerImage,omitempty"`
 	PostgresUserPrefix          string   `json:"postgresUserPrefix,omitempty"`
+
+	// DockerImage is the TaskCluster image. It defaults to
+	// taskcluster/taskcluster, and the version the operator was built with
+	// is added when no tag is given. The version is pinned when the Instance
+	// is created, so upgrading the operator does not upgrade TaskCluster.
+	// +optional
+	DockerImage string `json:"dockerImage,omitempty"`
 }
 
 // InstanceConditionType represents the type enum of a condition.
@@ -109,9 +232,36 @@ type InstanceCondition struct {
 	Message string `json:"message,omitempty"`
 }
 
+// CryptoKeyStatus reports the database crypto keys held by a service.
+type CryptoKeyStatus struct {
+	Service string `json:"service"`
+	// KeyIDs lists the keys available for decryption. The first is used to
+	// encrypt new values.
+	KeyIDs []string `json:"keyIds,omitempty"`
+}
+
+// AccessTokenRotationStatus reports the progress of a static access token
+// rotation.
+type AccessTokenRotationStatus struct {
+	// Service is the service whose access token is being replaced.
+	// +optional
+	Service string `json:"service,omitempty"`
+	// Stage is Auth while the auth service picks up the new token and
+	// Service while the service itself does.
+	// +optional
+	Stage string `json:"stage,omitempty"`
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// +optional
+	LastCompletionTime *metav1.Time `json:"lastCompletionTime,omitempty"`
+}
+
 // InstanceStatus defines the observed state of Instance
 type InstanceStatus struct {
-	Conditions []InstanceCondition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+	Conditions          []InstanceCondition       `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+	CryptoKeys          []CryptoKeyStatus         `json:"cryptoKeys,omitempty"`
+	AccessTokenRotation AccessTokenRotationStatus `json:"accessTokenRotation,omitempty"`
+	WebSockTunnelURL    string                    `json:"webSockTunnelUrl,omitempty"`
 }
 
 // +kubebuilder:object:root=true
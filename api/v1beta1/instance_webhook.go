@@ -0,0 +1,183 @@
+/*
+Copyright 2020 Well Played Games Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+const (
+	// GitHubLoginStrategy signs users in to the UI with GitHub. It requires
+	// spec.github.secretRef.
+	GitHubLoginStrategy = "github"
+
+	// DefaultDockerRepo is the TaskCluster image used when none is given.
+	DefaultDockerRepo = "taskcluster/taskcluster"
+	// DefaultVersion is the TaskCluster version used when an image has no
+	// tag.
+	DefaultVersion = "42.1.1"
+)
+
+// dockerImagePattern matches an image reference with an optional registry,
+// tag and digest.
+var dockerImagePattern = regexp.MustCompile(`^(?:[A-Za-z0-9.-]+(?::[0-9]+)?/)?[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*(?::[A-Za-z0-9_][A-Za-z0-9_.-]{0,127})?(?:@sha256:[a-f0-9]{64})?$`)
+
+// SetupWebhookWithManager registers the Instance webhooks.
+func (r *Instance) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// DefaultDockerImage returns the image to run for a dockerImage field.
+func DefaultDockerImage(image string) string {
+	if image == "" {
+		image = DefaultDockerRepo
+	}
+
+	// A colon after the last slash starts a tag rather than a registry port.
+	name := image[strings.LastIndex(image, "/")+1:]
+	if strings.ContainsAny(name, ":@") {
+		return image
+	}
+
+	return fmt.Sprintf("%s:v%s", image, DefaultVersion)
+}
+
+// +kubebuilder:webhook:verbs=create;update,path=/mutate-taskcluster-wellplayed-games-v1beta1-instance,mutating=true,failurePolicy=fail,groups=taskcluster.wellplayed.games,resources=instances,versions=v1beta1,name=minstance.taskcluster.wellplayed.games
+
+var _ webhook.Defaulter = &Instance{}
+
+// Default implements webhook.Defaulter so that the defaults the controller
+// applies are stored in the Instance.
+func (r *Instance) Default() {
+	spec := &r.Spec
+
+	spec.RootURL = strings.TrimSuffix(spec.RootURL, "/")
+	spec.DockerImage = DefaultDockerImage(spec.DockerImage)
+
+	if spec.Pulse.AdminSecretRef == nil && spec.Pulse.UseGuestCredentials == nil {
+		useGuestCredentials := true
+		spec.Pulse.UseGuestCredentials = &useGuestCredentials
+	}
+}
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-taskcluster-wellplayed-games-v1beta1-instance,mutating=false,failurePolicy=fail,groups=taskcluster.wellplayed.games,resources=instances,versions=v1beta1,name=vinstance.taskcluster.wellplayed.games
+
+var _ webhook.Validator = &Instance{}
+
+// ValidateCreate implements webhook.Validator
+func (r *Instance) ValidateCreate() error {
+	return r.validate(nil)
+}
+
+// ValidateUpdate implements webhook.Validator
+func (r *Instance) ValidateUpdate(old runtime.Object) error {
+	return r.validate(old.(*Instance))
+}
+
+// ValidateDelete implements webhook.Validator
+func (r *Instance) ValidateDelete() error {
+	return nil
+}
+
+func (r *Instance) validate(old *Instance) error {
+	var errs field.ErrorList
+	spec := &r.Spec
+	path := field.NewPath("spec")
+
+	errs = append(errs, validateRootURL(spec.RootURL, path.Child("rootUrl"))...)
+
+	if spec.DatabaseRef == nil || spec.DatabaseRef.Name == "" {
+		errs = append(errs, field.Required(path.Child("databaseRef", "name"), "a database is required"))
+	}
+
+	for i, strategy := range spec.LoginStrategies {
+		strategyPath := path.Child("loginStrategies").Index(i)
+		switch strategy {
+		case GitHubLoginStrategy:
+			if spec.GitHub.SecretRef == nil {
+				errs = append(errs, field.Required(path.Child("github", "secretRef"), "the github login strategy requires GitHub credentials"))
+			}
+		default:
+			errs = append(errs, field.NotSupported(strategyPath, strategy, []string{GitHubLoginStrategy}))
+		}
+	}
+
+	if image := spec.DockerImage; image != "" && !dockerImagePattern.MatchString(image) {
+		errs = append(errs, field.Invalid(path.Child("dockerImage"), image, "must be a docker image reference such as taskcluster/taskcluster:v42.1.1"))
+	}
+
+	if ingress := &spec.Ingress; ingress.StaticIPName != "" && ingress.Controller != "" && ingress.Controller != IngressControllerGCE {
+		errs = append(errs, field.Forbidden(path.Child("ingress", "staticIpName"), "only supported by the gce controller"))
+	}
+
+	if old != nil {
+		errs = append(errs, validateImmutable(spec.PostgresUserPrefix, old.Spec.PostgresUserPrefix, path.Child("postgresUserPrefix"))...)
+		errs = append(errs, validateImmutable(spec.Pulse.Vhost, old.Spec.Pulse.Vhost, path.Child("pulse", "vhost"))...)
+	}
+
+	if spec.Pulse.AdminSecretRef == nil && spec.Pulse.UseGuestCredentials != nil && !*spec.Pulse.UseGuestCredentials {
+		errs = append(errs, field.Required(path.Child("pulse", "adminSecretRef"), "required unless useGuestCredentials is set"))
+	}
+
+	if len(errs) > 0 {
+		return apierrors.NewInvalid(GroupVersion.WithKind("Instance").GroupKind(), r.Name, errs)
+	}
+
+	return nil
+}
+
+// validateRootURL checks that a root URL is an https URL without a path.
+func validateRootURL(rootURL string, path *field.Path) field.ErrorList {
+	if rootURL == "" {
+		return field.ErrorList{field.Required(path, "")}
+	}
+
+	u, err := url.Parse(rootURL)
+	if err != nil {
+		return field.ErrorList{field.Invalid(path, rootURL, err.Error())}
+	}
+
+	if u.Scheme != "https" || u.Host == "" {
+		return field.ErrorList{field.Invalid(path, rootURL, "must be an https URL")}
+	}
+
+	if (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return field.ErrorList{field.Invalid(path, rootURL, fmt.Sprintf("must not contain anything but the host, e.g. https://%s", u.Host))}
+	}
+
+	return nil
+}
+
+// validateImmutable checks that a field has not changed.
+func validateImmutable(value, old string, path *field.Path) field.ErrorList {
+	if value != old {
+		return field.ErrorList{field.Invalid(path, value, "field is immutable")}
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2020 Well Played Games Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RoleSpec defines the desired state of Role
+type RoleSpec struct {
+	// InstanceRef is the Instance the role is created in. The namespace
+	// defaults to that of the Role.
+	InstanceRef corev1.ObjectReference `json:"instanceRef"`
+
+	// +kubebuilder:validation:MinLength=1
+	RoleID      string   `json:"roleId"`
+	Description string   `json:"description,omitempty"`
+	Scopes      []string `json:"scopes"`
+}
+
+// RoleStatus defines the observed state of Role
+type RoleStatus struct {
+	Conditions         []SyncCondition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+	ObservedGeneration *int64          `json:"observedGeneration,omitempty"`
+
+	// RoleID is the ID of the role last created in TaskCluster.
+	// +optional
+	RoleID string `json:"roleId,omitempty"`
+	// ExpandedScopes are the scopes granted by the role after expanding any
+	// roles it assumes.
+	// +optional
+	ExpandedScopes []string `json:"expandedScopes,omitempty"`
+	// +optional
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Role",type=string,JSONPath=`.spec.roleId`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// Role is the Schema for the roles API
+type Role struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RoleSpec   `json:"spec,omitempty"`
+	Status RoleStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// RoleList contains a list of Role
+type RoleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Role `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Role{}, &RoleList{})
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2020 Well Played Games Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SyncConditionType represents the type enum of a condition on a resource
+// which is kept in sync with a TaskCluster API.
+type SyncConditionType string
+
+const (
+	// SyncReady is used when the resource matches its TaskCluster
+	// counterpart.
+	SyncReady SyncConditionType = "Ready"
+)
+
+// SyncCondition represents a condition of a resource which is kept in sync
+// with a TaskCluster API.
+type SyncCondition struct {
+	Type   SyncConditionType      `json:"type"`
+	Status corev1.ConditionStatus `json:"status"`
+	// Last time the condition transitioned from one status to another.
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+	// A short, machine understandable string that gives the reason for the
+	// condition's last transition.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// Human-readable message indicating details about last transition.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
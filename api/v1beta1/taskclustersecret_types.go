@@ -0,0 +1,100 @@
+/*
+Copyright 2020 Well Played Games Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TaskClusterSecretKey selects a key of the source Secret.
+type TaskClusterSecretKey struct {
+	// Key in the source Secret.
+	Key string `json:"key"`
+	// Property is the name of the value in the TaskCluster secret. Defaults
+	// to the key.
+	// +optional
+	Property string `json:"property,omitempty"`
+}
+
+// TaskClusterSecretSpec defines the desired state of TaskClusterSecret
+type TaskClusterSecretSpec struct {
+	// InstanceRef is the Instance the secret is stored in. The namespace
+	// defaults to that of the TaskClusterSecret.
+	InstanceRef corev1.ObjectReference `json:"instanceRef"`
+
+	// SecretName is the name of the secret in TaskCluster.
+	// +kubebuilder:validation:MinLength=1
+	SecretName string `json:"secretName"`
+
+	// SourceRef is the Kubernetes Secret whose data is copied.
+	SourceRef corev1.LocalObjectReference `json:"sourceRef"`
+	// Keys selects which keys of the source are copied. All keys are
+	// copied when empty.
+	// +optional
+	Keys []TaskClusterSecretKey `json:"keys,omitempty"`
+
+	// Expires is when the TaskCluster secret expires.
+	// +optional
+	Expires *metav1.Time `json:"expires,omitempty"`
+	// ExpiresAfter is used when Expires is unset. The expiry is pushed back
+	// once half of it has elapsed. Defaults to one year.
+	// +optional
+	ExpiresAfter *metav1.Duration `json:"expiresAfter,omitempty"`
+}
+
+// TaskClusterSecretStatus defines the observed state of TaskClusterSecret
+type TaskClusterSecretStatus struct {
+	Conditions         []SyncCondition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+	ObservedGeneration *int64          `json:"observedGeneration,omitempty"`
+
+	// SecretName is the name of the secret last written to TaskCluster.
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+	// +optional
+	Expires *metav1.Time `json:"expires,omitempty"`
+	// +optional
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Secret",type=string,JSONPath=`.spec.secretName`
+// +kubebuilder:printcolumn:name="Expires",type=date,JSONPath=`.status.expires`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+
+// TaskClusterSecret is the Schema for the taskclustersecrets API
+type TaskClusterSecret struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   TaskClusterSecretSpec   `json:"spec,omitempty"`
+	Status TaskClusterSecretStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// TaskClusterSecretList contains a list of TaskClusterSecret
+type TaskClusterSecretList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []TaskClusterSecret `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&TaskClusterSecret{}, &TaskClusterSecretList{})
+}
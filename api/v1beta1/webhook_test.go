@@ -0,0 +1,348 @@
+package v1beta1
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	cmmeta "github.com/jetstack/cert-manager/pkg/apis/meta/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// checkInvalid checks that err reports the given field, or that there is no
+// error if field is empty.
+func checkInvalid(t *testing.T, err error, field string) {
+	t.Helper()
+
+	if field == "" {
+		if err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+		return
+	}
+
+	if err == nil {
+		t.Errorf("expected %s to be invalid", field)
+	} else if !strings.Contains(err.Error(), field) {
+		t.Errorf("expected %s to be invalid, got %v", field, err)
+	}
+}
+
+func validInstance() *Instance {
+	return &Instance{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "taskcluster", Name: "tc"},
+		Spec: InstanceSpec{
+			RootURL:            "https://tc.example.com",
+			DatabaseRef:        &corev1.LocalObjectReference{Name: "db"},
+			DockerImage:        "taskcluster/taskcluster:v42.1.1",
+			PostgresUserPrefix: "tc",
+			Pulse:              PulseSpec{Vhost: "tc"},
+		},
+	}
+}
+
+func TestInstanceValidateCreate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(spec *InstanceSpec)
+		field  string
+	}{
+		{
+			name:   "valid",
+			change: func(spec *InstanceSpec) {},
+		},
+		{
+			name:   "root URL with a trailing slash",
+			change: func(spec *InstanceSpec) { spec.RootURL = "https://tc.example.com/" },
+		},
+		{
+			name:   "missing root URL",
+			change: func(spec *InstanceSpec) { spec.RootURL = "" },
+			field:  "spec.rootUrl",
+		},
+		{
+			name:   "http root URL",
+			change: func(spec *InstanceSpec) { spec.RootURL = "http://tc.example.com" },
+			field:  "spec.rootUrl",
+		},
+		{
+			name:   "root URL with a path",
+			change: func(spec *InstanceSpec) { spec.RootURL = "https://tc.example.com/taskcluster" },
+			field:  "spec.rootUrl",
+		},
+		{
+			name:   "missing database",
+			change: func(spec *InstanceSpec) { spec.DatabaseRef = nil },
+			field:  "spec.databaseRef.name",
+		},
+		{
+			name:   "unnamed database",
+			change: func(spec *InstanceSpec) { spec.DatabaseRef = &corev1.LocalObjectReference{} },
+			field:  "spec.databaseRef.name",
+		},
+		{
+			name:   "unknown login strategy",
+			change: func(spec *InstanceSpec) { spec.LoginStrategies = []string{"google"} },
+			field:  "spec.loginStrategies[0]",
+		},
+		{
+			name:   "github login without credentials",
+			change: func(spec *InstanceSpec) { spec.LoginStrategies = []string{GitHubLoginStrategy} },
+			field:  "spec.github.secretRef",
+		},
+		{
+			name: "github login",
+			change: func(spec *InstanceSpec) {
+				spec.LoginStrategies = []string{GitHubLoginStrategy}
+				spec.GitHub.SecretRef = &corev1.LocalObjectReference{Name: "github"}
+			},
+		},
+		{
+			name: "image from a registry with a port",
+			change: func(spec *InstanceSpec) {
+				spec.DockerImage = "registry.example.com:5000/taskcluster/taskcluster:v42.1.1"
+			},
+		},
+		{
+			name:   "malformed image",
+			change: func(spec *InstanceSpec) { spec.DockerImage = "Taskcluster/taskcluster:v42 1" },
+			field:  "spec.dockerImage",
+		},
+		{
+			name:   "image with an empty tag",
+			change: func(spec *InstanceSpec) { spec.DockerImage = "taskcluster/taskcluster:" },
+			field:  "spec.dockerImage",
+		},
+		{
+			name: "static IP for nginx",
+			change: func(spec *InstanceSpec) {
+				spec.Ingress.Controller = IngressControllerNginx
+				spec.Ingress.StaticIPName = "tc"
+			},
+			field: "spec.ingress.staticIpName",
+		},
+		{
+			name: "pulse without credentials",
+			change: func(spec *InstanceSpec) {
+				useGuestCredentials := false
+				spec.Pulse.UseGuestCredentials = &useGuestCredentials
+			},
+			field: "spec.pulse.adminSecretRef",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := validInstance()
+			tt.change(&instance.Spec)
+			checkInvalid(t, instance.ValidateCreate(), tt.field)
+		})
+	}
+}
+
+func TestInstanceValidateUpdate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(spec *InstanceSpec)
+		field  string
+	}{
+		{
+			name:   "mutable field",
+			change: func(spec *InstanceSpec) { spec.BannerMessage = "hello" },
+		},
+		{
+			name:   "postgres user prefix",
+			change: func(spec *InstanceSpec) { spec.PostgresUserPrefix = "other" },
+			field:  "spec.postgresUserPrefix",
+		},
+		{
+			name:   "pulse vhost",
+			change: func(spec *InstanceSpec) { spec.Pulse.Vhost = "other" },
+			field:  "spec.pulse.vhost",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := validInstance()
+			instance := old.DeepCopy()
+			tt.change(&instance.Spec)
+			checkInvalid(t, instance.ValidateUpdate(old), tt.field)
+		})
+	}
+}
+
+func TestAccessTokenValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(spec *AccessTokenSpec)
+		field  string
+	}{
+		{
+			name:   "static",
+			change: func(spec *AccessTokenSpec) {},
+		},
+		{
+			name: "dynamic",
+			change: func(spec *AccessTokenSpec) {
+				spec.Mode = AccessTokenDynamic
+				spec.ClientID = "project/ci"
+			},
+		},
+		{
+			name:   "missing instance",
+			change: func(spec *AccessTokenSpec) { spec.InstanceRef.Name = "" },
+			field:  "spec.instanceRef.name",
+		},
+		{
+			name:   "service client ID",
+			change: func(spec *AccessTokenSpec) { spec.ClientID = ServiceClientIDPrefix + "queue" },
+			field:  "spec.clientID",
+		},
+		{
+			name: "dynamic service client ID",
+			change: func(spec *AccessTokenSpec) {
+				spec.Mode = AccessTokenDynamic
+				spec.ClientID = ServiceClientIDPrefix + "queue"
+			},
+			field: "spec.clientID",
+		},
+		{
+			name: "dynamic static client ID",
+			change: func(spec *AccessTokenSpec) {
+				spec.Mode = AccessTokenDynamic
+				spec.ClientID = StaticClientIDPrefix + "ci"
+			},
+			field: "spec.clientID",
+		},
+		{
+			name:   "static client ID without prefix",
+			change: func(spec *AccessTokenSpec) { spec.ClientID = "project/ci" },
+			field:  "spec.clientID",
+		},
+		{
+			name:   "invalid scope",
+			change: func(spec *AccessTokenSpec) { spec.Scopes = []string{"queue:create-task:*", "bad\nscope"} },
+			field:  "spec.scopes[1]",
+		},
+		{
+			name: "zero rotation interval",
+			change: func(spec *AccessTokenSpec) {
+				spec.Rotation = &AccessTokenRotationSpec{Interval: &metav1.Duration{}}
+			},
+			field: "spec.rotation.interval",
+		},
+		{
+			name: "invalid restart selector",
+			change: func(spec *AccessTokenSpec) {
+				spec.Rotation = &AccessTokenRotationSpec{RestartSelector: &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "app", Operator: "Near"}},
+				}}
+			},
+			field: "spec.rotation.restartSelector",
+		},
+		{
+			name:   "negative expiry",
+			change: func(spec *AccessTokenSpec) { spec.ExpiresAfter = &metav1.Duration{Duration: -time.Hour} },
+			field:  "spec.expiresAfter",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := &AccessToken{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "taskcluster", Name: "ci"},
+				Spec: AccessTokenSpec{
+					InstanceRef: corev1.ObjectReference{Name: "tc"},
+					ClientID:    StaticClientIDPrefix + "ci",
+					Scopes:      []string{"queue:create-task:*"},
+				},
+			}
+			tt.change(&token.Spec)
+
+			checkInvalid(t, token.ValidateCreate(), tt.field)
+			checkInvalid(t, token.ValidateUpdate(token.DeepCopy()), tt.field)
+		})
+	}
+}
+
+func TestWebSockTunnelValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(spec *WebSockTunnelSpec)
+		field  string
+	}{
+		{
+			name:   "load balancer",
+			change: func(spec *WebSockTunnelSpec) {},
+		},
+		{
+			name: "http route",
+			change: func(spec *WebSockTunnelSpec) {
+				spec.Exposure = WebSockTunnelExposeHTTPRoute
+				spec.CertificateIssuerRef = cmmeta.ObjectReference{}
+				spec.HTTPRoute = &WebSockTunnelHTTPRouteSpec{ParentRefs: []WebSockTunnelGatewayRef{{Name: "gateway"}}}
+			},
+		},
+		{
+			name:   "invalid domain",
+			change: func(spec *WebSockTunnelSpec) { spec.DomainName = "Not A Domain" },
+			field:  "spec.domainName",
+		},
+		{
+			name:   "missing secret",
+			change: func(spec *WebSockTunnelSpec) { spec.SecretRef.Name = "" },
+			field:  "spec.secretRef.name",
+		},
+		{
+			name: "ingress without an issuer",
+			change: func(spec *WebSockTunnelSpec) {
+				spec.Exposure = WebSockTunnelExposeIngress
+				spec.CertificateIssuerRef = cmmeta.ObjectReference{}
+			},
+			field: "spec.certificateIssuerRef.name",
+		},
+		{
+			name:   "http route without parents",
+			change: func(spec *WebSockTunnelSpec) { spec.Exposure = WebSockTunnelExposeHTTPRoute },
+			field:  "spec.httpRoute.parentRefs",
+		},
+		{
+			name: "both disruption budgets",
+			change: func(spec *WebSockTunnelSpec) {
+				one := intstr.FromInt(1)
+				spec.PodDisruptionBudget = &WebSockTunnelPodDisruptionBudgetSpec{MinAvailable: &one, MaxUnavailable: &one}
+			},
+			field: "spec.podDisruptionBudget",
+		},
+		{
+			name:   "zero rotation interval",
+			change: func(spec *WebSockTunnelSpec) { spec.RotationInterval = &metav1.Duration{} },
+			field:  "spec.rotationInterval",
+		},
+		{
+			name:   "negative idle timeout",
+			change: func(spec *WebSockTunnelSpec) { spec.Envoy.IdleTimeout = &metav1.Duration{Duration: -time.Second} },
+			field:  "spec.envoy.idleTimeout",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wst := &WebSockTunnel{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "taskcluster", Name: "wst"},
+				Spec: WebSockTunnelSpec{
+					DomainName:           "websocktunnel.example.com",
+					SecretRef:            corev1.LocalObjectReference{Name: "wst"},
+					CertificateIssuerRef: cmmeta.ObjectReference{Name: "letsencrypt"},
+				},
+			}
+			tt.change(&wst.Spec)
+
+			checkInvalid(t, wst.ValidateCreate(), tt.field)
+			checkInvalid(t, wst.ValidateUpdate(wst.DeepCopy()), tt.field)
+		})
+	}
+}
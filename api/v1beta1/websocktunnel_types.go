@@ -20,8 +20,144 @@ import (
 	cmmeta "github.com/jetstack/cert-manager/pkg/apis/meta/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
+// WebSockTunnelExposure selects how a tunnel is reachable from outside the
+// cluster.
+// +kubebuilder:validation:Enum=LoadBalancer;Ingress;HTTPRoute
+type WebSockTunnelExposure string
+
+const (
+	// WebSockTunnelExposeLoadBalancer exposes the tunnel through its own
+	// Service, terminating TLS in an envoy sidecar.
+	WebSockTunnelExposeLoadBalancer WebSockTunnelExposure = "LoadBalancer"
+
+	// WebSockTunnelExposeIngress exposes the tunnel through an Ingress which
+	// terminates TLS.
+	WebSockTunnelExposeIngress WebSockTunnelExposure = "Ingress"
+
+	// WebSockTunnelExposeHTTPRoute exposes the tunnel through a Gateway API
+	// HTTPRoute. TLS is terminated by the parent Gateway.
+	WebSockTunnelExposeHTTPRoute WebSockTunnelExposure = "HTTPRoute"
+)
+
+// WebSockTunnelServiceSpec configures the Service in front of the tunnel.
+type WebSockTunnelServiceSpec struct {
+	// Type of the Service. Defaults to LoadBalancer when the tunnel is
+	// exposed through its Service and ClusterIP otherwise.
+	// +optional
+	Type corev1.ServiceType `json:"type,omitempty"`
+	// +optional
+	LoadBalancerIP string `json:"loadBalancerIP,omitempty"`
+	// +optional
+	LoadBalancerSourceRanges []string `json:"loadBalancerSourceRanges,omitempty"`
+	// Annotations are added to the Service.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// WebSockTunnelIngressSpec configures the Ingress used in Ingress mode.
+type WebSockTunnelIngressSpec struct {
+	// ClassName is the IngressClass of the Ingress.
+	// +optional
+	ClassName string `json:"className,omitempty"`
+	// Annotations are added to the Ingress. Most controllers need a long
+	// read timeout here to keep websockets open.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// WebSockTunnelGatewayRef refers to a Gateway an HTTPRoute attaches to.
+type WebSockTunnelGatewayRef struct {
+	Name string `json:"name"`
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	// +optional
+	SectionName string `json:"sectionName,omitempty"`
+}
+
+// WebSockTunnelHTTPRouteSpec configures the HTTPRoute used in HTTPRoute
+// mode.
+type WebSockTunnelHTTPRouteSpec struct {
+	ParentRefs []WebSockTunnelGatewayRef `json:"parentRefs"`
+	// Annotations are added to the HTTPRoute.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// WebSockTunnelAntiAffinity selects how strictly tunnel pods are kept on
+// separate nodes.
+// +kubebuilder:validation:Enum=Preferred;Required
+type WebSockTunnelAntiAffinity string
+
+const (
+	// WebSockTunnelAntiAffinityPreferred spreads pods across nodes where
+	// possible.
+	WebSockTunnelAntiAffinityPreferred WebSockTunnelAntiAffinity = "Preferred"
+
+	// WebSockTunnelAntiAffinityRequired never schedules two pods on the
+	// same node.
+	WebSockTunnelAntiAffinityRequired WebSockTunnelAntiAffinity = "Required"
+)
+
+// WebSockTunnelPodDisruptionBudgetSpec configures the PodDisruptionBudget
+// for the tunnel pods. Only one of the fields may be set.
+type WebSockTunnelPodDisruptionBudgetSpec struct {
+	// +optional
+	MinAvailable *intstr.IntOrString `json:"minAvailable,omitempty"`
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
+
+// WebSockTunnelEnvoySpec configures the envoy sidecar which terminates TLS
+// in LoadBalancer mode.
+type WebSockTunnelEnvoySpec struct {
+	// Image overrides the envoy image.
+	// +optional
+	Image string `json:"image,omitempty"`
+	// IdleTimeout closes tunnel connections with no traffic. Defaults to 1h.
+	// +optional
+	IdleTimeout *metav1.Duration `json:"idleTimeout,omitempty"`
+	// ConnectTimeout bounds connecting to the tunnel container. Defaults to
+	// 120s.
+	// +optional
+	ConnectTimeout *metav1.Duration `json:"connectTimeout,omitempty"`
+	// TLSMinimumVersion is the oldest TLS version accepted. Defaults to
+	// TLSv1_2.
+	// +kubebuilder:validation:Enum=TLSv1_2;TLSv1_3
+	// +optional
+	TLSMinimumVersion string `json:"tlsMinimumVersion,omitempty"`
+	// CipherSuites restricts the TLS 1.2 cipher suites offered, using
+	// BoringSSL names.
+	// +optional
+	CipherSuites []string `json:"cipherSuites,omitempty"`
+	// Resources of the envoy container.
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+// WebSockTunnelServiceMonitorSpec configures the Prometheus operator
+// ServiceMonitor for a tunnel.
+type WebSockTunnelServiceMonitorSpec struct {
+	// Interval between scrapes. Defaults to the Prometheus default.
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+	// Labels are added to the ServiceMonitor, typically to match the
+	// Prometheus serviceMonitorSelector.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// WebSockTunnelMetricsSpec configures metrics collection. Metrics are
+// served by the envoy sidecar, so are only available in LoadBalancer mode.
+type WebSockTunnelMetricsSpec struct {
+	// ServiceMonitor is created when set and the monitoring.coreos.com CRDs
+	// are installed.
+	// +optional
+	ServiceMonitor *WebSockTunnelServiceMonitorSpec `json:"serviceMonitor,omitempty"`
+}
+
 // WebSockTunnelSpec defines the desired state of WebSockTunnel
 type WebSockTunnelSpec struct {
 	DomainName string `json:"domainName"`
@@ -29,7 +165,45 @@ type WebSockTunnelSpec struct {
 	SecretRef            corev1.LocalObjectReference `json:"secretRef"`
 	CertificateIssuerRef cmmeta.ObjectReference      `json:"certificateIssuerRef"`
 
-	// TODO: Add rotation schedule here?
+	// RotationInterval is how often the tunnel secret is replaced. The
+	// previous secret remains valid until the next rotation. The secret is
+	// never rotated when unset.
+	// +optional
+	RotationInterval *metav1.Duration `json:"rotationInterval,omitempty"`
+
+	// Exposure selects how the tunnel is reachable. Defaults to
+	// LoadBalancer.
+	// +optional
+	Exposure WebSockTunnelExposure `json:"exposure,omitempty"`
+	// +optional
+	Service WebSockTunnelServiceSpec `json:"service,omitempty"`
+	// +optional
+	Ingress *WebSockTunnelIngressSpec `json:"ingress,omitempty"`
+	// +optional
+	HTTPRoute *WebSockTunnelHTTPRouteSpec `json:"httpRoute,omitempty"`
+	// +optional
+	Envoy WebSockTunnelEnvoySpec `json:"envoy,omitempty"`
+	// +optional
+	Metrics WebSockTunnelMetricsSpec `json:"metrics,omitempty"`
+
+	// Replicas is the number of tunnel pods. Defaults to 1.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+	// Resources of the tunnel container.
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+	// PodDisruptionBudget is created for the tunnel pods when set.
+	// +optional
+	PodDisruptionBudget *WebSockTunnelPodDisruptionBudgetSpec `json:"podDisruptionBudget,omitempty"`
+	// AntiAffinity spreads tunnel pods across nodes when set.
+	// +optional
+	AntiAffinity WebSockTunnelAntiAffinity `json:"antiAffinity,omitempty"`
+	// DrainPeriod is how long a terminating pod keeps serving its existing
+	// connections after it stops receiving new ones. Defaults to 30s. Zero
+	// disables the preStop hook.
+	// +optional
+	DrainPeriod *metav1.Duration `json:"drainPeriod,omitempty"`
 }
 
 // WebSockTunnelConditionType represents the type enum of a condition.
@@ -39,6 +213,10 @@ const (
 	// WebSockTunnelProgressing is used when the instance is not blocked by an
 	// external dependency or reconcile error.
 	WebSockTunnelProgressing WebSockTunnelConditionType = "Progressing"
+
+	// WebSockTunnelReady is used when the tunnel is able to serve
+	// connections.
+	WebSockTunnelReady WebSockTunnelConditionType = "Ready"
 )
 
 // WebSockTunnelCondition represents a condition of an Instance
@@ -61,10 +239,30 @@ type WebSockTunnelCondition struct {
 // WebSockTunnelStatus defines the observed state of WebSockTunnel
 type WebSockTunnelStatus struct {
 	Conditions []WebSockTunnelCondition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+
+	// URL is the address clients connect to the tunnel on.
+	// +optional
+	URL string `json:"url,omitempty"`
+	// ExternalAddress is the IP address or hostname assigned by the load
+	// balancer or ingress controller.
+	// +optional
+	ExternalAddress string `json:"externalAddress,omitempty"`
+	// CertificateExpiry is when the serving certificate expires.
+	// +optional
+	CertificateExpiry *metav1.Time `json:"certificateExpiry,omitempty"`
+	// +optional
+	LastRotationTime *metav1.Time `json:"lastRotationTime,omitempty"`
+	// +optional
+	NextRotationTime *metav1.Time `json:"nextRotationTime,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Address",type=string,JSONPath=`.status.externalAddress`
+// +kubebuilder:printcolumn:name="Certificate Expiry",type=date,JSONPath=`.status.certificateExpiry`
+// +kubebuilder:printcolumn:name="Last Rotation",type=date,JSONPath=`.status.lastRotationTime`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // WebSockTunnel is the Schema for the websocktunnels API
 type WebSockTunnel struct {
@@ -0,0 +1,104 @@
+/*
+Copyright 2020 Well Played Games Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// SetupWebhookWithManager registers the WebSockTunnel webhooks.
+func (r *WebSockTunnel) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-taskcluster-wellplayed-games-v1beta1-websocktunnel,mutating=false,failurePolicy=fail,groups=taskcluster.wellplayed.games,resources=websocktunnels,versions=v1beta1,name=vwebsocktunnel.taskcluster.wellplayed.games
+
+var _ webhook.Validator = &WebSockTunnel{}
+
+// ValidateCreate implements webhook.Validator
+func (r *WebSockTunnel) ValidateCreate() error {
+	return r.validate()
+}
+
+// ValidateUpdate implements webhook.Validator
+func (r *WebSockTunnel) ValidateUpdate(old runtime.Object) error {
+	return r.validate()
+}
+
+// ValidateDelete implements webhook.Validator
+func (r *WebSockTunnel) ValidateDelete() error {
+	return nil
+}
+
+func (r *WebSockTunnel) validate() error {
+	var errs field.ErrorList
+	spec := &r.Spec
+	path := field.NewPath("spec")
+
+	if msgs := validation.IsDNS1123Subdomain(spec.DomainName); len(msgs) > 0 {
+		errs = append(errs, field.Invalid(path.Child("domainName"), spec.DomainName, strings.Join(msgs, "; ")))
+	}
+
+	if spec.SecretRef.Name == "" {
+		errs = append(errs, field.Required(path.Child("secretRef", "name"), ""))
+	}
+
+	switch spec.Exposure {
+	case "", WebSockTunnelExposeLoadBalancer, WebSockTunnelExposeIngress:
+		if spec.CertificateIssuerRef.Name == "" {
+			errs = append(errs, field.Required(path.Child("certificateIssuerRef", "name"), "a certificate issuer is required unless exposure is HTTPRoute"))
+		}
+	case WebSockTunnelExposeHTTPRoute:
+		if spec.HTTPRoute == nil || len(spec.HTTPRoute.ParentRefs) == 0 {
+			errs = append(errs, field.Required(path.Child("httpRoute", "parentRefs"), "required when exposure is HTTPRoute"))
+		}
+	}
+
+	if pdb := spec.PodDisruptionBudget; pdb != nil && pdb.MinAvailable != nil && pdb.MaxUnavailable != nil {
+		errs = append(errs, field.Forbidden(path.Child("podDisruptionBudget"), "only one of minAvailable and maxUnavailable may be set"))
+	}
+
+	errs = append(errs, validatePositiveDuration(spec.RotationInterval, path.Child("rotationInterval"))...)
+	errs = append(errs, validatePositiveDuration(spec.Envoy.IdleTimeout, path.Child("envoy", "idleTimeout"))...)
+	errs = append(errs, validatePositiveDuration(spec.Envoy.ConnectTimeout, path.Child("envoy", "connectTimeout"))...)
+
+	if len(errs) > 0 {
+		return apierrors.NewInvalid(GroupVersion.WithKind("WebSockTunnel").GroupKind(), r.Name, errs)
+	}
+
+	return nil
+}
+
+// validatePositiveDuration checks that an optional duration is greater than
+// zero.
+func validatePositiveDuration(d *metav1.Duration, path *field.Path) field.ErrorList {
+	if d != nil && d.Duration <= 0 {
+		return field.ErrorList{field.Invalid(path, d.Duration.String(), "must be greater than zero")}
+	}
+
+	return nil
+}
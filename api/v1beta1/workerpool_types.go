@@ -0,0 +1,154 @@
+/*
+Copyright 2020 Well Played Games Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// WorkerPoolLifecycle configures how long workers may go without
+// registering.
+type WorkerPoolLifecycle struct {
+	// RegistrationTimeout is how long a worker may take to register, in
+	// seconds.
+	// +optional
+	RegistrationTimeout *int64 `json:"registrationTimeout,omitempty"`
+	// ReregistrationTimeout is how long a worker may run before it must
+	// reregister, in seconds.
+	// +optional
+	ReregistrationTimeout *int64 `json:"reregistrationTimeout,omitempty"`
+}
+
+// WorkerPoolLaunchConfig is a provider specific launch configuration.
+// +kubebuilder:pruning:PreserveUnknownFields
+type WorkerPoolLaunchConfig struct {
+	runtime.RawExtension `json:",inline"`
+}
+
+// WorkerPoolConfig is the provider independent part of a worker pool
+// configuration. Launch configs are passed to the provider unchanged.
+type WorkerPoolConfig struct {
+	// +kubebuilder:validation:Minimum=0
+	MinCapacity int32 `json:"minCapacity"`
+	// +kubebuilder:validation:Minimum=0
+	MaxCapacity int32 `json:"maxCapacity"`
+	// ScalingRatio is the number of new workers started per pending task,
+	// between 0 and 1.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=1
+	// +optional
+	ScalingRatio *float64 `json:"scalingRatio,omitempty"`
+	// +optional
+	Lifecycle *WorkerPoolLifecycle `json:"lifecycle,omitempty"`
+	// +optional
+	LaunchConfigs []WorkerPoolLaunchConfig `json:"launchConfigs,omitempty"`
+}
+
+// WorkerPoolSpec defines the desired state of WorkerPool
+type WorkerPoolSpec struct {
+	// InstanceRef is the Instance the worker pool is created in. The
+	// namespace defaults to that of the WorkerPool.
+	InstanceRef corev1.ObjectReference `json:"instanceRef"`
+
+	// +kubebuilder:validation:MinLength=1
+	WorkerPoolID string `json:"workerPoolId"`
+	// +kubebuilder:validation:MinLength=1
+	ProviderID   string `json:"providerId"`
+	Description  string `json:"description,omitempty"`
+	Owner        string `json:"owner"`
+	EmailOnError bool   `json:"emailOnError,omitempty"`
+
+	// Config is a typed worker pool configuration. Exactly one of Config
+	// and RawConfig must be set.
+	// +optional
+	Config *WorkerPoolConfig `json:"config,omitempty"`
+	// RawConfig is passed to worker-manager unchanged.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +optional
+	RawConfig *runtime.RawExtension `json:"rawConfig,omitempty"`
+
+	// CredentialsSecretRef names a Secret with clientId and accessToken
+	// keys to manage the pool with. The Instance's root client is used
+	// when unset.
+	// +optional
+	CredentialsSecretRef *corev1.LocalObjectReference `json:"credentialsSecretRef,omitempty"`
+}
+
+// WorkerPoolError is an error reported by worker-manager while
+// provisioning workers.
+type WorkerPoolError struct {
+	Reported    metav1.Time `json:"reported"`
+	Kind        string      `json:"kind,omitempty"`
+	Title       string      `json:"title,omitempty"`
+	Description string      `json:"description,omitempty"`
+}
+
+// WorkerPoolStatus defines the observed state of WorkerPool
+type WorkerPoolStatus struct {
+	Conditions         []SyncCondition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+	ObservedGeneration *int64          `json:"observedGeneration,omitempty"`
+
+	// WorkerPoolID is the ID of the worker pool last created in
+	// TaskCluster.
+	// +optional
+	WorkerPoolID string `json:"workerPoolId,omitempty"`
+	// +optional
+	CurrentCapacity int32 `json:"currentCapacity,omitempty"`
+	// +optional
+	RequestedCapacity int32 `json:"requestedCapacity,omitempty"`
+	// +optional
+	RunningCapacity int32 `json:"runningCapacity,omitempty"`
+	// +optional
+	StoppingCapacity int32 `json:"stoppingCapacity,omitempty"`
+	// RecentErrors are the latest errors reported for the pool.
+	// +optional
+	RecentErrors []WorkerPoolError `json:"recentErrors,omitempty"`
+	// +optional
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Pool",type=string,JSONPath=`.spec.workerPoolId`
+// +kubebuilder:printcolumn:name="Provider",type=string,JSONPath=`.spec.providerId`
+// +kubebuilder:printcolumn:name="Capacity",type=integer,JSONPath=`.status.currentCapacity`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// WorkerPool is the Schema for the workerpools API
+type WorkerPool struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   WorkerPoolSpec   `json:"spec,omitempty"`
+	Status WorkerPoolStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// WorkerPoolList contains a list of WorkerPool
+type WorkerPoolList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []WorkerPool `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&WorkerPool{}, &WorkerPoolList{})
+}
@@ -22,7 +22,9 @@ package v1beta1
 
 import (
 	"k8s.io/api/core/v1"
-	runtime "k8s.io/apimachinery/pkg/runtime"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -84,6 +86,119 @@ func (in *AccessTokenList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccessTokenPolicy) DeepCopyInto(out *AccessTokenPolicy) {
+	*out = *in
+	if in.AllowedClientIDPrefixes != nil {
+		in, out := &in.AllowedClientIDPrefixes, &out.AllowedClientIDPrefixes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessTokenPolicy.
+func (in *AccessTokenPolicy) DeepCopy() *AccessTokenPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(AccessTokenPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccessTokenRotationSpec) DeepCopyInto(out *AccessTokenRotationSpec) {
+	*out = *in
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.RestartSelector != nil {
+		in, out := &in.RestartSelector, &out.RestartSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessTokenRotationSpec.
+func (in *AccessTokenRotationSpec) DeepCopy() *AccessTokenRotationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AccessTokenRotationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccessTokenRotationStatus) DeepCopyInto(out *AccessTokenRotationStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastCompletionTime != nil {
+		in, out := &in.LastCompletionTime, &out.LastCompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessTokenRotationStatus.
+func (in *AccessTokenRotationStatus) DeepCopy() *AccessTokenRotationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AccessTokenRotationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccessTokenSecretKeys) DeepCopyInto(out *AccessTokenSecretKeys) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessTokenSecretKeys.
+func (in *AccessTokenSecretKeys) DeepCopy() *AccessTokenSecretKeys {
+	if in == nil {
+		return nil
+	}
+	out := new(AccessTokenSecretKeys)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccessTokenSecretTemplate) DeepCopyInto(out *AccessTokenSecretTemplate) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	out.Keys = in.Keys
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessTokenSecretTemplate.
+func (in *AccessTokenSecretTemplate) DeepCopy() *AccessTokenSecretTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(AccessTokenSecretTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AccessTokenSpec) DeepCopyInto(out *AccessTokenSpec) {
 	*out = *in
@@ -93,6 +208,21 @@ func (in *AccessTokenSpec) DeepCopyInto(out *AccessTokenSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Rotation != nil {
+		in, out := &in.Rotation, &out.Rotation
+		*out = new(AccessTokenRotationSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	in.SecretTemplate.DeepCopyInto(&out.SecretTemplate)
+	if in.Expires != nil {
+		in, out := &in.Expires, &out.Expires
+		*out = (*in).DeepCopy()
+	}
+	if in.ExpiresAfter != nil {
+		in, out := &in.ExpiresAfter, &out.ExpiresAfter
+		*out = new(metav1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessTokenSpec.
@@ -108,11 +238,31 @@ func (in *AccessTokenSpec) DeepCopy() *AccessTokenSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AccessTokenStatus) DeepCopyInto(out *AccessTokenStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]SyncCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.ObservedGeneration != nil {
 		in, out := &in.ObservedGeneration, &out.ObservedGeneration
 		*out = new(int64)
 		**out = **in
 	}
+	if in.InstanceRef != nil {
+		in, out := &in.InstanceRef, &out.InstanceRef
+		*out = new(v1.ObjectReference)
+		**out = **in
+	}
+	if in.LastRotationTime != nil {
+		in, out := &in.LastRotationTime, &out.LastRotationTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Expires != nil {
+		in, out := &in.Expires, &out.Expires
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessTokenStatus.
@@ -125,6 +275,62 @@ func (in *AccessTokenStatus) DeepCopy() *AccessTokenStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CryptoKeyRotationPolicy) DeepCopyInto(out *CryptoKeyRotationPolicy) {
+	*out = *in
+	out.RotationPolicy = in.RotationPolicy
+	if in.RetireAfter != nil {
+		in, out := &in.RetireAfter, &out.RetireAfter
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CryptoKeyRotationPolicy.
+func (in *CryptoKeyRotationPolicy) DeepCopy() *CryptoKeyRotationPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(CryptoKeyRotationPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CryptoKeyStatus) DeepCopyInto(out *CryptoKeyStatus) {
+	*out = *in
+	if in.KeyIDs != nil {
+		in, out := &in.KeyIDs, &out.KeyIDs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CryptoKeyStatus.
+func (in *CryptoKeyStatus) DeepCopy() *CryptoKeyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CryptoKeyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GCPKMSSpec) DeepCopyInto(out *GCPKMSSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GCPKMSSpec.
+func (in *GCPKMSSpec) DeepCopy() *GCPKMSSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GCPKMSSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GitHubSpec) DeepCopyInto(out *GitHubSpec) {
 	*out = *in
@@ -145,6 +351,151 @@ func (in *GitHubSpec) DeepCopy() *GitHubSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Hook) DeepCopyInto(out *Hook) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Hook.
+func (in *Hook) DeepCopy() *Hook {
+	if in == nil {
+		return nil
+	}
+	out := new(Hook)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Hook) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HookBinding) DeepCopyInto(out *HookBinding) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HookBinding.
+func (in *HookBinding) DeepCopy() *HookBinding {
+	if in == nil {
+		return nil
+	}
+	out := new(HookBinding)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HookList) DeepCopyInto(out *HookList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Hook, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HookList.
+func (in *HookList) DeepCopy() *HookList {
+	if in == nil {
+		return nil
+	}
+	out := new(HookList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HookList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HookSpec) DeepCopyInto(out *HookSpec) {
+	*out = *in
+	out.InstanceRef = in.InstanceRef
+	if in.Schedule != nil {
+		in, out := &in.Schedule, &out.Schedule
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.Task.DeepCopyInto(&out.Task)
+	if in.TriggerSchema != nil {
+		in, out := &in.TriggerSchema, &out.TriggerSchema
+		*out = new(runtime.RawExtension)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Bindings != nil {
+		in, out := &in.Bindings, &out.Bindings
+		*out = make([]HookBinding, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HookSpec.
+func (in *HookSpec) DeepCopy() *HookSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HookSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HookStatus) DeepCopyInto(out *HookStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]SyncCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ObservedGeneration != nil {
+		in, out := &in.ObservedGeneration, &out.ObservedGeneration
+		*out = new(int64)
+		**out = **in
+	}
+	if in.LastFireTime != nil {
+		in, out := &in.LastFireTime, &out.LastFireTime
+		*out = (*in).DeepCopy()
+	}
+	if in.NextScheduledTime != nil {
+		in, out := &in.NextScheduledTime, &out.NextScheduledTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastSyncTime != nil {
+		in, out := &in.LastSyncTime, &out.LastSyncTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HookStatus.
+func (in *HookStatus) DeepCopy() *HookStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HookStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Instance) DeepCopyInto(out *Instance) {
 	*out = *in
@@ -197,6 +548,13 @@ func (in *InstanceIngressSpec) DeepCopyInto(out *InstanceIngressSpec) {
 		**out = **in
 	}
 	out.IssuerRef = in.IssuerRef
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceIngressSpec.
@@ -244,6 +602,11 @@ func (in *InstanceList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *InstanceSpec) DeepCopyInto(out *InstanceSpec) {
 	*out = *in
+	if in.WebSockTunnelRef != nil {
+		in, out := &in.WebSockTunnelRef, &out.WebSockTunnelRef
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
 	if in.WebSockTunnelSecretRef != nil {
 		in, out := &in.WebSockTunnelSecretRef, &out.WebSockTunnelSecretRef
 		*out = new(v1.LocalObjectReference)
@@ -292,6 +655,13 @@ func (in *InstanceSpec) DeepCopyInto(out *InstanceSpec) {
 	in.GitHub.DeepCopyInto(&out.GitHub)
 	in.Pulse.DeepCopyInto(&out.Pulse)
 	in.Ingress.DeepCopyInto(&out.Ingress)
+	in.Rotation.DeepCopyInto(&out.Rotation)
+	if in.StateEncryption != nil {
+		in, out := &in.StateEncryption, &out.StateEncryption
+		*out = new(StateEncryptionSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	in.AccessTokens.DeepCopyInto(&out.AccessTokens)
 	if in.LoginStrategies != nil {
 		in, out := &in.LoginStrategies, &out.LoginStrategies
 		*out = make([]string, len(*in))
@@ -319,6 +689,14 @@ func (in *InstanceStatus) DeepCopyInto(out *InstanceStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.CryptoKeys != nil {
+		in, out := &in.CryptoKeys, &out.CryptoKeys
+		*out = make([]CryptoKeyStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.AccessTokenRotation.DeepCopyInto(&out.AccessTokenRotation)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceStatus.
@@ -339,6 +717,11 @@ func (in *PulseSpec) DeepCopyInto(out *PulseSpec) {
 		*out = new(v1.LocalObjectReference)
 		**out = **in
 	}
+	if in.UseGuestCredentials != nil {
+		in, out := &in.UseGuestCredentials, &out.UseGuestCredentials
+		*out = new(bool)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PulseSpec.
@@ -352,64 +735,527 @@ func (in *PulseSpec) DeepCopy() *PulseSpec {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *StaticAccessToken) DeepCopyInto(out *StaticAccessToken) {
+func (in *Role) DeepCopyInto(out *Role) {
 	*out = *in
-	if in.Scopes != nil {
-		in, out := &in.Scopes, &out.Scopes
-		*out = make([]string, len(*in))
-		copy(*out, *in)
-	}
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StaticAccessToken.
-func (in *StaticAccessToken) DeepCopy() *StaticAccessToken {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Role.
+func (in *Role) DeepCopy() *Role {
 	if in == nil {
 		return nil
 	}
-	out := new(StaticAccessToken)
+	out := new(Role)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Role) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *WebSockTunnel) DeepCopyInto(out *WebSockTunnel) {
+func (in *RoleList) DeepCopyInto(out *RoleList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
-	in.Status.DeepCopyInto(&out.Status)
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Role, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RoleList.
+func (in *RoleList) DeepCopy() *RoleList {
+	if in == nil {
+		return nil
+	}
+	out := new(RoleList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RoleList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RoleSpec) DeepCopyInto(out *RoleSpec) {
+	*out = *in
+	out.InstanceRef = in.InstanceRef
+	if in.Scopes != nil {
+		in, out := &in.Scopes, &out.Scopes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RoleSpec.
+func (in *RoleSpec) DeepCopy() *RoleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RoleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RoleStatus) DeepCopyInto(out *RoleStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]SyncCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ObservedGeneration != nil {
+		in, out := &in.ObservedGeneration, &out.ObservedGeneration
+		*out = new(int64)
+		**out = **in
+	}
+	if in.ExpandedScopes != nil {
+		in, out := &in.ExpandedScopes, &out.ExpandedScopes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastSyncTime != nil {
+		in, out := &in.LastSyncTime, &out.LastSyncTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RoleStatus.
+func (in *RoleStatus) DeepCopy() *RoleStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RoleStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RotationPolicy) DeepCopyInto(out *RotationPolicy) {
+	*out = *in
+	out.Interval = in.Interval
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RotationPolicy.
+func (in *RotationPolicy) DeepCopy() *RotationPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(RotationPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RotationSpec) DeepCopyInto(out *RotationSpec) {
+	*out = *in
+	if in.ServiceCredentials != nil {
+		in, out := &in.ServiceCredentials, &out.ServiceCredentials
+		*out = new(RotationPolicy)
+		**out = **in
+	}
+	if in.DBCryptoKeys != nil {
+		in, out := &in.DBCryptoKeys, &out.DBCryptoKeys
+		*out = new(CryptoKeyRotationPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AccessTokens != nil {
+		in, out := &in.AccessTokens, &out.AccessTokens
+		*out = new(RotationPolicy)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RotationSpec.
+func (in *RotationSpec) DeepCopy() *RotationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RotationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StateEncryptionSpec) DeepCopyInto(out *StateEncryptionSpec) {
+	*out = *in
+	if in.SecretKeyRef != nil {
+		in, out := &in.SecretKeyRef, &out.SecretKeyRef
+		*out = new(v1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.VaultTransit != nil {
+		in, out := &in.VaultTransit, &out.VaultTransit
+		*out = new(VaultTransitSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GCPKMS != nil {
+		in, out := &in.GCPKMS, &out.GCPKMS
+		*out = new(GCPKMSSpec)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StateEncryptionSpec.
+func (in *StateEncryptionSpec) DeepCopy() *StateEncryptionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(StateEncryptionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StaticAccessToken) DeepCopyInto(out *StaticAccessToken) {
+	*out = *in
+	if in.Scopes != nil {
+		in, out := &in.Scopes, &out.Scopes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StaticAccessToken.
+func (in *StaticAccessToken) DeepCopy() *StaticAccessToken {
+	if in == nil {
+		return nil
+	}
+	out := new(StaticAccessToken)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SyncCondition) DeepCopyInto(out *SyncCondition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SyncCondition.
+func (in *SyncCondition) DeepCopy() *SyncCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(SyncCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TaskClusterSecret) DeepCopyInto(out *TaskClusterSecret) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TaskClusterSecret.
+func (in *TaskClusterSecret) DeepCopy() *TaskClusterSecret {
+	if in == nil {
+		return nil
+	}
+	out := new(TaskClusterSecret)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TaskClusterSecret) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TaskClusterSecretKey) DeepCopyInto(out *TaskClusterSecretKey) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TaskClusterSecretKey.
+func (in *TaskClusterSecretKey) DeepCopy() *TaskClusterSecretKey {
+	if in == nil {
+		return nil
+	}
+	out := new(TaskClusterSecretKey)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TaskClusterSecretList) DeepCopyInto(out *TaskClusterSecretList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]TaskClusterSecret, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TaskClusterSecretList.
+func (in *TaskClusterSecretList) DeepCopy() *TaskClusterSecretList {
+	if in == nil {
+		return nil
+	}
+	out := new(TaskClusterSecretList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TaskClusterSecretList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TaskClusterSecretSpec) DeepCopyInto(out *TaskClusterSecretSpec) {
+	*out = *in
+	out.InstanceRef = in.InstanceRef
+	out.SourceRef = in.SourceRef
+	if in.Keys != nil {
+		in, out := &in.Keys, &out.Keys
+		*out = make([]TaskClusterSecretKey, len(*in))
+		copy(*out, *in)
+	}
+	if in.Expires != nil {
+		in, out := &in.Expires, &out.Expires
+		*out = (*in).DeepCopy()
+	}
+	if in.ExpiresAfter != nil {
+		in, out := &in.ExpiresAfter, &out.ExpiresAfter
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TaskClusterSecretSpec.
+func (in *TaskClusterSecretSpec) DeepCopy() *TaskClusterSecretSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TaskClusterSecretSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TaskClusterSecretStatus) DeepCopyInto(out *TaskClusterSecretStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]SyncCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ObservedGeneration != nil {
+		in, out := &in.ObservedGeneration, &out.ObservedGeneration
+		*out = new(int64)
+		**out = **in
+	}
+	if in.Expires != nil {
+		in, out := &in.Expires, &out.Expires
+		*out = (*in).DeepCopy()
+	}
+	if in.LastSyncTime != nil {
+		in, out := &in.LastSyncTime, &out.LastSyncTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TaskClusterSecretStatus.
+func (in *TaskClusterSecretStatus) DeepCopy() *TaskClusterSecretStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TaskClusterSecretStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultTransitSpec) DeepCopyInto(out *VaultTransitSpec) {
+	*out = *in
+	in.TokenSecretRef.DeepCopyInto(&out.TokenSecretRef)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultTransitSpec.
+func (in *VaultTransitSpec) DeepCopy() *VaultTransitSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultTransitSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebSockTunnel) DeepCopyInto(out *WebSockTunnel) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebSockTunnel.
+func (in *WebSockTunnel) DeepCopy() *WebSockTunnel {
+	if in == nil {
+		return nil
+	}
+	out := new(WebSockTunnel)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *WebSockTunnel) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebSockTunnelCondition) DeepCopyInto(out *WebSockTunnelCondition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebSockTunnelCondition.
+func (in *WebSockTunnelCondition) DeepCopy() *WebSockTunnelCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(WebSockTunnelCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebSockTunnelEnvoySpec) DeepCopyInto(out *WebSockTunnelEnvoySpec) {
+	*out = *in
+	if in.IdleTimeout != nil {
+		in, out := &in.IdleTimeout, &out.IdleTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.ConnectTimeout != nil {
+		in, out := &in.ConnectTimeout, &out.ConnectTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.CipherSuites != nil {
+		in, out := &in.CipherSuites, &out.CipherSuites
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(v1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebSockTunnelEnvoySpec.
+func (in *WebSockTunnelEnvoySpec) DeepCopy() *WebSockTunnelEnvoySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(WebSockTunnelEnvoySpec)
+	in.DeepCopyInto(out)
+	return out
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebSockTunnel.
-func (in *WebSockTunnel) DeepCopy() *WebSockTunnel {
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebSockTunnelGatewayRef) DeepCopyInto(out *WebSockTunnelGatewayRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebSockTunnelGatewayRef.
+func (in *WebSockTunnelGatewayRef) DeepCopy() *WebSockTunnelGatewayRef {
 	if in == nil {
 		return nil
 	}
-	out := new(WebSockTunnel)
+	out := new(WebSockTunnelGatewayRef)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *WebSockTunnel) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebSockTunnelHTTPRouteSpec) DeepCopyInto(out *WebSockTunnelHTTPRouteSpec) {
+	*out = *in
+	if in.ParentRefs != nil {
+		in, out := &in.ParentRefs, &out.ParentRefs
+		*out = make([]WebSockTunnelGatewayRef, len(*in))
+		copy(*out, *in)
 	}
-	return nil
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebSockTunnelHTTPRouteSpec.
+func (in *WebSockTunnelHTTPRouteSpec) DeepCopy() *WebSockTunnelHTTPRouteSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(WebSockTunnelHTTPRouteSpec)
+	in.DeepCopyInto(out)
+	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *WebSockTunnelCondition) DeepCopyInto(out *WebSockTunnelCondition) {
+func (in *WebSockTunnelIngressSpec) DeepCopyInto(out *WebSockTunnelIngressSpec) {
 	*out = *in
-	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebSockTunnelCondition.
-func (in *WebSockTunnelCondition) DeepCopy() *WebSockTunnelCondition {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebSockTunnelIngressSpec.
+func (in *WebSockTunnelIngressSpec) DeepCopy() *WebSockTunnelIngressSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(WebSockTunnelCondition)
+	out := new(WebSockTunnelIngressSpec)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -446,11 +1292,148 @@ func (in *WebSockTunnelList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebSockTunnelMetricsSpec) DeepCopyInto(out *WebSockTunnelMetricsSpec) {
+	*out = *in
+	if in.ServiceMonitor != nil {
+		in, out := &in.ServiceMonitor, &out.ServiceMonitor
+		*out = new(WebSockTunnelServiceMonitorSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebSockTunnelMetricsSpec.
+func (in *WebSockTunnelMetricsSpec) DeepCopy() *WebSockTunnelMetricsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(WebSockTunnelMetricsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebSockTunnelPodDisruptionBudgetSpec) DeepCopyInto(out *WebSockTunnelPodDisruptionBudgetSpec) {
+	*out = *in
+	if in.MinAvailable != nil {
+		in, out := &in.MinAvailable, &out.MinAvailable
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	if in.MaxUnavailable != nil {
+		in, out := &in.MaxUnavailable, &out.MaxUnavailable
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebSockTunnelPodDisruptionBudgetSpec.
+func (in *WebSockTunnelPodDisruptionBudgetSpec) DeepCopy() *WebSockTunnelPodDisruptionBudgetSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(WebSockTunnelPodDisruptionBudgetSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebSockTunnelServiceMonitorSpec) DeepCopyInto(out *WebSockTunnelServiceMonitorSpec) {
+	*out = *in
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebSockTunnelServiceMonitorSpec.
+func (in *WebSockTunnelServiceMonitorSpec) DeepCopy() *WebSockTunnelServiceMonitorSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(WebSockTunnelServiceMonitorSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebSockTunnelServiceSpec) DeepCopyInto(out *WebSockTunnelServiceSpec) {
+	*out = *in
+	if in.LoadBalancerSourceRanges != nil {
+		in, out := &in.LoadBalancerSourceRanges, &out.LoadBalancerSourceRanges
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebSockTunnelServiceSpec.
+func (in *WebSockTunnelServiceSpec) DeepCopy() *WebSockTunnelServiceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(WebSockTunnelServiceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WebSockTunnelSpec) DeepCopyInto(out *WebSockTunnelSpec) {
 	*out = *in
 	out.SecretRef = in.SecretRef
 	out.CertificateIssuerRef = in.CertificateIssuerRef
+	if in.RotationInterval != nil {
+		in, out := &in.RotationInterval, &out.RotationInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	in.Service.DeepCopyInto(&out.Service)
+	if in.Ingress != nil {
+		in, out := &in.Ingress, &out.Ingress
+		*out = new(WebSockTunnelIngressSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HTTPRoute != nil {
+		in, out := &in.HTTPRoute, &out.HTTPRoute
+		*out = new(WebSockTunnelHTTPRouteSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	in.Envoy.DeepCopyInto(&out.Envoy)
+	in.Metrics.DeepCopyInto(&out.Metrics)
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(v1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PodDisruptionBudget != nil {
+		in, out := &in.PodDisruptionBudget, &out.PodDisruptionBudget
+		*out = new(WebSockTunnelPodDisruptionBudgetSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DrainPeriod != nil {
+		in, out := &in.DrainPeriod, &out.DrainPeriod
+		*out = new(metav1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebSockTunnelSpec.
@@ -473,6 +1456,18 @@ func (in *WebSockTunnelStatus) DeepCopyInto(out *WebSockTunnelStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.CertificateExpiry != nil {
+		in, out := &in.CertificateExpiry, &out.CertificateExpiry
+		*out = (*in).DeepCopy()
+	}
+	if in.LastRotationTime != nil {
+		in, out := &in.LastRotationTime, &out.LastRotationTime
+		*out = (*in).DeepCopy()
+	}
+	if in.NextRotationTime != nil {
+		in, out := &in.NextRotationTime, &out.NextRotationTime
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebSockTunnelStatus.
@@ -484,3 +1479,220 @@ func (in *WebSockTunnelStatus) DeepCopy() *WebSockTunnelStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkerPool) DeepCopyInto(out *WorkerPool) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkerPool.
+func (in *WorkerPool) DeepCopy() *WorkerPool {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkerPool)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *WorkerPool) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkerPoolConfig) DeepCopyInto(out *WorkerPoolConfig) {
+	*out = *in
+	if in.ScalingRatio != nil {
+		in, out := &in.ScalingRatio, &out.ScalingRatio
+		*out = new(float64)
+		**out = **in
+	}
+	if in.Lifecycle != nil {
+		in, out := &in.Lifecycle, &out.Lifecycle
+		*out = new(WorkerPoolLifecycle)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LaunchConfigs != nil {
+		in, out := &in.LaunchConfigs, &out.LaunchConfigs
+		*out = make([]WorkerPoolLaunchConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkerPoolConfig.
+func (in *WorkerPoolConfig) DeepCopy() *WorkerPoolConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkerPoolConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkerPoolError) DeepCopyInto(out *WorkerPoolError) {
+	*out = *in
+	in.Reported.DeepCopyInto(&out.Reported)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkerPoolError.
+func (in *WorkerPoolError) DeepCopy() *WorkerPoolError {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkerPoolError)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkerPoolLaunchConfig) DeepCopyInto(out *WorkerPoolLaunchConfig) {
+	*out = *in
+	in.RawExtension.DeepCopyInto(&out.RawExtension)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkerPoolLaunchConfig.
+func (in *WorkerPoolLaunchConfig) DeepCopy() *WorkerPoolLaunchConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkerPoolLaunchConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkerPoolLifecycle) DeepCopyInto(out *WorkerPoolLifecycle) {
+	*out = *in
+	if in.RegistrationTimeout != nil {
+		in, out := &in.RegistrationTimeout, &out.RegistrationTimeout
+		*out = new(int64)
+		**out = **in
+	}
+	if in.ReregistrationTimeout != nil {
+		in, out := &in.ReregistrationTimeout, &out.ReregistrationTimeout
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkerPoolLifecycle.
+func (in *WorkerPoolLifecycle) DeepCopy() *WorkerPoolLifecycle {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkerPoolLifecycle)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkerPoolList) DeepCopyInto(out *WorkerPoolList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]WorkerPool, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkerPoolList.
+func (in *WorkerPoolList) DeepCopy() *WorkerPoolList {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkerPoolList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *WorkerPoolList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkerPoolSpec) DeepCopyInto(out *WorkerPoolSpec) {
+	*out = *in
+	out.InstanceRef = in.InstanceRef
+	if in.Config != nil {
+		in, out := &in.Config, &out.Config
+		*out = new(WorkerPoolConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RawConfig != nil {
+		in, out := &in.RawConfig, &out.RawConfig
+		*out = new(runtime.RawExtension)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CredentialsSecretRef != nil {
+		in, out := &in.CredentialsSecretRef, &out.CredentialsSecretRef
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkerPoolSpec.
+func (in *WorkerPoolSpec) DeepCopy() *WorkerPoolSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkerPoolSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkerPoolStatus) DeepCopyInto(out *WorkerPoolStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]SyncCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ObservedGeneration != nil {
+		in, out := &in.ObservedGeneration, &out.ObservedGeneration
+		*out = new(int64)
+		**out = **in
+	}
+	if in.RecentErrors != nil {
+		in, out := &in.RecentErrors, &out.RecentErrors
+		*out = make([]WorkerPoolError, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastSyncTime != nil {
+		in, out := &in.LastSyncTime, &out.LastSyncTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkerPoolStatus.
+func (in *WorkerPoolStatus) DeepCopy() *WorkerPoolStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkerPoolStatus)
+	in.DeepCopyInto(out)
+	return out
+}
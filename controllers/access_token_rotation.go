@@ -0,0 +1,181 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	taskclusterv1beta1 "github.com/wellplayedgames/taskcluster-operator/api/v1beta1"
+	"github.com/wellplayedgames/taskcluster-operator/pkg/pwgen"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	rotateAccessTokensAnnotation = fieldOwner + "/rotate-access-tokens"
+	secretChecksumAnnotation     = "checksum/secret"
+
+	rotationStageAuth    = "Auth"
+	rotationStageService = "Service"
+)
+
+// AccessTokenRotation tracks an in-progress rotation of the static service
+// access tokens.
+//
+// TaskCluster only allows one access token per static client, so each
+// service is rotated in turn: first auth is rolled out with the new token,
+// then the service itself.
+type AccessTokenRotation struct {
+	Started   time.Time `json:"started"`
+	Remaining []string  `json:"remaining,omitempty"`
+	Service   string    `json:"service,omitempty"`
+	Stage     string    `json:"stage,omitempty"`
+}
+
+// startAccessTokenRotation begins a rotation if one is due or has been
+// requested via annotation.
+func (o *TaskClusterOperations) startAccessTokenRotation() {
+	if o.state.AccessTokenRotation != nil {
+		return
+	}
+
+	policy := o.source.Spec.Rotation.AccessTokens
+	request := o.source.Annotations[rotateAccessTokensAnnotation]
+	requested := request != "" && request != o.state.AccessTokenRotationRequest
+
+	if !requested && !o.rotationDue(policy, o.state.AccessTokensRotated) {
+		o.state.AccessTokensRotated = o.trackRotation(policy, o.state.AccessTokensRotated)
+		return
+	}
+
+	o.Logger.Info("starting access token rotation")
+	o.state.AccessTokenRotationRequest = request
+	o.state.AccessTokenRotation = &AccessTokenRotation{
+		Started:   o.now,
+		Remaining: append([]string{}, accessTokenServices...),
+	}
+	o.nextAccessTokenRotation()
+}
+
+// nextAccessTokenRotation moves an in-progress rotation on to the next
+// service, finishing it when there are none left.
+func (o *TaskClusterOperations) nextAccessTokenRotation() {
+	rot := o.state.AccessTokenRotation
+
+	if len(rot.Remaining) == 0 {
+		o.Logger.Info("access token rotation complete")
+		now := o.now
+		o.state.AccessTokenRotation = nil
+		o.state.AccessTokensRotated = &now
+		return
+	}
+
+	rot.Service = rot.Remaining[0]
+	rot.Remaining = rot.Remaining[1:]
+	rot.Stage = rotationStageAuth
+
+	sa := o.ensureServiceAccount(rot.Service)
+	sa.PendingAccessToken = "TC" + pwgen.AlphaNumeric(22)
+}
+
+// ProgressAccessTokenRotation advances an in-progress rotation once the
+// deployments affected by the current stage have rolled out. It returns true
+// if the rotation is still in progress.
+func (o *TaskClusterOperations) ProgressAccessTokenRotation(ctx context.Context, objects []runtime.Object) (bool, error) {
+	rot := o.state.AccessTokenRotation
+	if rot == nil {
+		return false, nil
+	}
+
+	component := rot.Service
+	if rot.Stage == rotationStageAuth {
+		component = "auth"
+	}
+
+	ready, err := o.deploymentsRolledOut(ctx, objects, component)
+	if err != nil || !ready {
+		return true, err
+	}
+
+	sa := o.ensureServiceAccount(rot.Service)
+	switch rot.Stage {
+	case rotationStageAuth:
+		o.Logger.Info("auth has new access token", "service", rot.Service)
+		sa.AccessToken = sa.PendingAccessToken
+		sa.PendingAccessToken = ""
+		rot.Stage = rotationStageService
+
+	default:
+		o.Logger.Info("service has new access token", "service", rot.Service)
+		o.nextAccessTokenRotation()
+	}
+
+	if err := o.writeState(ctx); err != nil {
+		return true, err
+	}
+
+	return o.state.AccessTokenRotation != nil, nil
+}
+
+// deploymentsRolledOut returns true if every rendered deployment of a
+// service is running the rendered secret.
+func (o *TaskClusterOperations) deploymentsRolledOut(ctx context.Context, objects []runtime.Object, service string) (bool, error) {
+	appName := fmt.Sprintf("taskcluster-%s", strings.Replace(service, "_", "-", -1))
+
+	for _, obj := range objects {
+		desired, ok := obj.(*appsv1.Deployment)
+		if !ok || desired.Labels["app.kubernetes.io/name"] != appName {
+			continue
+		}
+
+		var live appsv1.Deployment
+		name := types.NamespacedName{
+			Namespace: desired.Namespace,
+			Name:      desired.Name,
+		}
+		if err := o.Client.Get(ctx, name, &live); err != nil {
+			return false, err
+		}
+
+		desiredChecksum := desired.Spec.Template.Annotations[secretChecksumAnnotation]
+		liveChecksum := live.Spec.Template.Annotations[secretChecksumAnnotation]
+		if desiredChecksum != liveChecksum || !deploymentRolledOut(&live) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func deploymentRolledOut(d *appsv1.Deployment) bool {
+	replicas := int32(1)
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+
+	return d.Status.ObservedGeneration >= d.Generation &&
+		d.Status.Replicas == replicas &&
+		d.Status.UpdatedReplicas == replicas &&
+		d.Status.AvailableReplicas == replicas
+}
+
+// AccessTokenRotationStatus returns the progress of the current access
+// token rotation.
+func (o *TaskClusterOperations) AccessTokenRotationStatus() taskclusterv1beta1.AccessTokenRotationStatus {
+	var status taskclusterv1beta1.AccessTokenRotationStatus
+
+	if t := o.state.AccessTokensRotated; t != nil {
+		status.LastCompletionTime = &metav1.Time{Time: *t}
+	}
+
+	if rot := o.state.AccessTokenRotation; rot != nil {
+		status.Service = rot.Service
+		status.Stage = rot.Stage
+		status.StartTime = &metav1.Time{Time: rot.Started}
+	}
+
+	return status
+}
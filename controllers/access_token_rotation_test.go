@@ -0,0 +1,359 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	taskclusterv1beta1 "github.com/wellplayedgames/taskcluster-operator/api/v1beta1"
+)
+
+var testNow = time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// newTestOperations returns operations for the Instance taskcluster/tc with
+// a static access token for every service.
+func newTestOperations(objs ...runtime.Object) *TaskClusterOperations {
+	o := &TaskClusterOperations{
+		Logger:         logf.NullLogger{},
+		Client:         newFakeClient(objs...),
+		Scheme:         newTestScheme(),
+		NamespacedName: types.NamespacedName{Namespace: "taskcluster", Name: "tc"},
+		now:            testNow,
+	}
+	o.source.Namespace = o.Namespace
+	o.source.Name = o.Name
+	o.state.ServiceAccounts = map[string]*ServiceAccount{}
+	for _, svc := range accessTokenServices {
+		o.ensureAccessToken(svc)
+	}
+
+	return o
+}
+
+func TestStartAccessTokenRotation(t *testing.T) {
+	daily := &taskclusterv1beta1.RotationPolicy{Interval: metav1.Duration{Duration: 24 * time.Hour}}
+	longAgo := testNow.Add(-48 * time.Hour)
+	recently := testNow.Add(-time.Hour)
+
+	tests := []struct {
+		name        string
+		policy      *taskclusterv1beta1.RotationPolicy
+		rotated     *time.Time
+		annotation  string
+		lastRequest string
+		inProgress  bool
+
+		started      bool
+		lastRotated  *time.Time
+		nextRotation time.Time
+	}{
+		{
+			name:        "no policy starts the clock",
+			lastRotated: &testNow,
+		},
+		{
+			name:         "not yet due",
+			policy:       daily,
+			rotated:      &recently,
+			lastRotated:  &recently,
+			nextRotation: recently.Add(24 * time.Hour),
+		},
+		{
+			name:    "due",
+			policy:  daily,
+			rotated: &longAgo,
+			started: true,
+		},
+		{
+			name:       "requested",
+			rotated:    &recently,
+			annotation: "1",
+			started:    true,
+		},
+		{
+			name:        "already requested",
+			rotated:     &recently,
+			annotation:  "1",
+			lastRequest: "1",
+			lastRotated: &recently,
+		},
+		{
+			name:       "in progress",
+			policy:     daily,
+			rotated:    &longAgo,
+			annotation: "1",
+			inProgress: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newTestOperations()
+			o.source.Spec.Rotation.AccessTokens = tt.policy
+			o.source.Annotations = map[string]string{}
+			if tt.annotation != "" {
+				o.source.Annotations[rotateAccessTokensAnnotation] = tt.annotation
+			}
+			o.state.AccessTokensRotated = tt.rotated
+			o.state.AccessTokenRotationRequest = tt.lastRequest
+
+			var existing *AccessTokenRotation
+			if tt.inProgress {
+				existing = &AccessTokenRotation{Service: "queue", Stage: rotationStageService}
+				o.state.AccessTokenRotation = existing
+			}
+
+			before := o.state.ServiceAccounts[accessTokenServices[0]].AccessToken
+			o.startAccessTokenRotation()
+			rot := o.state.AccessTokenRotation
+
+			switch {
+			case tt.inProgress:
+				if rot != existing || rot.Service != "queue" || rot.Stage != rotationStageService {
+					t.Fatalf("rotation in progress was changed: %+v", rot)
+				}
+
+			case tt.started:
+				if rot == nil {
+					t.Fatal("rotation was not started")
+				}
+				if !rot.Started.Equal(testNow) || rot.Service != accessTokenServices[0] || rot.Stage != rotationStageAuth {
+					t.Errorf("unexpected rotation %+v", rot)
+				}
+				if len(rot.Remaining) != len(accessTokenServices)-1 {
+					t.Errorf("expected %d remaining services, got %v", len(accessTokenServices)-1, rot.Remaining)
+				}
+				if o.state.AccessTokenRotationRequest != tt.annotation {
+					t.Errorf("expected request %q to be recorded, got %q", tt.annotation, o.state.AccessTokenRotationRequest)
+				}
+
+				sa := o.state.ServiceAccounts[rot.Service]
+				if sa.AccessToken != before || sa.PendingAccessToken == "" || sa.PendingAccessToken == before {
+					t.Errorf("expected a new pending token alongside the current one, got %+v", sa)
+				}
+
+			default:
+				if rot != nil {
+					t.Fatalf("unexpected rotation %+v", rot)
+				}
+				if o.state.AccessTokensRotated == nil || !o.state.AccessTokensRotated.Equal(*tt.lastRotated) {
+					t.Errorf("expected last rotation %v, got %v", tt.lastRotated, o.state.AccessTokensRotated)
+				}
+				if !o.nextRotation.Equal(tt.nextRotation) {
+					t.Errorf("expected next rotation %v, got %v", tt.nextRotation, o.nextRotation)
+				}
+			}
+		})
+	}
+}
+
+func TestNextAccessTokenRotation(t *testing.T) {
+	t.Run("moves to the next service", func(t *testing.T) {
+		o := newTestOperations()
+		o.state.AccessTokenRotation = &AccessTokenRotation{
+			Started:   testNow.Add(-time.Hour),
+			Service:   "github",
+			Stage:     rotationStageService,
+			Remaining: []string{"hooks", "index"},
+		}
+
+		o.nextAccessTokenRotation()
+
+		rot := o.state.AccessTokenRotation
+		if rot.Service != "hooks" || rot.Stage != rotationStageAuth || len(rot.Remaining) != 1 || rot.Remaining[0] != "index" {
+			t.Errorf("unexpected rotation %+v", rot)
+		}
+		if o.state.ServiceAccounts["hooks"].PendingAccessToken == "" {
+			t.Error("expected a pending token for hooks")
+		}
+		if o.state.ServiceAccounts["index"].PendingAccessToken != "" {
+			t.Error("expected no pending token for index yet")
+		}
+	})
+
+	t.Run("finishes after the last service", func(t *testing.T) {
+		o := newTestOperations()
+		o.state.AccessTokenRotation = &AccessTokenRotation{
+			Started: testNow.Add(-time.Hour),
+			Service: "worker_manager",
+			Stage:   rotationStageService,
+		}
+
+		o.nextAccessTokenRotation()
+
+		if o.state.AccessTokenRotation != nil {
+			t.Errorf("expected rotation to finish, got %+v", o.state.AccessTokenRotation)
+		}
+		if o.state.AccessTokensRotated == nil || !o.state.AccessTokensRotated.Equal(testNow) {
+			t.Errorf("expected completion at %v, got %v", testNow, o.state.AccessTokensRotated)
+		}
+	})
+}
+
+// testDeployment returns a deployment of a TaskCluster service running the
+// secret with the given checksum. If rolledOut is false, its pods have not
+// yet been updated.
+func testDeployment(service, checksum string, rolledOut bool) *appsv1.Deployment {
+	replicas := int32(2)
+	d := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:  "taskcluster",
+			Name:       "taskcluster-" + service + "-web",
+			Labels:     map[string]string{"app.kubernetes.io/name": "taskcluster-" + service},
+			Generation: 2,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{secretChecksumAnnotation: checksum},
+				},
+			},
+		},
+		Status: appsv1.DeploymentStatus{
+			ObservedGeneration: 2,
+			Replicas:           replicas,
+			UpdatedReplicas:    replicas,
+			AvailableReplicas:  replicas,
+		},
+	}
+
+	if !rolledOut {
+		d.Status.UpdatedReplicas = 1
+	}
+
+	return d
+}
+
+func TestProgressAccessTokenRotation(t *testing.T) {
+	// Rendered deployments carry the checksum of the secret being rolled
+	// out.
+	rendered := []runtime.Object{
+		testDeployment("auth", "new", true),
+		testDeployment("github", "new", true),
+	}
+
+	tests := []struct {
+		name      string
+		rotation  *AccessTokenRotation
+		live      []runtime.Object
+		remaining bool
+
+		service string
+		stage   string
+		swapped bool
+	}{
+		{
+			name: "no rotation",
+		},
+		{
+			name:      "auth has not picked up the new secret",
+			rotation:  &AccessTokenRotation{Service: "github", Stage: rotationStageAuth, Remaining: []string{"hooks"}},
+			live:      []runtime.Object{testDeployment("auth", "old", true), testDeployment("github", "old", true)},
+			remaining: true,
+			service:   "github",
+			stage:     rotationStageAuth,
+		},
+		{
+			name:      "auth is still rolling out",
+			rotation:  &AccessTokenRotation{Service: "github", Stage: rotationStageAuth, Remaining: []string{"hooks"}},
+			live:      []runtime.Object{testDeployment("auth", "new", false), testDeployment("github", "old", true)},
+			remaining: true,
+			service:   "github",
+			stage:     rotationStageAuth,
+		},
+		{
+			name:      "auth rolled out",
+			rotation:  &AccessTokenRotation{Service: "github", Stage: rotationStageAuth, Remaining: []string{"hooks"}},
+			live:      []runtime.Object{testDeployment("auth", "new", true), testDeployment("github", "old", true)},
+			remaining: true,
+			service:   "github",
+			stage:     rotationStageService,
+			swapped:   true,
+		},
+		{
+			name:      "service is still rolling out",
+			rotation:  &AccessTokenRotation{Service: "github", Stage: rotationStageService, Remaining: []string{"hooks"}},
+			live:      []runtime.Object{testDeployment("auth", "new", true), testDeployment("github", "new", false)},
+			remaining: true,
+			service:   "github",
+			stage:     rotationStageService,
+		},
+		{
+			name:      "service rolled out",
+			rotation:  &AccessTokenRotation{Service: "github", Stage: rotationStageService, Remaining: []string{"hooks"}},
+			live:      []runtime.Object{testDeployment("auth", "new", true), testDeployment("github", "new", true)},
+			remaining: true,
+			service:   "hooks",
+			stage:     rotationStageAuth,
+		},
+		{
+			name:     "last service rolled out",
+			rotation: &AccessTokenRotation{Service: "github", Stage: rotationStageService},
+			live:     []runtime.Object{testDeployment("auth", "new", true), testDeployment("github", "new", true)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			o := newTestOperations(tt.live...)
+			o.state.AccessTokenRotation = tt.rotation
+
+			sa := o.state.ServiceAccounts["github"]
+			oldToken := sa.AccessToken
+			authStage := tt.rotation != nil && tt.rotation.Stage == rotationStageAuth
+			if authStage {
+				sa.PendingAccessToken = "TCpending"
+			}
+
+			remaining, err := o.ProgressAccessTokenRotation(ctx, rendered)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if remaining != tt.remaining {
+				t.Errorf("expected in progress %v, got %v", tt.remaining, remaining)
+			}
+
+			rot := o.state.AccessTokenRotation
+			if tt.service == "" {
+				if rot != nil {
+					t.Errorf("expected no rotation, got %+v", rot)
+				}
+			} else if rot == nil || rot.Service != tt.service || rot.Stage != tt.stage {
+				t.Errorf("expected %s/%s, got %+v", tt.service, tt.stage, rot)
+			}
+
+			if tt.swapped {
+				if sa.AccessToken != "TCpending" || sa.PendingAccessToken != "" {
+					t.Errorf("expected the pending token to become current, got %+v", sa)
+				}
+
+				var secret corev1.Secret
+				if err := o.Client.Get(ctx, types.NamespacedName{Namespace: "taskcluster", Name: "tc-state"}, &secret); err != nil {
+					t.Fatal(err)
+				}
+
+				var state TaskClusterState
+				if err := json.Unmarshal(secret.Data[stateKey], &state); err != nil {
+					t.Fatal(err)
+				}
+				if state.ServiceAccounts["github"].AccessToken != "TCpending" {
+					t.Error("expected the new token to be saved in the state")
+				}
+			} else if authStage {
+				if sa.AccessToken != oldToken || sa.PendingAccessToken != "TCpending" {
+					t.Errorf("expected the token not to change yet, got %+v", sa)
+				}
+			}
+		})
+	}
+}
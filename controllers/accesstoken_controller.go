@@ -0,0 +1,531 @@
+/*
+Copyright 2020 Well Played Games Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/wellplayedgames/taskcluster-operator/pkg/tcclient"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	taskclusterv1beta1 "github.com/wellplayedgames/taskcluster-operator/api/v1beta1"
+)
+
+const (
+	// dynamicClientYears is how many years dynamic clients are created for.
+	dynamicClientYears = 100
+
+	// rotateAccessTokenAnnotation regenerates an AccessToken whenever its
+	// value changes.
+	rotateAccessTokenAnnotation = fieldOwner + "/rotate"
+	// defaultRestartAnnotation is bumped on Deployments consuming a rotated
+	// AccessToken.
+	defaultRestartAnnotation = fieldOwner + "/access-token-rotated"
+
+	// accessTokenRotatingReason is the Ready reason of a static AccessToken
+	// whose consumers are restarted once auth accepts its new token.
+	accessTokenRotatingReason = "Rotating"
+)
+
+// isDynamicAccessToken returns true if the token is issued through the auth
+// API rather than as a static client.
+func isDynamicAccessToken(token *taskclusterv1beta1.AccessToken) bool {
+	return token.Spec.Mode == taskclusterv1beta1.AccessTokenDynamic
+}
+
+// accessTokenRotationDue returns true if the token has outlived its rotation
+// interval or a rotation has been requested through its annotation.
+func accessTokenRotationDue(token *taskclusterv1beta1.AccessToken, now time.Time) bool {
+	request := token.Annotations[rotateAccessTokenAnnotation]
+	if request != "" && request != token.Status.RotationRequest {
+		return true
+	}
+
+	next := nextAccessTokenRotation(token)
+	return !next.IsZero() && !now.Before(next)
+}
+
+// nextAccessTokenRotation returns when the token is next due to be rotated,
+// or zero if it has no rotation interval.
+func nextAccessTokenRotation(token *taskclusterv1beta1.AccessToken) time.Time {
+	rot := token.Spec.Rotation
+	last := token.Status.LastRotationTime
+	if rot == nil || rot.Interval == nil || rot.Interval.Duration <= 0 || last == nil {
+		return time.Time{}
+	}
+
+	return last.Add(rot.Interval.Duration)
+}
+
+// recordAccessTokenRotation records in the token's status that a new access
+// token was issued.
+func recordAccessTokenRotation(token *taskclusterv1beta1.AccessToken, now time.Time) {
+	token.Status.LastRotationTime = &metav1.Time{Time: now}
+	token.Status.RotationRequest = token.Annotations[rotateAccessTokenAnnotation]
+}
+
+// restartAccessTokenConsumers changes the restart annotation of the
+// Deployments selected by the token's rotation policy so that their pods
+// pick up the new token.
+func restartAccessTokenConsumers(ctx context.Context, c client.Client, token *taskclusterv1beta1.AccessToken, now time.Time) error {
+	rot := token.Spec.Rotation
+	if rot == nil || rot.RestartSelector == nil {
+		return nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(rot.RestartSelector)
+	if err != nil {
+		return err
+	}
+
+	annotation := rot.RestartAnnotation
+	if annotation == "" {
+		annotation = defaultRestartAnnotation
+	}
+
+	var deployments appsv1.DeploymentList
+	if err := c.List(ctx, &deployments, client.InNamespace(token.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return err
+	}
+
+	for idx := range deployments.Items {
+		deployment := &deployments.Items[idx]
+		patch := client.MergeFrom(deployment.DeepCopy())
+
+		if deployment.Spec.Template.Annotations == nil {
+			deployment.Spec.Template.Annotations = map[string]string{}
+		}
+		deployment.Spec.Template.Annotations[annotation] = now.Format(time.RFC3339)
+
+		if err := c.Patch(ctx, deployment, patch); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateAccessToken returns an error if an AccessToken cannot be issued
+// by an Instance.
+func validateAccessToken(token *taskclusterv1beta1.AccessToken, instance *taskclusterv1beta1.Instance) error {
+	clientID := token.Spec.ClientID
+	if err := tcclient.ValidateClientID(clientID); err != nil {
+		return err
+	}
+
+	static := strings.HasPrefix(clientID, taskclusterv1beta1.StaticClientIDPrefix)
+	if strings.HasPrefix(clientID, taskclusterv1beta1.ServiceClientIDPrefix) {
+		return fmt.Errorf("client ID %s is reserved for the TaskCluster services", clientID)
+	} else if isDynamicAccessToken(token) && static {
+		return fmt.Errorf("client ID %s is reserved for static clients", clientID)
+	} else if !isDynamicAccessToken(token) && !static {
+		return fmt.Errorf("static client ID %s must start with %s", clientID, taskclusterv1beta1.StaticClientIDPrefix)
+	}
+
+	if prefixes := instance.Spec.AccessTokens.AllowedClientIDPrefixes; len(prefixes) > 0 {
+		allowed := false
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(clientID, prefix) {
+				allowed = true
+				break
+			}
+		}
+
+		if !allowed {
+			return fmt.Errorf("client ID %s does not start with an allowed prefix: %s", clientID, strings.Join(prefixes, ", "))
+		}
+	}
+
+	for _, scope := range token.Spec.Scopes {
+		if err := tcclient.ValidateScope(scope); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// instanceReference returns a reference to an Instance.
+func instanceReference(instance *taskclusterv1beta1.Instance) *corev1.ObjectReference {
+	return &corev1.ObjectReference{
+		APIVersion: taskclusterv1beta1.GroupVersion.String(),
+		Kind:       "Instance",
+		Namespace:  instance.Namespace,
+		Name:       instance.Name,
+		UID:        instance.UID,
+	}
+}
+
+// accessTokenReady returns true if the AccessToken's Ready condition is
+// True.
+func accessTokenReady(token *taskclusterv1beta1.AccessToken) bool {
+	for _, c := range token.Status.Conditions {
+		if c.Type == taskclusterv1beta1.SyncReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+
+	return false
+}
+
+// accessTokenRestartPending returns true if a static AccessToken was rotated
+// and its consumers have not yet been restarted.
+func accessTokenRestartPending(token *taskclusterv1beta1.AccessToken) bool {
+	for _, c := range token.Status.Conditions {
+		if c.Type == taskclusterv1beta1.SyncReady {
+			return c.Status != corev1.ConditionTrue && c.Reason == accessTokenRotatingReason
+		}
+	}
+
+	return false
+}
+
+// setAccessTokenReady sets the Ready condition of an AccessToken, returning
+// true if it changed.
+func setAccessTokenReady(token *taskclusterv1beta1.AccessToken, status corev1.ConditionStatus, reason, message string, now time.Time) bool {
+	before := append([]taskclusterv1beta1.SyncCondition{}, token.Status.Conditions...)
+	setSyncCondition(&token.Status.Conditions, taskclusterv1beta1.SyncCondition{
+		Type:               taskclusterv1beta1.SyncReady,
+		LastTransitionTime: metav1.Time{Time: now},
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+	})
+
+	return !equality.Semantic.DeepEqual(before, token.Status.Conditions)
+}
+
+// AccessTokenReconciler reconciles AccessTokens in Dynamic mode. Static
+// tokens are rendered into the auth service by the InstanceReconciler.
+type AccessTokenReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+
+	// Clients returns the TaskCluster client used for an Instance. It
+	// defaults to RootClients.
+	Clients TaskClusterClientFunc
+
+	// now returns the current time. It defaults to time.Now.
+	now func() time.Time
+}
+
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+func (r *AccessTokenReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	logger := r.Log.WithValues("accesstoken", req.NamespacedName)
+
+	var token taskclusterv1beta1.AccessToken
+	if err := r.Client.Get(ctx, req.NamespacedName, &token); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	instanceName := instanceRefName(&token.Spec.InstanceRef, token.Namespace)
+
+	// Remove the dynamic client if the token is deleted or switched back to
+	// a static client.
+	if (token.DeletionTimestamp != nil || !isDynamicAccessToken(&token)) && hasFinalizer(token.Finalizers, taskClusterFinalizer) {
+		if err := r.deleteClient(ctx, instanceName, token.Status.ClientID); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		logger.Info("deleted client", "clientId", token.Status.ClientID)
+		token.Finalizers = removeFinalizer(token.Finalizers, taskClusterFinalizer)
+		if err := r.Client.Update(ctx, &token); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		if token.DeletionTimestamp == nil {
+			token.Status.ClientID = ""
+			if err := r.Client.Status().Update(ctx, &token); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+	}
+
+	if token.DeletionTimestamp != nil {
+		return ctrl.Result{}, nil
+	}
+
+	now := time.Now()
+	if r.now != nil {
+		now = r.now()
+	}
+
+	if accessTokenExpired(&token, now) {
+		return ctrl.Result{}, r.expireAccessToken(ctx, &token, instanceName, now)
+	}
+
+	if !isDynamicAccessToken(&token) {
+		return r.reconcileStatic(ctx, &token, instanceName, now)
+	}
+
+	if !hasFinalizer(token.Finalizers, taskClusterFinalizer) {
+		token.Finalizers = append(token.Finalizers, taskClusterFinalizer)
+		if err := r.Client.Update(ctx, &token); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	ready := taskclusterv1beta1.SyncCondition{
+		Type:               taskclusterv1beta1.SyncReady,
+		LastTransitionTime: metav1.Time{Time: now},
+		Status:             corev1.ConditionFalse,
+		Reason:             "Unknown",
+	}
+	defer func() {
+		setSyncCondition(&token.Status.Conditions, ready)
+
+		err := r.Client.Status().Update(ctx, &token)
+		if err != nil {
+			logger.Error(err, "failed to update status")
+		}
+	}()
+
+	nextExpiry := r.trackAccessTokenExpiry(&token, now)
+
+	var instance taskclusterv1beta1.Instance
+	if err := r.Client.Get(ctx, instanceName, &instance); apierrors.IsNotFound(err) {
+		ready.Reason = "InstanceNotFound"
+		ready.Message = fmt.Sprintf("Instance %s does not exist", instanceName)
+		return ctrl.Result{}, nil
+	} else if err != nil {
+		ready.Reason = "InstanceUnavailable"
+		ready.Message = err.Error()
+		return ctrl.Result{}, err
+	}
+	token.Status.InstanceRef = instanceReference(&instance)
+
+	if err := validateAccessToken(&token, &instance); err != nil {
+		ready.Reason = "InvalidSpec"
+		ready.Message = err.Error()
+		return ctrl.Result{}, nil
+	}
+
+	tc, err := r.Clients(ctx, instanceName)
+	if err != nil {
+		ready.Reason = "InstanceUnavailable"
+		ready.Message = err.Error()
+		return ctrl.Result{}, err
+	}
+
+	// Remove the previous client if the client ID changed.
+	if previous := token.Status.ClientID; previous != "" && previous != token.Spec.ClientID {
+		if err := tc.DeleteAuthClient(ctx, previous); err != nil && !tcclient.IsNotFound(err) {
+			ready.Reason = "DeleteFailed"
+			ready.Message = err.Error()
+			return ctrl.Result{}, err
+		}
+	}
+
+	secret, err := getAccessTokenSecret(ctx, r.Client, &token)
+	if err != nil {
+		ready.Reason = "SecretUnavailable"
+		ready.Message = err.Error()
+		return ctrl.Result{}, err
+	}
+
+	clientID, accessToken := readAccessTokenSecret(&token, secret)
+	hasToken := clientID == token.Spec.ClientID && accessToken != ""
+
+	reset := !hasToken || accessTokenRotationDue(&token, now)
+	issued, err := r.syncClient(ctx, tc, &token.Spec, accessTokenExpiry(&token), reset, now)
+	if err != nil {
+		ready.Reason = "SyncFailed"
+		ready.Message = err.Error()
+		return ctrl.Result{}, err
+	}
+
+	if issued != "" {
+		logger.Info("issued access token", "clientId", token.Spec.ClientID)
+		accessToken = issued
+	}
+
+	if err := writeAccessTokenSecret(ctx, r.Client, r.Scheme, &token, secret, accessToken, tc.RootURL); err != nil {
+		ready.Reason = "SecretFailed"
+		ready.Message = err.Error()
+		return ctrl.Result{}, err
+	}
+
+	if issued != "" && token.Status.Created {
+		if err := restartAccessTokenConsumers(ctx, r.Client, &token, now); err != nil {
+			ready.Reason = "RestartFailed"
+			ready.Message = err.Error()
+			return ctrl.Result{}, err
+		}
+	}
+
+	if issued != "" || token.Status.LastRotationTime == nil {
+		recordAccessTokenRotation(&token, now)
+	}
+
+	token.Status.Created = true
+	token.Status.ClientID = token.Spec.ClientID
+	token.Status.SecretName = accessTokenSecretName(&token).Name
+	token.Status.ObservedGeneration = &token.Generation
+
+	ready.Status = corev1.ConditionTrue
+	ready.Reason = "Synced"
+
+	requeueAfter := taskClusterResyncPeriod
+	for _, next := range []time.Time{nextAccessTokenRotation(&token), nextExpiry} {
+		if !next.IsZero() && next.Sub(now) < requeueAfter {
+			requeueAfter = next.Sub(now)
+		}
+	}
+
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// reconcileStatic reports a static token whose Instance does not exist and
+// tracks its expiry. Otherwise static tokens are handled by the
+// InstanceReconciler.
+func (r *AccessTokenReconciler) reconcileStatic(ctx context.Context, token *taskclusterv1beta1.AccessToken, instanceName types.NamespacedName, now time.Time) (ctrl.Result, error) {
+	before := token.Status.DeepCopy()
+	nextExpiry := r.trackAccessTokenExpiry(token, now)
+
+	var instance taskclusterv1beta1.Instance
+	if err := r.Client.Get(ctx, instanceName, &instance); apierrors.IsNotFound(err) {
+		message := fmt.Sprintf("Instance %s does not exist", instanceName)
+		setAccessTokenReady(token, corev1.ConditionFalse, "InstanceNotFound", message, now)
+		token.Status.InstanceRef = nil
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if !equality.Semantic.DeepEqual(before, &token.Status) {
+		if err := r.Client.Status().Update(ctx, token); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if nextExpiry.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	return ctrl.Result{RequeueAfter: nextExpiry.Sub(now)}, nil
+}
+
+// syncClient creates the client or updates it if it differs from the spec.
+// It returns a new access token if one was issued, which happens when the
+// client is created or when reset is set. The client expires at expires, or
+// far in the future if it is zero.
+func (r *AccessTokenReconciler) syncClient(ctx context.Context, tc *tcclient.Client, spec *taskclusterv1beta1.AccessTokenSpec, expires time.Time, reset bool, now time.Time) (string, error) {
+	desired := &tcclient.AuthClientRequest{
+		Description: spec.Description,
+		Scopes:      spec.Scopes,
+	}
+	if desired.Scopes == nil {
+		desired.Scopes = []string{}
+	}
+
+	desiredExpires := expires
+	if desiredExpires.IsZero() {
+		desiredExpires = now.AddDate(dynamicClientYears, 0, 0)
+	}
+
+	remote, err := tc.AuthClient(ctx, spec.ClientID)
+	if tcclient.IsNotFound(err) {
+		desired.Expires = desiredExpires
+		created, err := tc.CreateAuthClient(ctx, spec.ClientID, desired)
+		if err != nil {
+			return "", err
+		}
+
+		return created.AccessToken, nil
+	} else if err != nil {
+		return "", err
+	}
+
+	// Clients without an expiry are pushed back once half of their
+	// lifetime has elapsed.
+	expiresChanged := !remote.Expires.Equal(expires)
+	if expires.IsZero() {
+		expiresChanged = remote.Expires.Before(now.AddDate(dynamicClientYears/2, 0, 0))
+	}
+
+	if remote.Description != desired.Description || !sameScopes(remote.Scopes, desired.Scopes) || expiresChanged {
+		desired.Expires = remote.Expires
+		if expiresChanged {
+			desired.Expires = desiredExpires
+		}
+		if _, err := tc.UpdateAuthClient(ctx, spec.ClientID, desired); err != nil {
+			return "", err
+		}
+	}
+
+	if !reset {
+		return "", nil
+	}
+
+	updated, err := tc.ResetAccessToken(ctx, spec.ClientID)
+	if err != nil {
+		return "", err
+	}
+
+	return updated.AccessToken, nil
+}
+
+// deleteClient removes a dynamic client from TaskCluster. If the Instance no
+// longer exists there is nothing to remove.
+func (r *AccessTokenReconciler) deleteClient(ctx context.Context, instanceName types.NamespacedName, clientID string) error {
+	if clientID == "" {
+		return nil
+	}
+
+	tc, err := r.Clients(ctx, instanceName)
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if err := tc.DeleteAuthClient(ctx, clientID); err != nil && !tcclient.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+func (r *AccessTokenReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Clients == nil {
+		r.Clients = RootClients(r.Log, mgr.GetClient())
+	}
+	if r.Recorder == nil {
+		r.Recorder = mgr.GetEventRecorderFor("accesstoken-controller")
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&taskclusterv1beta1.AccessToken{}).
+		Owns(&corev1.Secret{}).
+		Complete(r)
+}
@@ -0,0 +1,549 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	taskclusterv1beta1 "github.com/wellplayedgames/taskcluster-operator/api/v1beta1"
+	"github.com/wellplayedgames/taskcluster-operator/pkg/tcclient"
+)
+
+var testAccessTokenName = types.NamespacedName{Namespace: "taskcluster", Name: "ci"}
+
+func testInstance() *taskclusterv1beta1.Instance {
+	return &taskclusterv1beta1.Instance{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testInstanceName.Namespace, Name: testInstanceName.Name},
+	}
+}
+
+func testAccessToken(mode taskclusterv1beta1.AccessTokenMode, clientID string) *taskclusterv1beta1.AccessToken {
+	return &taskclusterv1beta1.AccessToken{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         testAccessTokenName.Namespace,
+			Name:              testAccessTokenName.Name,
+			CreationTimestamp: metav1.Time{Time: testNow.Add(-time.Hour)},
+		},
+		Spec: taskclusterv1beta1.AccessTokenSpec{
+			InstanceRef: corev1.ObjectReference{Name: testInstanceName.Name},
+			ClientID:    clientID,
+			Description: "ci",
+			Scopes:      []string{"queue:create-task:*"},
+			Mode:        mode,
+		},
+	}
+}
+
+func newTestAccessTokenReconciler(c client.Client, clients TaskClusterClientFunc) *AccessTokenReconciler {
+	return &AccessTokenReconciler{
+		Client:   c,
+		Log:      logf.NullLogger{},
+		Scheme:   newTestScheme(),
+		Recorder: record.NewFakeRecorder(10),
+		Clients:  clients,
+	}
+}
+
+// reconcileAccessToken reconciles the test AccessToken and returns it.
+func reconcileAccessToken(t *testing.T, r *AccessTokenReconciler) (*taskclusterv1beta1.AccessToken, ctrl.Result) {
+	result, err := r.Reconcile(ctrl.Request{NamespacedName: testAccessTokenName})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var token taskclusterv1beta1.AccessToken
+	if err := r.Client.Get(context.Background(), testAccessTokenName, &token); err != nil {
+		t.Fatal(err)
+	}
+
+	return &token, result
+}
+
+// updateAccessToken applies a change to the test AccessToken.
+func updateAccessToken(t *testing.T, c client.Client, change func(token *taskclusterv1beta1.AccessToken)) {
+	var token taskclusterv1beta1.AccessToken
+	if err := c.Get(context.Background(), testAccessTokenName, &token); err != nil {
+		t.Fatal(err)
+	}
+
+	change(&token)
+	if err := c.Update(context.Background(), &token); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readTestSecret(t *testing.T, c client.Client, name string) *corev1.Secret {
+	var secret corev1.Secret
+	if err := c.Get(context.Background(), types.NamespacedName{Namespace: "taskcluster", Name: name}, &secret); err != nil {
+		t.Fatal(err)
+	}
+
+	return &secret
+}
+
+func TestAccessTokenReconcilerDynamic(t *testing.T) {
+	t.Run("creates the client", func(t *testing.T) {
+		auth, clients := newTestAuth(t)
+		defer auth.Close()
+		c := newFakeClient(testInstance(), testAccessToken(taskclusterv1beta1.AccessTokenDynamic, "project/ci"))
+
+		token, result := reconcileAccessToken(t, newTestAccessTokenReconciler(c, clients))
+
+		remote := auth.AuthClient("project/ci")
+		if remote == nil || remote.Description != "ci" || !sameScopes(remote.Scopes, []string{"queue:create-task:*"}) {
+			t.Fatalf("unexpected client %+v", remote)
+		}
+
+		secret := readTestSecret(t, c, "ci")
+		if string(secret.Data["client-id"]) != "project/ci" || string(secret.Data["access-token"]) != remote.AccessToken {
+			t.Errorf("expected the issued token in the secret, got %v", secret.Data)
+		}
+		if owner := metav1.GetControllerOf(secret); owner == nil || owner.Name != "ci" {
+			t.Errorf("expected the secret to be owned by the token, got %v", owner)
+		}
+
+		if !hasFinalizer(token.Finalizers, taskClusterFinalizer) {
+			t.Error("expected the finalizer to be added")
+		}
+		if !token.Status.Created || token.Status.ClientID != "project/ci" || token.Status.SecretName != "ci" || !accessTokenReady(token) {
+			t.Errorf("unexpected status %+v", token.Status)
+		}
+		if result.RequeueAfter != taskClusterResyncPeriod {
+			t.Errorf("expected a resync after %v, got %v", taskClusterResyncPeriod, result.RequeueAfter)
+		}
+	})
+
+	t.Run("updates the client", func(t *testing.T) {
+		auth, clients := newTestAuth(t)
+		defer auth.Close()
+		c := newFakeClient(testInstance(), testAccessToken(taskclusterv1beta1.AccessTokenDynamic, "project/ci"))
+		r := newTestAccessTokenReconciler(c, clients)
+
+		reconcileAccessToken(t, r)
+		issued := auth.AuthClient("project/ci").AccessToken
+
+		updateAccessToken(t, c, func(token *taskclusterv1beta1.AccessToken) {
+			token.Spec.Scopes = []string{"queue:create-task:*", "secrets:get:*"}
+		})
+		reconcileAccessToken(t, r)
+
+		remote := auth.AuthClient("project/ci")
+		if !sameScopes(remote.Scopes, []string{"queue:create-task:*", "secrets:get:*"}) {
+			t.Errorf("expected the scopes to be updated, got %v", remote.Scopes)
+		}
+		if remote.AccessToken != issued || string(readTestSecret(t, c, "ci").Data["access-token"]) != issued {
+			t.Error("expected the access token to be kept")
+		}
+	})
+
+	t.Run("resets the access token", func(t *testing.T) {
+		auth, clients := newTestAuth(t)
+		defer auth.Close()
+
+		consumer := testDeployment("consumer", "", true)
+		consumer.Labels = map[string]string{"app": "consumer"}
+		token := testAccessToken(taskclusterv1beta1.AccessTokenDynamic, "project/ci")
+		token.Spec.Rotation = &taskclusterv1beta1.AccessTokenRotationSpec{
+			RestartSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "consumer"}},
+		}
+		c := newFakeClient(testInstance(), token, consumer)
+		r := newTestAccessTokenReconciler(c, clients)
+
+		reconcileAccessToken(t, r)
+		issued := auth.AuthClient("project/ci").AccessToken
+
+		updateAccessToken(t, c, func(token *taskclusterv1beta1.AccessToken) {
+			token.Annotations = map[string]string{rotateAccessTokenAnnotation: "1"}
+		})
+		rotated, _ := reconcileAccessToken(t, r)
+
+		remote := auth.AuthClient("project/ci")
+		if remote.AccessToken == issued {
+			t.Fatal("expected a new access token")
+		}
+		if string(readTestSecret(t, c, "ci").Data["access-token"]) != remote.AccessToken {
+			t.Error("expected the new access token in the secret")
+		}
+		if rotated.Status.RotationRequest != "1" {
+			t.Errorf("expected the rotation request to be recorded, got %q", rotated.Status.RotationRequest)
+		}
+
+		var deployment appsv1.Deployment
+		if err := c.Get(context.Background(), types.NamespacedName{Namespace: consumer.Namespace, Name: consumer.Name}, &deployment); err != nil {
+			t.Fatal(err)
+		}
+		if deployment.Spec.Template.Annotations[defaultRestartAnnotation] == "" {
+			t.Error("expected the consumer to be restarted")
+		}
+
+		// The request is only honoured once.
+		reconcileAccessToken(t, r)
+		if auth.AuthClient("project/ci").AccessToken != remote.AccessToken {
+			t.Error("expected the access token not to be reset again")
+		}
+	})
+
+	t.Run("replaces a renamed client", func(t *testing.T) {
+		auth, clients := newTestAuth(t)
+		defer auth.Close()
+		c := newFakeClient(testInstance(), testAccessToken(taskclusterv1beta1.AccessTokenDynamic, "project/ci"))
+		r := newTestAccessTokenReconciler(c, clients)
+
+		reconcileAccessToken(t, r)
+		updateAccessToken(t, c, func(token *taskclusterv1beta1.AccessToken) {
+			token.Spec.ClientID = "project/ci-2"
+		})
+		token, _ := reconcileAccessToken(t, r)
+
+		if auth.AuthClient("project/ci") != nil {
+			t.Error("expected the previous client to be deleted")
+		}
+		remote := auth.AuthClient("project/ci-2")
+		if remote == nil {
+			t.Fatal("expected the new client to be created")
+		}
+		if string(readTestSecret(t, c, "ci").Data["access-token"]) != remote.AccessToken {
+			t.Error("expected the new client's token in the secret")
+		}
+		if token.Status.ClientID != "project/ci-2" {
+			t.Errorf("expected status to record the new client, got %s", token.Status.ClientID)
+		}
+	})
+
+	t.Run("switching to static deletes the client", func(t *testing.T) {
+		auth, clients := newTestAuth(t)
+		defer auth.Close()
+		c := newFakeClient(testInstance(), testAccessToken(taskclusterv1beta1.AccessTokenDynamic, "project/ci"))
+		r := newTestAccessTokenReconciler(c, clients)
+
+		reconcileAccessToken(t, r)
+		updateAccessToken(t, c, func(token *taskclusterv1beta1.AccessToken) {
+			token.Spec.Mode = taskclusterv1beta1.AccessTokenStatic
+			token.Spec.ClientID = taskclusterv1beta1.StaticClientIDPrefix + "ci"
+		})
+		token, _ := reconcileAccessToken(t, r)
+
+		if auth.AuthClient("project/ci") != nil {
+			t.Error("expected the dynamic client to be deleted")
+		}
+		if hasFinalizer(token.Finalizers, taskClusterFinalizer) {
+			t.Error("expected the finalizer to be removed")
+		}
+		if token.Status.ClientID != "" {
+			t.Errorf("expected the client ID to be cleared, got %s", token.Status.ClientID)
+		}
+	})
+
+	t.Run("switching to dynamic creates the client", func(t *testing.T) {
+		auth, clients := newTestAuth(t)
+		defer auth.Close()
+
+		// The static token's Secret was written by the InstanceReconciler.
+		token := testAccessToken(taskclusterv1beta1.AccessTokenStatic, taskclusterv1beta1.StaticClientIDPrefix+"ci")
+		token.Status.Created = true
+		token.Status.SecretName = "ci"
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "taskcluster", Name: "ci"},
+			Data: map[string][]byte{
+				"client-id":    []byte(token.Spec.ClientID),
+				"access-token": []byte("static-token"),
+			},
+		}
+		c := newFakeClient(testInstance(), token, secret)
+		r := newTestAccessTokenReconciler(c, clients)
+
+		reconcileAccessToken(t, r)
+		if len(auth.Requests()) != 0 {
+			t.Error("expected static tokens not to use the auth API")
+		}
+
+		updateAccessToken(t, c, func(token *taskclusterv1beta1.AccessToken) {
+			token.Spec.Mode = taskclusterv1beta1.AccessTokenDynamic
+			token.Spec.ClientID = "project/ci"
+		})
+		reconciled, _ := reconcileAccessToken(t, r)
+
+		remote := auth.AuthClient("project/ci")
+		if remote == nil {
+			t.Fatal("expected the dynamic client to be created")
+		}
+		data := readTestSecret(t, c, "ci").Data
+		if string(data["client-id"]) != "project/ci" || string(data["access-token"]) != remote.AccessToken {
+			t.Errorf("expected the dynamic credentials in the secret, got %v", data)
+		}
+		if !hasFinalizer(reconciled.Finalizers, taskClusterFinalizer) {
+			t.Error("expected the finalizer to be added")
+		}
+	})
+
+	t.Run("deletes the client", func(t *testing.T) {
+		auth, clients := newTestAuth(t)
+		defer auth.Close()
+		auth.SetAuthClient(tcclient.AuthClient{ClientID: "project/ci"})
+
+		now := metav1.Now()
+		token := testAccessToken(taskclusterv1beta1.AccessTokenDynamic, "project/ci")
+		token.DeletionTimestamp = &now
+		token.Finalizers = []string{taskClusterFinalizer}
+		token.Status.ClientID = "project/ci"
+		c := newFakeClient(testInstance(), token)
+
+		deleted, _ := reconcileAccessToken(t, newTestAccessTokenReconciler(c, clients))
+
+		if auth.AuthClient("project/ci") != nil {
+			t.Error("expected the client to be deleted")
+		}
+		if hasFinalizer(deleted.Finalizers, taskClusterFinalizer) {
+			t.Error("expected the finalizer to be removed")
+		}
+	})
+
+	t.Run("writes the secret template", func(t *testing.T) {
+		auth, clients := newTestAuth(t)
+		defer auth.Close()
+
+		token := testAccessToken(taskclusterv1beta1.AccessTokenDynamic, "project/ci")
+		token.Spec.SecretTemplate = taskclusterv1beta1.AccessTokenSecretTemplate{
+			Name:           "ci-credentials",
+			Labels:         map[string]string{"app": "ci"},
+			Keys:           taskclusterv1beta1.AccessTokenSecretKeys{ClientID: "TASKCLUSTER_CLIENT_ID", AccessToken: "TASKCLUSTER_ACCESS_TOKEN", RootURL: "TASKCLUSTER_ROOT_URL"},
+			IncludeRootURL: true,
+		}
+		c := newFakeClient(testInstance(), token)
+
+		reconciled, _ := reconcileAccessToken(t, newTestAccessTokenReconciler(c, clients))
+
+		secret := readTestSecret(t, c, "ci-credentials")
+		if string(secret.Data["TASKCLUSTER_CLIENT_ID"]) != "project/ci" ||
+			string(secret.Data["TASKCLUSTER_ACCESS_TOKEN"]) != auth.AuthClient("project/ci").AccessToken ||
+			string(secret.Data["TASKCLUSTER_ROOT_URL"]) != auth.URL {
+			t.Errorf("unexpected secret data %v", secret.Data)
+		}
+		if secret.Labels["app"] != "ci" {
+			t.Errorf("expected the template labels, got %v", secret.Labels)
+		}
+		if reconciled.Status.SecretName != "ci-credentials" {
+			t.Errorf("expected status to record the secret, got %s", reconciled.Status.SecretName)
+		}
+	})
+
+	t.Run("expires the token", func(t *testing.T) {
+		auth, clients := newTestAuth(t)
+		defer auth.Close()
+		auth.SetAuthClient(tcclient.AuthClient{ClientID: "project/ci"})
+
+		expires := metav1.NewTime(time.Now().Add(-time.Minute))
+		token := testAccessToken(taskclusterv1beta1.AccessTokenDynamic, "project/ci")
+		token.Spec.Expires = &expires
+		token.Finalizers = []string{taskClusterFinalizer}
+		token.Status.ClientID = "project/ci"
+		token.Status.SecretName = "ci"
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "taskcluster", Name: "ci"}}
+		c := newFakeClient(testInstance(), token, secret)
+
+		expired, result := reconcileAccessToken(t, newTestAccessTokenReconciler(c, clients))
+
+		if auth.AuthClient("project/ci") != nil {
+			t.Error("expected the client to be deleted")
+		}
+		var remaining corev1.Secret
+		if err := c.Get(context.Background(), types.NamespacedName{Namespace: "taskcluster", Name: "ci"}, &remaining); err == nil {
+			t.Error("expected the secret to be deleted")
+		}
+		if accessTokenReady(expired) || expired.Status.ClientID != "" {
+			t.Errorf("unexpected status %+v", expired.Status)
+		}
+		if result.RequeueAfter != 0 {
+			t.Errorf("expected no requeue, got %v", result.RequeueAfter)
+		}
+	})
+}
+
+func TestAccessTokenRotationDue(t *testing.T) {
+	hourly := &taskclusterv1beta1.AccessTokenRotationSpec{Interval: &metav1.Duration{Duration: time.Hour}}
+	rotated := metav1.NewTime(testNow.Add(-30 * time.Minute))
+	longAgo := metav1.NewTime(testNow.Add(-2 * time.Hour))
+
+	tests := []struct {
+		name        string
+		rotation    *taskclusterv1beta1.AccessTokenRotationSpec
+		lastRotated *metav1.Time
+		annotation  string
+		lastRequest string
+
+		next time.Time
+		due  bool
+	}{
+		{
+			name:        "no rotation policy",
+			lastRotated: &longAgo,
+		},
+		{
+			name:        "no interval",
+			rotation:    &taskclusterv1beta1.AccessTokenRotationSpec{},
+			lastRotated: &longAgo,
+		},
+		{
+			name:     "never rotated",
+			rotation: hourly,
+		},
+		{
+			name:        "not yet due",
+			rotation:    hourly,
+			lastRotated: &rotated,
+			next:        rotated.Add(time.Hour),
+		},
+		{
+			name:        "due",
+			rotation:    hourly,
+			lastRotated: &longAgo,
+			next:        longAgo.Add(time.Hour),
+			due:         true,
+		},
+		{
+			name:        "exactly due",
+			rotation:    &taskclusterv1beta1.AccessTokenRotationSpec{Interval: &metav1.Duration{Duration: 30 * time.Minute}},
+			lastRotated: &rotated,
+			next:        testNow,
+			due:         true,
+		},
+		{
+			name:        "requested",
+			lastRotated: &rotated,
+			annotation:  "1",
+			due:         true,
+		},
+		{
+			name:        "already requested",
+			lastRotated: &rotated,
+			annotation:  "1",
+			lastRequest: "1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := testAccessToken(taskclusterv1beta1.AccessTokenDynamic, "project/ci")
+			token.Spec.Rotation = tt.rotation
+			token.Status.LastRotationTime = tt.lastRotated
+			token.Status.RotationRequest = tt.lastRequest
+			if tt.annotation != "" {
+				token.Annotations = map[string]string{rotateAccessTokenAnnotation: tt.annotation}
+			}
+
+			if next := nextAccessTokenRotation(token); !next.Equal(tt.next) {
+				t.Errorf("expected next rotation %v, got %v", tt.next, next)
+			}
+			if due := accessTokenRotationDue(token, testNow); due != tt.due {
+				t.Errorf("expected due %v, got %v", tt.due, due)
+			}
+		})
+	}
+}
+
+func TestStaticAccessTokenRestartsConsumersAfterAuth(t *testing.T) {
+	ctx := context.Background()
+
+	token := testAccessToken(taskclusterv1beta1.AccessTokenStatic, taskclusterv1beta1.StaticClientIDPrefix+"ci")
+	token.Annotations = map[string]string{rotateAccessTokenAnnotation: "1"}
+	token.Spec.Rotation = &taskclusterv1beta1.AccessTokenRotationSpec{
+		RestartSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "consumer"}},
+	}
+	token.Status.Created = true
+	token.Status.SecretName = "ci"
+	token.Status.LastRotationTime = &metav1.Time{Time: testNow.Add(-time.Hour)}
+	token.Status.Conditions = []taskclusterv1beta1.SyncCondition{
+		{Type: taskclusterv1beta1.SyncReady, Status: corev1.ConditionTrue, Reason: "Synced"},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "taskcluster", Name: "ci"},
+		Data: map[string][]byte{
+			"client-id":    []byte(token.Spec.ClientID),
+			"access-token": []byte("old"),
+		},
+	}
+	consumer := testDeployment("consumer", "", true)
+	consumer.Labels = map[string]string{"app": "consumer"}
+
+	// Auth is rendered with the new token, but has not rolled out yet.
+	rendered := []runtime.Object{testDeployment("auth", "new", true)}
+	c := newFakeClient(token, secret, consumer, testDeployment("auth", "old", true))
+
+	restarted := func() bool {
+		var deployment appsv1.Deployment
+		if err := c.Get(ctx, types.NamespacedName{Namespace: consumer.Namespace, Name: consumer.Name}, &deployment); err != nil {
+			t.Fatal(err)
+		}
+		return deployment.Spec.Template.Annotations[defaultRestartAnnotation] != ""
+	}
+
+	reconcile := func() bool {
+		o := newTestOperations()
+		o.Client = c
+		o.source = *testInstance()
+		if err := o.migrateAccessTokenResources(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		ready, err := o.AccessTokensReady(ctx, rendered)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ready
+	}
+
+	if reconcile() {
+		t.Error("expected the token to wait for auth")
+	}
+	if string(readTestSecret(t, c, "ci").Data["access-token"]) == "old" {
+		t.Fatal("expected the token to be rotated")
+	}
+	if restarted() {
+		t.Fatal("expected consumers not to be restarted before auth rolls out")
+	}
+
+	// Auth has picked up the new token but is still rolling out.
+	var auth appsv1.Deployment
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "taskcluster", Name: "taskcluster-auth-web"}, &auth); err != nil {
+		t.Fatal(err)
+	}
+	auth.Spec.Template.Annotations[secretChecksumAnnotation] = "new"
+	auth.Status.UpdatedReplicas = 1
+	if err := c.Update(ctx, &auth); err != nil {
+		t.Fatal(err)
+	}
+
+	if reconcile() || restarted() {
+		t.Fatal("expected consumers not to be restarted while auth rolls out")
+	}
+
+	auth.Status.UpdatedReplicas = auth.Status.Replicas
+	if err := c.Update(ctx, &auth); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reconcile() {
+		t.Error("expected the token to be ready once auth rolled out")
+	}
+	if !restarted() {
+		t.Error("expected consumers to be restarted once auth rolled out")
+	}
+
+	var reconciled taskclusterv1beta1.AccessToken
+	if err := c.Get(ctx, testAccessTokenName, &reconciled); err != nil {
+		t.Fatal(err)
+	}
+	if !accessTokenReady(&reconciled) || accessTokenRestartPending(&reconciled) {
+		t.Errorf("unexpected status %+v", reconciled.Status)
+	}
+}
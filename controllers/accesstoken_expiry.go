@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	taskclusterv1beta1 "github.com/wellplayedgames/taskcluster-operator/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// expiredAnnotation marks a Secret whose credentials were removed
+	// because its AccessToken expired.
+	expiredAnnotation = fieldOwner + "/expired"
+
+	// accessTokenExpiryWarning is how long before an AccessToken expires an
+	// Event warns about it. It is shortened to a tenth of the token's
+	// lifetime for short-lived tokens.
+	accessTokenExpiryWarning = 24 * time.Hour
+)
+
+// accessTokenExpiry returns when an AccessToken expires, or zero if it does
+// not.
+func accessTokenExpiry(token *taskclusterv1beta1.AccessToken) time.Time {
+	if token.Spec.Expires != nil {
+		return token.Spec.Expires.Time.UTC().Truncate(time.Second)
+	}
+
+	if token.Spec.ExpiresAfter != nil {
+		return token.CreationTimestamp.Add(token.Spec.ExpiresAfter.Duration).UTC().Truncate(time.Second)
+	}
+
+	return time.Time{}
+}
+
+// accessTokenExpired returns true if an AccessToken has expired.
+func accessTokenExpired(token *taskclusterv1beta1.AccessToken, now time.Time) bool {
+	expires := accessTokenExpiry(token)
+	return !expires.IsZero() && !now.Before(expires)
+}
+
+// accessTokenExpiryWarningTime returns when to warn that an AccessToken is
+// about to expire.
+func accessTokenExpiryWarningTime(token *taskclusterv1beta1.AccessToken, expires time.Time) time.Time {
+	window := accessTokenExpiryWarning
+	if lifetime := expires.Sub(token.CreationTimestamp.Time); lifetime/10 < window {
+		window = lifetime / 10
+	}
+
+	return expires.Add(-window)
+}
+
+// trackAccessTokenExpiry records the expiry of an AccessToken in its status
+// and emits an Event once it is about to expire. It returns when the token
+// next needs reconciling for its expiry, or zero if it does not expire.
+func (r *AccessTokenReconciler) trackAccessTokenExpiry(token *taskclusterv1beta1.AccessToken, now time.Time) time.Time {
+	expires := accessTokenExpiry(token)
+	if expires.IsZero() {
+		token.Status.Expires = nil
+		token.Status.ExpiryWarningSent = false
+		return time.Time{}
+	}
+
+	if token.Status.Expires == nil || !token.Status.Expires.Time.Equal(expires) {
+		token.Status.Expires = &metav1.Time{Time: expires}
+		token.Status.ExpiryWarningSent = false
+	}
+
+	warnAt := accessTokenExpiryWarningTime(token, expires)
+	if token.Status.ExpiryWarningSent {
+		return expires
+	} else if now.Before(warnAt) {
+		return warnAt
+	}
+
+	r.Recorder.Eventf(token, corev1.EventTypeWarning, "ExpiringSoon", "Access token %s expires at %s", token.Spec.ClientID, expires.Format(time.RFC3339))
+	token.Status.ExpiryWarningSent = true
+	return expires
+}
+
+// expireAccessToken stops issuing an expired AccessToken. Dynamic clients are
+// deleted, while static clients are dropped by the InstanceReconciler once
+// the token is marked as expired.
+func (r *AccessTokenReconciler) expireAccessToken(ctx context.Context, token *taskclusterv1beta1.AccessToken, instanceName types.NamespacedName, now time.Time) error {
+	if isDynamicAccessToken(token) {
+		if err := r.deleteClient(ctx, instanceName, token.Status.ClientID); err != nil {
+			return err
+		}
+		token.Status.ClientID = ""
+	}
+
+	if err := r.retireSecret(ctx, token); err != nil {
+		return err
+	}
+
+	expires := accessTokenExpiry(token)
+	message := fmt.Sprintf("Access token expired at %s", expires.Format(time.RFC3339))
+	if setAccessTokenReady(token, corev1.ConditionFalse, "Expired", message, now) {
+		r.Recorder.Event(token, corev1.EventTypeNormal, "Expired", message)
+	}
+
+	token.Status.Expires = &metav1.Time{Time: expires}
+	return r.Client.Status().Update(ctx, token)
+}
+
+// retireSecret deletes the Secret of an expired AccessToken or removes the
+// credentials from it, depending on its policy.
+func (r *AccessTokenReconciler) retireSecret(ctx context.Context, token *taskclusterv1beta1.AccessToken) error {
+	secret, err := getAccessTokenSecret(ctx, r.Client, token)
+	if err != nil || secret.ResourceVersion == "" {
+		return err
+	}
+
+	if token.Spec.ExpiredSecretPolicy != taskclusterv1beta1.AccessTokenDisableSecret {
+		if err := r.Client.Delete(ctx, secret); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		return nil
+	}
+
+	if len(secret.Data) == 0 && secret.Annotations[expiredAnnotation] != "" {
+		return nil
+	}
+
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	secret.Annotations[expiredAnnotation] = accessTokenExpiry(token).Format(time.RFC3339)
+	secret.Data = nil
+	return r.Client.Update(ctx, secret)
+}
@@ -0,0 +1,224 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+
+	taskclusterv1beta1 "github.com/wellplayedgames/taskcluster-operator/api/v1beta1"
+)
+
+func TestAccessTokenExpiry(t *testing.T) {
+	created := testNow.Add(-time.Hour)
+	expires := metav1.NewTime(testNow.Add(30 * time.Minute))
+
+	tests := []struct {
+		name         string
+		expires      *metav1.Time
+		expiresAfter *metav1.Duration
+		now          time.Time
+
+		expiry  time.Time
+		warnAt  time.Time
+		expired bool
+	}{
+		{
+			name: "never expires",
+			now:  testNow,
+		},
+		{
+			name:    "expires",
+			expires: &expires,
+			now:     testNow,
+			expiry:  expires.Time,
+			// A tenth of the 90 minute lifetime.
+			warnAt: expires.Add(-9 * time.Minute),
+		},
+		{
+			name:    "expires exactly now",
+			expires: &expires,
+			now:     expires.Time,
+			expiry:  expires.Time,
+			warnAt:  expires.Add(-9 * time.Minute),
+			expired: true,
+		},
+		{
+			name:         "expires after a duration",
+			expiresAfter: &metav1.Duration{Duration: 30 * 24 * time.Hour},
+			now:          testNow,
+			expiry:       created.Add(30 * 24 * time.Hour),
+			warnAt:       created.Add(29 * 24 * time.Hour),
+		},
+		{
+			name:         "expired after a duration",
+			expiresAfter: &metav1.Duration{Duration: 30 * time.Minute},
+			now:          testNow,
+			expiry:       created.Add(30 * time.Minute),
+			warnAt:       created.Add(27 * time.Minute),
+			expired:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := testAccessToken(taskclusterv1beta1.AccessTokenDynamic, "project/ci")
+			token.CreationTimestamp = metav1.NewTime(created)
+			token.Spec.Expires = tt.expires
+			token.Spec.ExpiresAfter = tt.expiresAfter
+
+			expiry := accessTokenExpiry(token)
+			if !expiry.Equal(tt.expiry) {
+				t.Errorf("expected expiry %v, got %v", tt.expiry, expiry)
+			}
+			if expired := accessTokenExpired(token, tt.now); expired != tt.expired {
+				t.Errorf("expected expired %v, got %v", tt.expired, expired)
+			}
+			if expiry.IsZero() {
+				return
+			}
+			if warnAt := accessTokenExpiryWarningTime(token, expiry); !warnAt.Equal(tt.warnAt) {
+				t.Errorf("expected warning at %v, got %v", tt.warnAt, warnAt)
+			}
+		})
+	}
+}
+
+func TestRetireSecret(t *testing.T) {
+	expires := metav1.NewTime(testNow.Add(-time.Minute))
+
+	tests := []struct {
+		name   string
+		policy taskclusterv1beta1.AccessTokenExpiredSecretPolicy
+
+		deleted bool
+	}{
+		{
+			name:    "default",
+			deleted: true,
+		},
+		{
+			name:    "delete",
+			policy:  taskclusterv1beta1.AccessTokenDeleteSecret,
+			deleted: true,
+		},
+		{
+			name:   "disable",
+			policy: taskclusterv1beta1.AccessTokenDisableSecret,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			token := testAccessToken(taskclusterv1beta1.AccessTokenDynamic, "project/ci")
+			token.Spec.Expires = &expires
+			token.Spec.ExpiredSecretPolicy = tt.policy
+			token.Status.SecretName = "ci"
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "taskcluster", Name: "ci"},
+				Data:       map[string][]byte{"client-id": []byte("project/ci"), "access-token": []byte("token")},
+			}
+			c := newFakeClient(token, secret)
+			r := newTestAccessTokenReconciler(c, nil)
+
+			if err := r.retireSecret(ctx, token); err != nil {
+				t.Fatal(err)
+			}
+
+			var retired corev1.Secret
+			err := c.Get(ctx, types.NamespacedName{Namespace: "taskcluster", Name: "ci"}, &retired)
+			if tt.deleted {
+				if !apierrors.IsNotFound(err) {
+					t.Errorf("expected the secret to be deleted, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(retired.Data) != 0 {
+				t.Errorf("expected the credentials to be removed, got %v", retired.Data)
+			}
+			if retired.Annotations[expiredAnnotation] != expires.Time.UTC().Format(time.RFC3339) {
+				t.Errorf("expected the secret to be marked expired, got %v", retired.Annotations)
+			}
+
+			// A disabled secret is left alone.
+			if err := r.retireSecret(ctx, token); err != nil {
+				t.Fatal(err)
+			}
+			var again corev1.Secret
+			if err := c.Get(ctx, types.NamespacedName{Namespace: "taskcluster", Name: "ci"}, &again); err != nil {
+				t.Fatal(err)
+			}
+			if again.ResourceVersion != retired.ResourceVersion {
+				t.Error("expected a disabled secret not to be updated again")
+			}
+		})
+	}
+}
+
+func TestAccessTokenRequeuesAtExpiry(t *testing.T) {
+	expires := metav1.NewTime(testNow.Add(2 * time.Minute))
+
+	t.Run("dynamic", func(t *testing.T) {
+		auth, clients := newTestAuth(t)
+		defer auth.Close()
+
+		token := testAccessToken(taskclusterv1beta1.AccessTokenDynamic, "project/ci")
+		token.Spec.Expires = &expires
+		c := newFakeClient(testInstance(), token)
+		r := newTestAccessTokenReconciler(c, clients)
+		r.now = func() time.Time { return testNow }
+
+		reconciled, result := reconcileAccessToken(t, r)
+
+		if result.RequeueAfter != 2*time.Minute {
+			t.Errorf("expected a requeue at expiry, got %v", result.RequeueAfter)
+		}
+		if remote := auth.AuthClient("project/ci"); remote == nil || !remote.Expires.Equal(expires.Time) {
+			t.Errorf("expected the client to expire at %v, got %+v", expires, remote)
+		}
+		if !reconciled.Status.ExpiryWarningSent {
+			t.Error("expected an expiry warning")
+		}
+		select {
+		case event := <-r.Recorder.(*record.FakeRecorder).Events:
+			t.Log(event)
+		default:
+			t.Error("expected an ExpiringSoon event")
+		}
+
+		// At expiry the client is removed.
+		r.now = func() time.Time { return expires.Time }
+		expired, result := reconcileAccessToken(t, r)
+
+		if auth.AuthClient("project/ci") != nil {
+			t.Error("expected the client to be deleted")
+		}
+		if accessTokenReady(expired) || result.RequeueAfter != 0 {
+			t.Errorf("expected the token to be expired, got %+v and %v", expired.Status, result)
+		}
+	})
+
+	t.Run("static", func(t *testing.T) {
+		token := testAccessToken(taskclusterv1beta1.AccessTokenStatic, taskclusterv1beta1.StaticClientIDPrefix+"ci")
+		token.Spec.Expires = &expires
+		c := newFakeClient(testInstance(), token)
+		r := newTestAccessTokenReconciler(c, nil)
+		r.now = func() time.Time { return testNow }
+
+		_, result := reconcileAccessToken(t, r)
+
+		if result.RequeueAfter != 2*time.Minute {
+			t.Errorf("expected a requeue at expiry, got %v", result.RequeueAfter)
+		}
+	})
+}
@@ -0,0 +1,190 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	taskclusterv1beta1 "github.com/wellplayedgames/taskcluster-operator/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// accessTokenSecretKeys returns the keys of an AccessToken's Secret with
+// defaults applied.
+func accessTokenSecretKeys(token *taskclusterv1beta1.AccessToken) taskclusterv1beta1.AccessTokenSecretKeys {
+	keys := token.Spec.SecretTemplate.Keys
+	if keys.ClientID == "" {
+		keys.ClientID = "client-id"
+	}
+	if keys.AccessToken == "" {
+		keys.AccessToken = "access-token"
+	}
+	if keys.RootURL == "" {
+		keys.RootURL = "root-url"
+	}
+
+	return keys
+}
+
+// accessTokenSecretName returns the name of the Secret an AccessToken is
+// written to.
+func accessTokenSecretName(token *taskclusterv1beta1.AccessToken) types.NamespacedName {
+	name := token.Spec.SecretTemplate.Name
+	if name == "" {
+		name = token.Name
+	}
+
+	return types.NamespacedName{
+		Namespace: token.Namespace,
+		Name:      name,
+	}
+}
+
+// getAccessTokenSecret fetches the Secret an AccessToken was last written
+// to, so that renaming the Secret does not issue a new token. An empty
+// Secret is returned if it does not exist.
+func getAccessTokenSecret(ctx context.Context, c client.Client, token *taskclusterv1beta1.AccessToken) (*corev1.Secret, error) {
+	name := accessTokenSecretName(token)
+	if token.Status.SecretName != "" {
+		name.Name = token.Status.SecretName
+	}
+
+	var secret corev1.Secret
+	if err := c.Get(ctx, name, &secret); err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+
+	return &secret, nil
+}
+
+// readAccessTokenSecret returns the client ID and access token stored in an
+// AccessToken's Secret.
+func readAccessTokenSecret(token *taskclusterv1beta1.AccessToken, secret *corev1.Secret) (string, string) {
+	keys := accessTokenSecretKeys(token)
+	return string(secret.Data[keys.ClientID]), string(secret.Data[keys.AccessToken])
+}
+
+// accessTokenSecretData renders the data of an AccessToken's Secret.
+func accessTokenSecretData(token *taskclusterv1beta1.AccessToken, accessToken, rootURL string) (map[string][]byte, error) {
+	template := &token.Spec.SecretTemplate
+	keys := accessTokenSecretKeys(token)
+
+	data := map[string][]byte{
+		keys.ClientID:    []byte(token.Spec.ClientID),
+		keys.AccessToken: []byte(accessToken),
+	}
+	if template.IncludeRootURL {
+		data[keys.RootURL] = []byte(rootURL)
+	}
+
+	if keys.Credentials != "" {
+		credentials := map[string]string{
+			"clientId":    token.Spec.ClientID,
+			"accessToken": accessToken,
+		}
+		if template.IncludeRootURL {
+			credentials["rootUrl"] = rootURL
+		}
+
+		raw, err := json.Marshal(credentials)
+		if err != nil {
+			return nil, err
+		}
+		data[keys.Credentials] = raw
+	}
+
+	return data, nil
+}
+
+// writeAccessTokenSecret renders an AccessToken's Secret from its template.
+// existing is the Secret returned by getAccessTokenSecret, which is replaced
+// if the Secret was renamed or its type changed. A renamed Secret is created
+// before the old one is deleted, so that consumers always find one.
+func writeAccessTokenSecret(ctx context.Context, c client.Client, scheme *runtime.Scheme, token *taskclusterv1beta1.AccessToken, existing *corev1.Secret, accessToken, rootURL string) error {
+	template := &token.Spec.SecretTemplate
+	name := accessTokenSecretName(token)
+
+	data, err := accessTokenSecretData(token, accessToken, rootURL)
+	if err != nil {
+		return err
+	}
+
+	secretType := template.Type
+	if secretType == "" {
+		secretType = corev1.SecretTypeOpaque
+	}
+
+	exists := existing.ResourceVersion != ""
+	replace := exists && (existing.Name != name.Name || existing.Type != secretType)
+
+	if exists && !replace {
+		if equality.Semantic.DeepEqual(existing.Data, data) &&
+			equality.Semantic.DeepEqual(existing.Labels, template.Labels) &&
+			equality.Semantic.DeepEqual(existing.Annotations, template.Annotations) {
+			return nil
+		}
+
+		existing.Labels = template.Labels
+		existing.Annotations = template.Annotations
+		existing.Data = data
+		return c.Update(ctx, existing)
+	}
+
+	// The type of a Secret cannot be changed, so one which keeps its name is
+	// deleted first.
+	renamed := exists && existing.Name != name.Name
+	if replace && !renamed {
+		if err := c.Delete(ctx, existing); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	secret := &corev1.Secret{
+		Type: secretType,
+		Data: data,
+	}
+	secret.Namespace = name.Namespace
+	secret.Name = name.Name
+	secret.Labels = template.Labels
+	secret.Annotations = template.Annotations
+
+	if err := controllerutil.SetControllerReference(token, secret, scheme); err != nil {
+		return err
+	}
+
+	if err := c.Create(ctx, secret); apierrors.IsAlreadyExists(err) && renamed {
+		// An earlier attempt created the Secret but did not delete the old
+		// one.
+		var current corev1.Secret
+		if err := c.Get(ctx, name, &current); err != nil {
+			return err
+		}
+		if !metav1.IsControlledBy(&current, token) {
+			return fmt.Errorf("secret %s already exists", name)
+		}
+
+		current.Labels = template.Labels
+		current.Annotations = template.Annotations
+		current.Data = data
+		if err := c.Update(ctx, &current); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	if replace && renamed {
+		if err := c.Delete(ctx, existing); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
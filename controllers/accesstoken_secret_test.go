@@ -0,0 +1,183 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	taskclusterv1beta1 "github.com/wellplayedgames/taskcluster-operator/api/v1beta1"
+)
+
+func TestAccessTokenSecretData(t *testing.T) {
+	tests := []struct {
+		name     string
+		template taskclusterv1beta1.AccessTokenSecretTemplate
+
+		data        map[string]string
+		credentials string
+		json        map[string]string
+	}{
+		{
+			name: "default keys",
+			data: map[string]string{"client-id": "project/ci", "access-token": "token"},
+		},
+		{
+			name:     "root URL",
+			template: taskclusterv1beta1.AccessTokenSecretTemplate{IncludeRootURL: true},
+			data:     map[string]string{"client-id": "project/ci", "access-token": "token", "root-url": "https://tc.example.com"},
+		},
+		{
+			name: "custom keys",
+			template: taskclusterv1beta1.AccessTokenSecretTemplate{
+				Keys: taskclusterv1beta1.AccessTokenSecretKeys{ClientID: "id", AccessToken: "token", RootURL: "url"},
+			},
+			data: map[string]string{"id": "project/ci", "token": "token"},
+		},
+		{
+			name: "credentials",
+			template: taskclusterv1beta1.AccessTokenSecretTemplate{
+				Keys: taskclusterv1beta1.AccessTokenSecretKeys{Credentials: "credentials.json"},
+			},
+			data:        map[string]string{"client-id": "project/ci", "access-token": "token"},
+			credentials: "credentials.json",
+			json:        map[string]string{"clientId": "project/ci", "accessToken": "token"},
+		},
+		{
+			name: "credentials with root URL",
+			template: taskclusterv1beta1.AccessTokenSecretTemplate{
+				Keys:           taskclusterv1beta1.AccessTokenSecretKeys{Credentials: "credentials.json"},
+				IncludeRootURL: true,
+			},
+			data:        map[string]string{"client-id": "project/ci", "access-token": "token", "root-url": "https://tc.example.com"},
+			credentials: "credentials.json",
+			json:        map[string]string{"clientId": "project/ci", "accessToken": "token", "rootUrl": "https://tc.example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := testAccessToken(taskclusterv1beta1.AccessTokenDynamic, "project/ci")
+			token.Spec.SecretTemplate = tt.template
+
+			data, err := accessTokenSecretData(token, "token", "https://tc.example.com")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var credentials map[string]string
+			if tt.credentials != "" {
+				if err := json.Unmarshal(data[tt.credentials], &credentials); err != nil {
+					t.Fatal(err)
+				}
+				delete(data, tt.credentials)
+			}
+			if !reflect.DeepEqual(credentials, tt.json) {
+				t.Errorf("expected credentials %v, got %v", tt.json, credentials)
+			}
+
+			actual := map[string]string{}
+			for k, v := range data {
+				actual[k] = string(v)
+			}
+			if !reflect.DeepEqual(actual, tt.data) {
+				t.Errorf("expected data %v, got %v", tt.data, actual)
+			}
+		})
+	}
+}
+
+// recordingClient records the Secrets created and deleted through it.
+type recordingClient struct {
+	client.Client
+	calls []string
+}
+
+func (c *recordingClient) Create(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+	if secret, ok := obj.(*corev1.Secret); ok {
+		c.calls = append(c.calls, "create "+secret.Name)
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func (c *recordingClient) Delete(ctx context.Context, obj runtime.Object, opts ...client.DeleteOption) error {
+	if secret, ok := obj.(*corev1.Secret); ok {
+		c.calls = append(c.calls, "delete "+secret.Name)
+	}
+	return c.Client.Delete(ctx, obj, opts...)
+}
+
+func TestWriteAccessTokenSecretReplaces(t *testing.T) {
+	tests := []struct {
+		name     string
+		template taskclusterv1beta1.AccessTokenSecretTemplate
+		leftover bool
+
+		calls []string
+	}{
+		{
+			name:     "renamed",
+			template: taskclusterv1beta1.AccessTokenSecretTemplate{Name: "ci-credentials"},
+			calls:    []string{"create ci-credentials", "delete ci"},
+		},
+		{
+			name:     "renamed after an earlier attempt",
+			template: taskclusterv1beta1.AccessTokenSecretTemplate{Name: "ci-credentials"},
+			leftover: true,
+			calls:    []string{"create ci-credentials", "delete ci"},
+		},
+		{
+			name:     "type changed",
+			template: taskclusterv1beta1.AccessTokenSecretTemplate{Type: "example.com/credentials"},
+			calls:    []string{"delete ci", "create ci"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			token := testAccessToken(taskclusterv1beta1.AccessTokenDynamic, "project/ci")
+			token.Status.SecretName = "ci"
+			token.Spec.SecretTemplate = tt.template
+
+			objs := []runtime.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "taskcluster", Name: "ci"},
+					Type:       corev1.SecretTypeOpaque,
+					Data:       map[string][]byte{"client-id": []byte("project/ci"), "access-token": []byte("token")},
+				},
+			}
+			if tt.leftover {
+				leftover := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "taskcluster", Name: "ci-credentials"}}
+				if err := controllerutil.SetControllerReference(token, leftover, newTestScheme()); err != nil {
+					t.Fatal(err)
+				}
+				objs = append(objs, leftover)
+			}
+			c := &recordingClient{Client: newFakeClient(objs...)}
+
+			existing, err := getAccessTokenSecret(ctx, c, token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := writeAccessTokenSecret(ctx, c, newTestScheme(), token, existing, "token", ""); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(c.calls, tt.calls) {
+				t.Errorf("expected %v, got %v", tt.calls, c.calls)
+			}
+
+			secret := readTestSecret(t, c, accessTokenSecretName(token).Name)
+			if string(secret.Data["access-token"]) != "token" {
+				t.Errorf("expected the token in the new secret, got %v", secret.Data)
+			}
+		})
+	}
+}
@@ -0,0 +1,325 @@
+package controllers
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	taskclusterv1beta1 "github.com/wellplayedgames/taskcluster-operator/api/v1beta1"
+	"github.com/wellplayedgames/taskcluster-operator/pkg/dbcrypto"
+	"github.com/wellplayedgames/taskcluster-operator/pkg/pwgen"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// cryptoKeyRetryPeriod is how long to wait before trying again to retire
+	// a crypto key which cannot be retired yet.
+	cryptoKeyRetryPeriod = 15 * time.Second
+)
+
+type encryptedColumn struct {
+	Table  string
+	Column string
+}
+
+var (
+	// encryptedColumns lists the encrypted columns owned by each service in
+	// the TaskCluster database schema.
+	encryptedColumns = map[string][]encryptedColumn{
+		"auth": {
+			{Table: "clients", Column: "encrypted_access_token"},
+		},
+		"hooks": {
+			{Table: "hooks", Column: "encrypted_trigger_token"},
+			{Table: "hooks", Column: "encrypted_next_task_id"},
+		},
+		"secrets": {
+			{Table: "secrets", Column: "encrypted_secret"},
+		},
+		"web_server": {
+			{Table: "access_tokens", Column: "encrypted_access_token"},
+			{Table: "github_access_tokens", Column: "encrypted_access_token"},
+			{Table: "sessions", Column: "encrypted_session_id"},
+		},
+		"worker_manager": {
+			{Table: "workers", Column: "secret"},
+		},
+	}
+)
+
+func newCryptoKey(now time.Time) DBCryptoKey {
+	id := strconv.Itoa(int(now.Unix()))
+	rawKey := pwgen.AlphaNumeric(32)
+	key := base64.StdEncoding.EncodeToString([]byte(rawKey))
+
+	return DBCryptoKey{
+		ID:        id,
+		Algorithm: "aes-256",
+		Key:       key,
+	}
+}
+
+// cryptoKeyCreated returns the creation time of a key, which is encoded in
+// its ID.
+func cryptoKeyCreated(key DBCryptoKey) (time.Time, bool) {
+	unix, err := strconv.ParseInt(key.ID, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return time.Unix(unix, 0).UTC(), true
+}
+
+// RetireCryptoKeys removes the crypto keys of each service which have been
+// superseded for longer than the retirement policy allows, and saves the
+// state.
+func (o *TaskClusterOperations) RetireCryptoKeys(ctx context.Context, objects []runtime.Object) error {
+	for _, svc := range cryptoServices {
+		if err := o.retireCryptoKeys(ctx, objects, svc); err != nil {
+			return err
+		}
+	}
+
+	return o.writeState(ctx)
+}
+
+// retireCryptoKeys removes a service's keys which have been superseded for
+// longer than the retirement policy allows. The service must have rolled out
+// with the active key, so that nothing writes with the old keys any more,
+// and every value using a key is re-encrypted before it is removed.
+func (o *TaskClusterOperations) retireCryptoKeys(ctx context.Context, objects []runtime.Object, name string) error {
+	policy := o.source.Spec.Rotation.DBCryptoKeys
+	if policy == nil || policy.RetireAfter == nil || policy.RetireAfter.Duration <= 0 {
+		return nil
+	}
+
+	sa := o.ensureServiceAccount(name)
+	active := sa.DBCryptoKeys[0]
+	kept := []DBCryptoKey{active}
+	var retired []DBCryptoKey
+
+	for idx := 1; idx < len(sa.DBCryptoKeys); idx++ {
+		key := sa.DBCryptoKeys[idx]
+		supersededAt, ok := cryptoKeyCreated(sa.DBCryptoKeys[idx-1])
+		if !ok {
+			kept = append(kept, key)
+			continue
+		}
+
+		retireAt := supersededAt.Add(policy.RetireAfter.Duration)
+		if o.now.Before(retireAt) {
+			o.scheduleRotation(retireAt)
+			kept = append(kept, key)
+			continue
+		}
+
+		retired = append(retired, key)
+	}
+
+	if len(retired) == 0 {
+		return nil
+	}
+
+	rolledOut, err := o.deploymentsRolledOut(ctx, objects, name)
+	if err != nil {
+		return err
+	}
+	if !rolledOut {
+		o.scheduleRotation(o.now.Add(cryptoKeyRetryPeriod))
+		return nil
+	}
+
+	keys := map[string][]byte{}
+	for _, key := range sa.DBCryptoKeys {
+		raw, err := dbcrypto.ParseKey(key.Key)
+		if err != nil {
+			return fmt.Errorf("invalid crypto key %s for %s: %w", key.ID, name, err)
+		}
+
+		keys[key.ID] = raw
+	}
+
+	store, err := o.encryptedColumnStore(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range retired {
+		remaining := 0
+		for _, col := range encryptedColumns[name] {
+			count, left, err := reencryptColumn(ctx, store, col, keys, key.ID, active.ID)
+			if err != nil {
+				return fmt.Errorf("error re-encrypting %s.%s: %w", col.Table, col.Column, err)
+			}
+
+			o.Logger.Info("re-encrypted values", "service", name, "table", col.Table, "column", col.Column, "from", key.ID, "to", active.ID, "count", count, "remaining", left)
+			remaining += left
+		}
+
+		// Values changed while they were being re-encrypted are picked up
+		// by the next pass.
+		if remaining > 0 {
+			o.scheduleRotation(o.now.Add(cryptoKeyRetryPeriod))
+			kept = append(kept, key)
+			continue
+		}
+
+		o.Logger.Info("retiring crypto key", "service", name, "key", key.ID)
+	}
+
+	sa.DBCryptoKeys = kept
+	return nil
+}
+
+// encryptedRow is a value read from an encrypted column.
+type encryptedRow struct {
+	ctid  string
+	value string
+}
+
+// encryptedColumnStore reads and writes the encrypted columns of the
+// TaskCluster database.
+type encryptedColumnStore interface {
+	// HasColumn returns true if the column exists.
+	HasColumn(ctx context.Context, col encryptedColumn) (bool, error)
+	// Encrypted returns the values of a column encrypted with a key.
+	Encrypted(ctx context.Context, col encryptedColumn, keyID string) ([]encryptedRow, error)
+	// Replace sets a value if it has not changed since it was read, and
+	// returns false if it has.
+	Replace(ctx context.Context, col encryptedColumn, row encryptedRow, value string) (bool, error)
+}
+
+func (o *TaskClusterOperations) encryptedColumnStore(ctx context.Context) (encryptedColumnStore, error) {
+	if o.columnStore != nil {
+		return o.columnStore, nil
+	}
+
+	db, err := o.connectToPostgres(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	o.columnStore = postgresColumnStore{db}
+	return o.columnStore, nil
+}
+
+// postgresColumnStore is an encryptedColumnStore backed by Postgres.
+type postgresColumnStore struct {
+	db *pgx.Conn
+}
+
+func (s postgresColumnStore) HasColumn(ctx context.Context, col encryptedColumn) (bool, error) {
+	rows, err := s.db.Query(ctx, "SELECT 1 FROM information_schema.columns WHERE table_schema = 'public' AND table_name = $1 AND column_name = $2",
+		pgx.QuerySimpleProtocol(true), col.Table, col.Column)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	return rows.Next(), nil
+}
+
+func (s postgresColumnStore) Encrypted(ctx context.Context, col encryptedColumn, keyID string) ([]encryptedRow, error) {
+	table := pgx.Identifier{col.Table}.Sanitize()
+	column := pgx.Identifier{col.Column}.Sanitize()
+
+	rows, err := s.db.Query(ctx, fmt.Sprintf("SELECT ctid::text, %s::text FROM %s WHERE %s->>'kid' = $1", column, table, column),
+		pgx.QuerySimpleProtocol(true), keyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var encrypted []encryptedRow
+	for rows.Next() {
+		var row encryptedRow
+		if err := rows.Scan(&row.ctid, &row.value); err != nil {
+			return nil, err
+		}
+
+		encrypted = append(encrypted, row)
+	}
+
+	return encrypted, rows.Err()
+}
+
+func (s postgresColumnStore) Replace(ctx context.Context, col encryptedColumn, row encryptedRow, value string) (bool, error) {
+	table := pgx.Identifier{col.Table}.Sanitize()
+	column := pgx.Identifier{col.Column}.Sanitize()
+
+	// Only update rows which have not changed since they were read, in case
+	// a service wrote to them in the meantime.
+	sql := fmt.Sprintf("UPDATE %s SET %s = $1::jsonb WHERE ctid = $2::tid AND %s::text = $3", table, column, column)
+	tag, err := s.db.Exec(ctx, sql, pgx.QuerySimpleProtocol(true), value, row.ctid, row.value)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+// reencryptColumn moves every value in a column encrypted with one key to
+// another. It returns the number of values updated and the number still
+// encrypted with the old key afterwards, which were changed or moved while
+// the column was being updated.
+func reencryptColumn(ctx context.Context, store encryptedColumnStore, col encryptedColumn, keys map[string][]byte, fromID, toID string) (int, int, error) {
+	exists, err := store.HasColumn(ctx, col)
+	if err != nil || !exists {
+		return 0, 0, err
+	}
+
+	pending, err := store.Encrypted(ctx, col, fromID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	updated := 0
+	for _, row := range pending {
+		value, err := dbcrypto.Reencrypt([]byte(row.value), keys, toID)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		ok, err := store.Replace(ctx, col, row, string(value))
+		if err != nil {
+			return 0, 0, err
+		}
+		if ok {
+			updated++
+		}
+	}
+
+	remaining, err := store.Encrypted(ctx, col, fromID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return updated, len(remaining), nil
+}
+
+// CryptoKeyStatus returns the crypto keys currently held by each service.
+func (o *TaskClusterOperations) CryptoKeyStatus() []taskclusterv1beta1.CryptoKeyStatus {
+	var status []taskclusterv1beta1.CryptoKeyStatus
+
+	for _, svc := range cryptoServices {
+		sa, ok := o.state.ServiceAccounts[svc]
+		if !ok {
+			continue
+		}
+
+		keyIDs := make([]string, 0, len(sa.DBCryptoKeys))
+		for _, key := range sa.DBCryptoKeys {
+			keyIDs = append(keyIDs, key.ID)
+		}
+
+		status = append(status, taskclusterv1beta1.CryptoKeyStatus{
+			Service: svc,
+			KeyIDs:  keyIDs,
+		})
+	}
+
+	return status
+}
@@ -0,0 +1,216 @@
+package controllers
+
+import (
+	"context"
+	"encoding/base64"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	taskclusterv1beta1 "github.com/wellplayedgames/taskcluster-operator/api/v1beta1"
+	"github.com/wellplayedgames/taskcluster-operator/pkg/dbcrypto"
+)
+
+// fakeColumnStore holds encrypted columns in memory, keyed by ctid.
+type fakeColumnStore struct {
+	values map[encryptedColumn]map[string]string
+	// writes are made by a service just before the row is next replaced.
+	writes map[string]string
+}
+
+func (s *fakeColumnStore) HasColumn(ctx context.Context, col encryptedColumn) (bool, error) {
+	_, ok := s.values[col]
+	return ok, nil
+}
+
+func (s *fakeColumnStore) Encrypted(ctx context.Context, col encryptedColumn, keyID string) ([]encryptedRow, error) {
+	var rows []encryptedRow
+	for ctid, value := range s.values[col] {
+		if kid, err := dbcrypto.KeyID([]byte(value)); err != nil {
+			return nil, err
+		} else if kid == keyID {
+			rows = append(rows, encryptedRow{ctid: ctid, value: value})
+		}
+	}
+
+	sort.Slice(rows, func(i, j int) bool { return rows[i].ctid < rows[j].ctid })
+	return rows, nil
+}
+
+func (s *fakeColumnStore) Replace(ctx context.Context, col encryptedColumn, row encryptedRow, value string) (bool, error) {
+	if write, ok := s.writes[row.ctid]; ok {
+		s.values[col][row.ctid] = write
+		delete(s.writes, row.ctid)
+	}
+
+	if s.values[col][row.ctid] != row.value {
+		return false, nil
+	}
+
+	s.values[col][row.ctid] = value
+	return true, nil
+}
+
+// testCryptoKey returns a key created at the given time.
+func testCryptoKey(created time.Time, raw string) (DBCryptoKey, []byte) {
+	return DBCryptoKey{
+		ID:        strconv.Itoa(int(created.Unix())),
+		Algorithm: "aes-256",
+		Key:       base64.StdEncoding.EncodeToString([]byte(raw)),
+	}, []byte(raw)
+}
+
+func TestRetireCryptoKeys(t *testing.T) {
+	clients := encryptedColumn{Table: "clients", Column: "encrypted_access_token"}
+	rendered := []runtime.Object{testDeployment("auth", "new", true)}
+
+	tests := []struct {
+		name          string
+		activeCreated time.Time
+		live          string
+		rolledOut     bool
+		writes        map[string]string
+
+		retired      bool
+		nextRotation time.Time
+	}{
+		{
+			name:          "not due",
+			activeCreated: testNow.Add(-time.Hour),
+			live:          "new",
+			rolledOut:     true,
+			nextRotation:  testNow.Add(23 * time.Hour),
+		},
+		{
+			name:          "not rolled out",
+			activeCreated: testNow.Add(-48 * time.Hour),
+			live:          "new",
+			nextRotation:  testNow.Add(cryptoKeyRetryPeriod),
+		},
+		{
+			name:          "old secret running",
+			activeCreated: testNow.Add(-48 * time.Hour),
+			live:          "old",
+			rolledOut:     true,
+			nextRotation:  testNow.Add(cryptoKeyRetryPeriod),
+		},
+		{
+			name:          "rolled out",
+			activeCreated: testNow.Add(-48 * time.Hour),
+			live:          "new",
+			rolledOut:     true,
+			retired:       true,
+		},
+		{
+			name:          "changed while re-encrypting",
+			activeCreated: testNow.Add(-48 * time.Hour),
+			live:          "new",
+			rolledOut:     true,
+			writes:        map[string]string{"(0,1)": "changed"},
+			nextRotation:  testNow.Add(cryptoKeyRetryPeriod),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			active, activeKey := testCryptoKey(tt.activeCreated, "0123456789abcdefghijklmnopqrstuv")
+			old, oldKey := testCryptoKey(testNow.Add(-60*24*time.Hour), "ABCDEFGHIJKLMNOPQRSTUVWXYZ012345")
+
+			encrypt := func(plaintext, keyID string, key []byte) string {
+				value, err := dbcrypto.Encrypt([]byte(plaintext), keyID, key)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return string(value)
+			}
+			plaintexts := map[string]string{"(0,1)": "first", "(0,2)": "second", "(0,3)": "third"}
+			store := &fakeColumnStore{
+				values: map[encryptedColumn]map[string]string{
+					clients: {
+						"(0,1)": encrypt("first", old.ID, oldKey),
+						"(0,2)": encrypt("second", old.ID, oldKey),
+						"(0,3)": encrypt("third", active.ID, activeKey),
+					},
+				},
+				writes: map[string]string{},
+			}
+			for ctid, plaintext := range tt.writes {
+				store.writes[ctid] = encrypt(plaintext, old.ID, oldKey)
+				plaintexts[ctid] = plaintext
+			}
+
+			o := newTestOperations(testDeployment("auth", tt.live, tt.rolledOut))
+			o.columnStore = store
+			o.source.Spec.Rotation.DBCryptoKeys = &taskclusterv1beta1.CryptoKeyRotationPolicy{
+				RetireAfter: &metav1.Duration{Duration: 24 * time.Hour},
+			}
+			for _, svc := range cryptoServices {
+				o.ensureServiceAccount(svc).DBCryptoKeys = []DBCryptoKey{active}
+			}
+			o.ensureServiceAccount("auth").DBCryptoKeys = []DBCryptoKey{active, old}
+
+			if err := o.RetireCryptoKeys(ctx, rendered); err != nil {
+				t.Fatal(err)
+			}
+
+			expected := []DBCryptoKey{active, old}
+			if tt.retired {
+				expected = []DBCryptoKey{active}
+			}
+			if keys := o.state.ServiceAccounts["auth"].DBCryptoKeys; !reflect.DeepEqual(keys, expected) {
+				t.Errorf("expected keys %v, got %v", expected, keys)
+			}
+			if !o.nextRotation.Equal(tt.nextRotation) {
+				t.Errorf("expected next attempt at %v, got %v", tt.nextRotation, o.nextRotation)
+			}
+
+			if !tt.retired && len(tt.writes) == 0 {
+				return
+			}
+
+			// Everything but concurrent writes has moved to the active key,
+			// and still decrypts.
+			keys := map[string][]byte{active.ID: activeKey, old.ID: oldKey}
+			for ctid, value := range store.values[clients] {
+				kid, err := dbcrypto.KeyID([]byte(value))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, written := tt.writes[ctid]; kid != active.ID && !written {
+					t.Errorf("expected %s to use the active key, got %s", ctid, kid)
+				}
+
+				plaintext, err := dbcrypto.Decrypt([]byte(value), keys[kid])
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(plaintext) != plaintexts[ctid] {
+					t.Errorf("expected %s to decrypt to %q, got %q", ctid, plaintexts[ctid], plaintext)
+				}
+			}
+
+			if len(tt.writes) == 0 {
+				return
+			}
+
+			// The next pass re-encrypts the concurrent writes and retires
+			// the key.
+			o.nextRotation = time.Time{}
+			if err := o.RetireCryptoKeys(ctx, rendered); err != nil {
+				t.Fatal(err)
+			}
+			if keys := o.state.ServiceAccounts["auth"].DBCryptoKeys; !reflect.DeepEqual(keys, []DBCryptoKey{active}) {
+				t.Errorf("expected the old key to be retired, got %v", keys)
+			}
+			if remaining, _ := store.Encrypted(ctx, clients, old.ID); len(remaining) != 0 {
+				t.Errorf("expected no values to use the old key, got %v", remaining)
+			}
+		})
+	}
+}
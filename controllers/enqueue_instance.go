@@ -3,7 +3,6 @@ package controllers
 import (
 	taskclusterv1beta1 "github.com/wellplayedgames/taskcluster-operator/api/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/util/workqueue"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -34,12 +33,11 @@ func (e *enqueueRequestForInstance) Generic(evt event.GenericEvent, q workqueue.
 
 // addInstanceReconcileRequest returns all valid requests for clusters based on the passed-in resource.
 func (e *enqueueRequestForInstance) addInstanceReconcileRequest(object metav1.Object, q workqueue.RateLimitingInterface) {
-	if token, ok := object.(*taskclusterv1beta1.AccessToken); ok {
+	// Dynamic tokens are issued through the auth API and do not affect the
+	// rendered Instance.
+	if token, ok := object.(*taskclusterv1beta1.AccessToken); ok && !isDynamicAccessToken(token) {
 		q.Add(reconcile.Request{
-			NamespacedName: types.NamespacedName{
-				Namespace: token.Spec.InstanceRef.Namespace,
-				Name:      token.Spec.InstanceRef.Name,
-			},
+			NamespacedName: instanceRefName(&token.Spec.InstanceRef, token.Namespace),
 		})
 	}
 }
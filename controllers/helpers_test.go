@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"context"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	taskclusterv1 "github.com/wellplayedgames/taskcluster-operator/api/v1"
+	taskclusterv1beta1 "github.com/wellplayedgames/taskcluster-operator/api/v1beta1"
+)
+
+// newTestScheme returns a scheme with the types the controllers use.
+func newTestScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		panic(err)
+	}
+	if err := taskclusterv1beta1.AddToScheme(scheme); err != nil {
+		panic(err)
+	}
+	if err := taskclusterv1.AddToScheme(scheme); err != nil {
+		panic(err)
+	}
+
+	return scheme
+}
+
+// applyClient wraps the fake client, which cannot apply patches. Apply
+// patches create the object or replace it entirely.
+type applyClient struct {
+	client.Client
+}
+
+// newFakeClient returns a fake client holding objs. Like the API server, it
+// gives every object a resource version.
+func newFakeClient(objs ...runtime.Object) client.Client {
+	for _, obj := range objs {
+		if objMeta, err := meta.Accessor(obj); err == nil && objMeta.GetResourceVersion() == "" {
+			objMeta.SetResourceVersion("1")
+		}
+	}
+
+	return applyClient{fake.NewFakeClientWithScheme(newTestScheme(), objs...)}
+}
+
+func (c applyClient) Patch(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if patch.Type() != types.ApplyPatchType {
+		return c.Client.Patch(ctx, obj, patch, opts...)
+	}
+
+	key, err := client.ObjectKeyFromObject(obj)
+	if err != nil {
+		return err
+	}
+
+	existing := obj.DeepCopyObject()
+	if err := c.Client.Get(ctx, key, existing); apierrors.IsNotFound(err) {
+		return c.Client.Create(ctx, obj)
+	} else if err != nil {
+		return err
+	}
+
+	existingMeta, err := meta.Accessor(existing)
+	if err != nil {
+		return err
+	}
+	objMeta, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+
+	objMeta.SetResourceVersion(existingMeta.GetResourceVersion())
+	return c.Client.Update(ctx, obj)
+}
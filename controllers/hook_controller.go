@@ -0,0 +1,274 @@
+/*
+Copyright 2020 Well Played Games Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/wellplayedgames/taskcluster-operator/pkg/tcclient"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	taskclusterv1beta1 "github.com/wellplayedgames/taskcluster-operator/api/v1beta1"
+)
+
+const (
+	// triggerHookAnnotation fires a hook whenever its value changes.
+	triggerHookAnnotation = fieldOwner + "/trigger"
+
+	// defaultTriggerSchema accepts only an empty payload, matching the
+	// hooks service default.
+	defaultTriggerSchema = `{"type":"object","additionalProperties":false}`
+)
+
+// HookReconciler reconciles a Hook object
+type HookReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+
+	// Clients returns the TaskCluster client used for an Instance. It
+	// defaults to RootClients.
+	Clients TaskClusterClientFunc
+
+	// now returns the current time. It defaults to time.Now.
+	now func() time.Time
+}
+
+// +kubebuilder:rbac:groups=taskcluster.wellplayed.games,resources=hooks,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=taskcluster.wellplayed.games,resources=hooks/status,verbs=get;update;patch
+
+func (r *HookReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	logger := r.Log.WithValues("hook", req.NamespacedName)
+
+	var hook taskclusterv1beta1.Hook
+	if err := r.Client.Get(ctx, req.NamespacedName, &hook); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	instanceName := instanceRefName(&hook.Spec.InstanceRef, hook.Namespace)
+
+	if hook.DeletionTimestamp != nil {
+		if !hasFinalizer(hook.Finalizers, taskClusterFinalizer) {
+			return ctrl.Result{}, nil
+		}
+
+		if hook.Status.HookID != "" {
+			tc, err := r.Clients(ctx, instanceName)
+			if err != nil && !apierrors.IsNotFound(err) {
+				return ctrl.Result{}, err
+			}
+
+			if tc != nil {
+				err := tc.DeleteHook(ctx, hook.Status.HookGroupID, hook.Status.HookID)
+				if err != nil && !tcclient.IsNotFound(err) {
+					return ctrl.Result{}, err
+				}
+			}
+		}
+
+		logger.Info("deleted hook", "hookGroupId", hook.Status.HookGroupID, "hookId", hook.Status.HookID)
+		hook.Finalizers = removeFinalizer(hook.Finalizers, taskClusterFinalizer)
+		return ctrl.Result{}, r.Client.Update(ctx, &hook)
+	}
+
+	if !hasFinalizer(hook.Finalizers, taskClusterFinalizer) {
+		hook.Finalizers = append(hook.Finalizers, taskClusterFinalizer)
+		if err := r.Client.Update(ctx, &hook); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	now := time.Now()
+	if r.now != nil {
+		now = r.now()
+	}
+	ready := taskclusterv1beta1.SyncCondition{
+		Type:               taskclusterv1beta1.SyncReady,
+		LastTransitionTime: metav1.Time{Time: now},
+		Status:             corev1.ConditionFalse,
+		Reason:             "Unknown",
+	}
+	defer func() {
+		setSyncCondition(&hook.Status.Conditions, ready)
+
+		err := r.Client.Status().Update(ctx, &hook)
+		if err != nil {
+			logger.Error(err, "failed to update status")
+		}
+	}()
+
+	tc, err := r.Clients(ctx, instanceName)
+	if err != nil {
+		ready.Reason = "InstanceUnavailable"
+		ready.Message = err.Error()
+		return ctrl.Result{}, err
+	}
+
+	spec := &hook.Spec
+
+	// Remove the previous hook if it was renamed.
+	if hook.Status.HookID != "" && (hook.Status.HookGroupID != spec.HookGroupID || hook.Status.HookID != spec.HookID) {
+		err := tc.DeleteHook(ctx, hook.Status.HookGroupID, hook.Status.HookID)
+		if err != nil && !tcclient.IsNotFound(err) {
+			ready.Reason = "DeleteFailed"
+			ready.Message = err.Error()
+			return ctrl.Result{}, err
+		}
+	}
+
+	if err := syncHook(ctx, tc, spec); err != nil {
+		ready.Reason = "SyncFailed"
+		ready.Message = err.Error()
+		return ctrl.Result{}, err
+	}
+	hook.Status.HookGroupID = spec.HookGroupID
+	hook.Status.HookID = spec.HookID
+
+	if request := hook.Annotations[triggerHookAnnotation]; request != "" && request != hook.Status.LastTriggerRequest {
+		taskID, err := tc.TriggerHook(ctx, spec.HookGroupID, spec.HookID, map[string]interface{}{})
+		if err != nil {
+			ready.Reason = "TriggerFailed"
+			ready.Message = err.Error()
+			return ctrl.Result{}, err
+		}
+
+		logger.Info("triggered hook", "taskId", taskID)
+		hook.Status.LastTriggerRequest = request
+	}
+
+	status, err := tc.HookStatus(ctx, spec.HookGroupID, spec.HookID)
+	if err != nil {
+		ready.Reason = "StatusFailed"
+		ready.Message = err.Error()
+		return ctrl.Result{}, err
+	}
+
+	hook.Status.LastFireResult = status.LastFire.Result
+	hook.Status.LastFireTaskID = status.LastFire.TaskID
+	hook.Status.LastFireTime = nil
+	if !status.LastFire.Time.IsZero() {
+		hook.Status.LastFireTime = &metav1.Time{Time: status.LastFire.Time}
+	}
+	hook.Status.NextScheduledTime = nil
+	if next := status.NextScheduledDate; next != nil {
+		hook.Status.NextScheduledTime = &metav1.Time{Time: *next}
+	}
+	hook.Status.ObservedGeneration = &hook.Generation
+	hook.Status.LastSyncTime = &metav1.Time{Time: now}
+
+	ready.Status = corev1.ConditionTrue
+	ready.Reason = "Synced"
+
+	// Refresh the status shortly after the next scheduled fire.
+	result := ctrl.Result{RequeueAfter: taskClusterResyncPeriod}
+	if next := status.NextScheduledDate; next != nil {
+		if untilNext := next.Sub(now) + time.Minute; untilNext > 0 && untilNext < result.RequeueAfter {
+			result.RequeueAfter = untilNext
+		}
+	}
+
+	return result, nil
+}
+
+// hookDefinition builds the hooks service definition of a hook.
+func hookDefinition(spec *taskclusterv1beta1.HookSpec) *tcclient.Hook {
+	name := spec.Name
+	if name == "" {
+		name = spec.HookID
+	}
+
+	triggerSchema := json.RawMessage(defaultTriggerSchema)
+	if spec.TriggerSchema != nil {
+		triggerSchema = spec.TriggerSchema.Raw
+	}
+
+	hook := &tcclient.Hook{
+		Metadata: tcclient.HookMetadata{
+			Name:         name,
+			Description:  spec.Description,
+			Owner:        spec.Owner,
+			EmailOnError: spec.EmailOnError,
+		},
+		Schedule:      spec.Schedule,
+		Task:          spec.Task.Raw,
+		TriggerSchema: triggerSchema,
+		Bindings:      make([]tcclient.HookBinding, 0, len(spec.Bindings)),
+	}
+	if hook.Schedule == nil {
+		hook.Schedule = []string{}
+	}
+
+	for _, binding := range spec.Bindings {
+		hook.Bindings = append(hook.Bindings, tcclient.HookBinding{
+			Exchange:          binding.Exchange,
+			RoutingKeyPattern: binding.RoutingKeyPattern,
+		})
+	}
+
+	return hook
+}
+
+// syncHook creates the hook or updates it if it differs from the spec.
+func syncHook(ctx context.Context, tc *tcclient.Client, spec *taskclusterv1beta1.HookSpec) error {
+	desired := hookDefinition(spec)
+
+	remote, err := tc.Hook(ctx, spec.HookGroupID, spec.HookID)
+	if tcclient.IsNotFound(err) {
+		_, err = tc.CreateHook(ctx, spec.HookGroupID, spec.HookID, desired)
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	remote.HookGroupID = ""
+	remote.HookID = ""
+	remoteJSON, err := json.Marshal(remote)
+	if err != nil {
+		return err
+	}
+
+	desiredJSON, err := json.Marshal(desired)
+	if err != nil {
+		return err
+	}
+
+	if sameJSON(remoteJSON, desiredJSON) {
+		return nil
+	}
+
+	_, err = tc.UpdateHook(ctx, spec.HookGroupID, spec.HookID, desired)
+	return err
+}
+
+func (r *HookReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Clients == nil {
+		r.Clients = RootClients(r.Log, mgr.GetClient())
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&taskclusterv1beta1.Hook{}).
+		Complete(r)
+}
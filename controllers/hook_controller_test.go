@@ -0,0 +1,292 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	taskclusterv1beta1 "github.com/wellplayedgames/taskcluster-operator/api/v1beta1"
+	"github.com/wellplayedgames/taskcluster-operator/pkg/tcclient"
+)
+
+const testHookPath = "/api/hooks/v1/hooks/project-test/nightly"
+
+func testHook(hookGroupID, hookID string) *taskclusterv1beta1.Hook {
+	return &taskclusterv1beta1.Hook{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "taskcluster", Name: "hook"},
+		Spec: taskclusterv1beta1.HookSpec{
+			InstanceRef: corev1.ObjectReference{Name: testInstanceName.Name},
+			HookGroupID: hookGroupID,
+			HookID:      hookID,
+			Description: "test hook",
+			Owner:       "ci@example.com",
+			Schedule:    []string{"0 0 0 * * *"},
+			Task:        runtime.RawExtension{Raw: []byte(`{"provisionerId":"proj-test","workerType":"ci"}`)},
+		},
+	}
+}
+
+func reconcileHook(t *testing.T, c client.Client, clients TaskClusterClientFunc) (ctrl.Result, *taskclusterv1beta1.Hook) {
+	r := &HookReconciler{
+		Client:  c,
+		Log:     logf.NullLogger{},
+		Scheme:  newTestScheme(),
+		Clients: clients,
+		now:     func() time.Time { return testNow },
+	}
+	name := types.NamespacedName{Namespace: "taskcluster", Name: "hook"}
+	result, err := r.Reconcile(ctrl.Request{NamespacedName: name})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var hook taskclusterv1beta1.Hook
+	if err := c.Get(context.Background(), name, &hook); err != nil {
+		t.Fatal(err)
+	}
+
+	return result, &hook
+}
+
+func countRequests(requests []string, request string) int {
+	count := 0
+	for _, r := range requests {
+		if r == request {
+			count++
+		}
+	}
+	return count
+}
+
+func TestHookDefinition(t *testing.T) {
+	spec := testHook("project-test", "nightly").Spec
+
+	hook := hookDefinition(&spec)
+	if hook.Metadata.Name != "nightly" || hook.Metadata.Owner != "ci@example.com" {
+		t.Errorf("unexpected metadata %+v", hook.Metadata)
+	}
+	if string(hook.TriggerSchema) != defaultTriggerSchema {
+		t.Errorf("expected the default trigger schema, got %s", hook.TriggerSchema)
+	}
+	if hook.Bindings == nil || len(hook.Bindings) != 0 {
+		t.Errorf("expected empty bindings, got %v", hook.Bindings)
+	}
+
+	spec.Name = "Nightly build"
+	spec.Schedule = nil
+	spec.TriggerSchema = &runtime.RawExtension{Raw: []byte(`{"type":"object"}`)}
+	spec.Bindings = []taskclusterv1beta1.HookBinding{{Exchange: "exchange/test", RoutingKeyPattern: "#"}}
+
+	hook = hookDefinition(&spec)
+	if hook.Metadata.Name != "Nightly build" {
+		t.Errorf("expected the spec name, got %q", hook.Metadata.Name)
+	}
+	if hook.Schedule == nil || len(hook.Schedule) != 0 {
+		t.Errorf("expected an empty schedule, got %v", hook.Schedule)
+	}
+	if string(hook.TriggerSchema) != `{"type":"object"}` {
+		t.Errorf("expected the spec trigger schema, got %s", hook.TriggerSchema)
+	}
+	if len(hook.Bindings) != 1 || hook.Bindings[0] != (tcclient.HookBinding{Exchange: "exchange/test", RoutingKeyPattern: "#"}) {
+		t.Errorf("unexpected bindings %v", hook.Bindings)
+	}
+}
+
+func TestHookReconciler(t *testing.T) {
+	t.Run("creates the hook", func(t *testing.T) {
+		auth, clients := newTestAuth(t)
+		defer auth.Close()
+		c := newFakeClient(testHook("project-test", "nightly"))
+
+		_, hook := reconcileHook(t, c, clients)
+
+		remote := auth.Hook("project-test", "nightly")
+		if remote == nil || remote.Metadata.Description != "test hook" || len(remote.Schedule) != 1 {
+			t.Fatalf("unexpected hook %+v", remote)
+		}
+		if requests := auth.Requests(); countRequests(requests, "PUT "+testHookPath) != 1 {
+			t.Errorf("expected the hook to be created, got %v", requests)
+		}
+		if !hasFinalizer(hook.Finalizers, taskClusterFinalizer) {
+			t.Error("expected the finalizer to be added")
+		}
+		if hook.Status.HookGroupID != "project-test" || hook.Status.HookID != "nightly" {
+			t.Errorf("unexpected status %+v", hook.Status)
+		}
+		if !syncReady(hook.Status.Conditions) {
+			t.Errorf("expected the hook to be ready, got %+v", hook.Status.Conditions)
+		}
+	})
+
+	t.Run("updates only on diff", func(t *testing.T) {
+		auth, clients := newTestAuth(t)
+		defer auth.Close()
+		c := newFakeClient(testHook("project-test", "nightly"))
+
+		reconcileHook(t, c, clients)
+		auth.Requests()
+
+		// An unchanged hook is only read.
+		reconcileHook(t, c, clients)
+		if requests := auth.Requests(); countRequests(requests, "POST "+testHookPath) != 0 || countRequests(requests, "GET "+testHookPath) != 1 {
+			t.Errorf("expected the hook only to be read, got %v", requests)
+		}
+
+		changed := *auth.Hook("project-test", "nightly")
+		changed.Metadata.Description = "changed"
+		auth.SetHook(changed)
+
+		reconcileHook(t, c, clients)
+		if requests := auth.Requests(); countRequests(requests, "POST "+testHookPath) != 1 {
+			t.Errorf("expected the hook to be updated, got %v", requests)
+		}
+		if remote := auth.Hook("project-test", "nightly"); remote.Metadata.Description != "test hook" {
+			t.Errorf("expected the hook to be corrected, got %+v", remote.Metadata)
+		}
+	})
+
+	t.Run("renames the hook", func(t *testing.T) {
+		auth, clients := newTestAuth(t)
+		defer auth.Close()
+		auth.SetHook(tcclient.Hook{HookGroupID: "project-test", HookID: "old"})
+
+		renamed := testHook("project-test", "nightly")
+		renamed.Finalizers = []string{taskClusterFinalizer}
+		renamed.Status.HookGroupID = "project-test"
+		renamed.Status.HookID = "old"
+		c := newFakeClient(renamed)
+
+		_, hook := reconcileHook(t, c, clients)
+
+		if auth.Hook("project-test", "old") != nil {
+			t.Error("expected the old hook to be deleted")
+		}
+		if auth.Hook("project-test", "nightly") == nil {
+			t.Error("expected the new hook to be created")
+		}
+		if hook.Status.HookID != "nightly" {
+			t.Errorf("expected the status to track the new hook, got %+v", hook.Status)
+		}
+	})
+
+	t.Run("triggers the hook once", func(t *testing.T) {
+		auth, clients := newTestAuth(t)
+		defer auth.Close()
+		triggered := testHook("project-test", "nightly")
+		triggered.Annotations = map[string]string{triggerHookAnnotation: "1"}
+		c := newFakeClient(triggered)
+
+		reconcileHook(t, c, clients)
+		_, hook := reconcileHook(t, c, clients)
+
+		if requests := auth.Requests(); countRequests(requests, "POST "+testHookPath+"/trigger") != 1 {
+			t.Errorf("expected the hook to be triggered once, got %v", requests)
+		}
+		if hook.Status.LastTriggerRequest != "1" || hook.Status.LastFireTaskID != "task-1" {
+			t.Errorf("unexpected status %+v", hook.Status)
+		}
+
+		// Changing the annotation triggers the hook again.
+		hook.Annotations[triggerHookAnnotation] = "2"
+		if err := c.Update(context.Background(), hook); err != nil {
+			t.Fatal(err)
+		}
+		_, hook = reconcileHook(t, c, clients)
+
+		if requests := auth.Requests(); countRequests(requests, "POST "+testHookPath+"/trigger") != 1 {
+			t.Errorf("expected the hook to be triggered again, got %v", requests)
+		}
+		if hook.Status.LastTriggerRequest != "2" || hook.Status.LastFireTaskID != "task-2" {
+			t.Errorf("unexpected status %+v", hook.Status)
+		}
+	})
+
+	t.Run("deletes the hook", func(t *testing.T) {
+		auth, clients := newTestAuth(t)
+		defer auth.Close()
+		auth.SetHook(tcclient.Hook{HookGroupID: "project-test", HookID: "nightly"})
+
+		now := metav1.Now()
+		deleted := testHook("project-test", "nightly")
+		deleted.DeletionTimestamp = &now
+		deleted.Finalizers = []string{taskClusterFinalizer}
+		deleted.Status.HookGroupID = "project-test"
+		deleted.Status.HookID = "nightly"
+		c := newFakeClient(deleted)
+
+		_, hook := reconcileHook(t, c, clients)
+
+		if auth.Hook("project-test", "nightly") != nil {
+			t.Error("expected the hook to be deleted")
+		}
+		if hasFinalizer(hook.Finalizers, taskClusterFinalizer) {
+			t.Error("expected the finalizer to be removed")
+		}
+	})
+}
+
+func TestHookReconcilerStatus(t *testing.T) {
+	soon := testNow.Add(2 * time.Minute)
+	later := testNow.Add(time.Hour)
+	lastFire := testNow.Add(-time.Hour)
+
+	tests := []struct {
+		name string
+		next *time.Time
+
+		requeueAfter time.Duration
+	}{
+		{
+			name:         "not scheduled",
+			requeueAfter: taskClusterResyncPeriod,
+		},
+		{
+			name:         "scheduled soon",
+			next:         &soon,
+			requeueAfter: 3 * time.Minute,
+		},
+		{
+			name:         "scheduled later",
+			next:         &later,
+			requeueAfter: taskClusterResyncPeriod,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth, clients := newTestAuth(t)
+			defer auth.Close()
+			auth.SetHookStatus("project-test", "nightly", tcclient.HookStatus{
+				LastFire:          tcclient.HookLastFire{Result: "success", TaskID: "task-0", Time: lastFire},
+				NextScheduledDate: tt.next,
+			})
+			c := newFakeClient(testHook("project-test", "nightly"))
+
+			result, hook := reconcileHook(t, c, clients)
+
+			if result.RequeueAfter != tt.requeueAfter {
+				t.Errorf("expected requeue after %v, got %v", tt.requeueAfter, result.RequeueAfter)
+			}
+			if hook.Status.LastFireResult != "success" || hook.Status.LastFireTaskID != "task-0" {
+				t.Errorf("unexpected last fire %+v", hook.Status)
+			}
+			if hook.Status.LastFireTime == nil || !hook.Status.LastFireTime.Time.Equal(lastFire) {
+				t.Errorf("expected last fire at %v, got %v", lastFire, hook.Status.LastFireTime)
+			}
+			if next := hook.Status.NextScheduledTime; (next == nil) != (tt.next == nil) || (next != nil && !next.Time.Equal(*tt.next)) {
+				t.Errorf("expected next fire at %v, got %v", tt.next, next)
+			}
+			if hook.Status.LastSyncTime == nil || !hook.Status.LastSyncTime.Time.Equal(testNow) {
+				t.Errorf("expected last sync at %v, got %v", testNow, hook.Status.LastSyncTime)
+			}
+		})
+	}
+}
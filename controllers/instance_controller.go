@@ -20,11 +20,15 @@ import (
 	"context"
 	"github.com/go-logr/logr"
 	"github.com/wellplayedgames/tiny-operator/pkg/composite"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 	"time"
 
@@ -49,6 +53,7 @@ type InstanceReconciler struct {
 // +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles;rolebindings,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=cert-manager.io,resources=certificates,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=extensions,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=sql.cnrm.cloud.google.com,resources=sqlinstances;sqldatabases,verbs=get;list;watch
 
 func (r *InstanceReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
@@ -113,12 +118,28 @@ func (r *InstanceReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		return ctrl.Result{}, err
 	}
 
+	ready, err := ops.ResolveWebSockTunnel(ctx)
+	if err != nil {
+		progressing.Reason = "WebSockTunnelFailed"
+		progressing.Message = err.Error()
+		return ctrl.Result{}, err
+	}
+
+	instance.Status.WebSockTunnelURL = ops.WebSockTunnelURL
+	if !ready {
+		progressing.Reason = "WaitingForWebSockTunnel"
+		progressing.Message = "Waiting for WebSockTunnel to become ready"
+		return ctrl.Result{}, nil
+	}
+
 	r.Log.Info("migrating state")
 	if err := ops.MigrateState(ctx); err != nil {
 		progressing.Reason = "MigrateFailed"
 		progressing.Message = err.Error()
 		return ctrl.Result{}, err
 	}
+	instance.Status.CryptoKeys = ops.CryptoKeyStatus()
+	instance.Status.AccessTokenRotation = ops.AccessTokenRotationStatus()
 
 	r.Log.Info("rendering values")
 	objects, err := ops.Build(ctx)
@@ -158,14 +179,155 @@ func (r *InstanceReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		return ctrl.Result{}, err
 	}
 
+	err = ops.RetireCryptoKeys(ctx, objects)
+	instance.Status.CryptoKeys = ops.CryptoKeyStatus()
+	if err != nil {
+		progressing.Reason = "CryptoKeyRetirementFailed"
+		progressing.Message = err.Error()
+		return ctrl.Result{}, err
+	}
+
+	tokensReady, err := ops.AccessTokensReady(ctx, objects)
+	if err != nil {
+		progressing.Reason = "AccessTokenStatusFailed"
+		progressing.Message = err.Error()
+		return ctrl.Result{}, err
+	}
+
+	rotating, err := ops.ProgressAccessTokenRotation(ctx, objects)
+	instance.Status.AccessTokenRotation = ops.AccessTokenRotationStatus()
+	if err != nil {
+		progressing.Reason = "AccessTokenRotationFailed"
+		progressing.Message = err.Error()
+		return ctrl.Result{}, err
+	}
+
+	if rotating {
+		progressing.Status = corev1.ConditionTrue
+		progressing.Reason = "RotatingAccessTokens"
+		progressing.Message = "Waiting for services to pick up new access tokens"
+		return ctrl.Result{RequeueAfter: 15 * time.Second}, nil
+	}
+
 	progressing.Status = corev1.ConditionTrue
 	progressing.Reason = "Reconciled"
-	return ctrl.Result{}, nil
+
+	requeueAfter := ops.RequeueAfter()
+	if !tokensReady && (requeueAfter == 0 || requeueAfter > 15*time.Second) {
+		requeueAfter = 15 * time.Second
+	}
+
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// instancesForSecret maps a Secret to the Instances which use it as their
+// WebSockTunnel secret, either directly or through a WebSockTunnel.
+func (r *InstanceReconciler) instancesForSecret(obj handler.MapObject) []reconcile.Request {
+	requests := r.instancesMatching(obj.Meta.GetNamespace(), fieldWebSockTunnelSecretRef, obj.Meta.GetName())
+
+	var tunnels taskclusterv1beta1.WebSockTunnelList
+	if err := r.Client.List(context.Background(), &tunnels, client.InNamespace(obj.Meta.GetNamespace()), client.MatchingFields{fieldSecretRef: obj.Meta.GetName()}); err != nil {
+		r.Log.Error(err, "failed to list websocktunnels", "field", fieldSecretRef)
+		return requests
+	}
+
+	for _, wst := range tunnels.Items {
+		requests = append(requests, r.instancesMatching(wst.Namespace, fieldWebSockTunnelRef, wst.Name)...)
+	}
+
+	return requests
+}
+
+// instancesForDeployment maps a WebSockTunnel's Deployment to the Instances
+// which reference the tunnel or its secret, so they switch secrets once it
+// has rolled out.
+func (r *InstanceReconciler) instancesForDeployment(obj handler.MapObject) []reconcile.Request {
+	owner := metav1.GetControllerOf(obj.Meta)
+	if owner == nil || owner.Kind != "WebSockTunnel" {
+		return nil
+	}
+
+	requests := r.instancesMatching(obj.Meta.GetNamespace(), fieldWebSockTunnelRef, owner.Name)
+
+	var wst taskclusterv1beta1.WebSockTunnel
+	name := types.NamespacedName{
+		Namespace: obj.Meta.GetNamespace(),
+		Name:      owner.Name,
+	}
+	if err := r.Client.Get(context.Background(), name, &wst); err != nil {
+		r.Log.Error(err, "failed to get websocktunnel", "name", name)
+		return requests
+	}
+
+	return append(requests, r.instancesMatching(wst.Namespace, fieldWebSockTunnelSecretRef, webSockTunnelSecretName(&wst))...)
+}
+
+// instancesForWebSockTunnel maps a WebSockTunnel to the Instances which
+// reference it.
+func (r *InstanceReconciler) instancesForWebSockTunnel(obj handler.MapObject) []reconcile.Request {
+	return r.instancesMatching(obj.Meta.GetNamespace(), fieldWebSockTunnelRef, obj.Meta.GetName())
+}
+
+func (r *InstanceReconciler) instancesMatching(namespace, field, value string) []reconcile.Request {
+	var instances taskclusterv1beta1.InstanceList
+	if err := r.Client.List(context.Background(), &instances, client.InNamespace(namespace), client.MatchingFields{field: value}); err != nil {
+		r.Log.Error(err, "failed to list instances", "field", field)
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(instances.Items))
+	for _, instance := range instances.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: instance.Namespace,
+				Name:      instance.Name,
+			},
+		})
+	}
+
+	return requests
 }
 
 func (r *InstanceReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	ctx := context.Background()
+
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &taskclusterv1beta1.Instance{}, fieldWebSockTunnelSecretRef, func(obj runtime.Object) []string {
+		instance := obj.(*taskclusterv1beta1.Instance)
+		if ref := instance.Spec.WebSockTunnelSecretRef; ref != nil {
+			return []string{ref.Name}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &taskclusterv1beta1.Instance{}, fieldWebSockTunnelRef, func(obj runtime.Object) []string {
+		instance := obj.(*taskclusterv1beta1.Instance)
+		if ref := instance.Spec.WebSockTunnelRef; ref != nil {
+			return []string{ref.Name}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &taskclusterv1beta1.WebSockTunnel{}, fieldSecretRef, func(obj runtime.Object) []string {
+		return []string{webSockTunnelSecretName(obj.(*taskclusterv1beta1.WebSockTunnel))}
+	}); err != nil {
+		return err
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&taskclusterv1beta1.Instance{}).
 		Watches(&source.Kind{Type: &taskclusterv1beta1.AccessToken{}}, &enqueueRequestForInstance{}).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.instancesForSecret),
+		}).
+		Watches(&source.Kind{Type: &taskclusterv1beta1.WebSockTunnel{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.instancesForWebSockTunnel),
+		}).
+		Watches(&source.Kind{Type: &appsv1.Deployment{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.instancesForDeployment),
+		}).
 		Complete(r)
 }
@@ -0,0 +1,191 @@
+/*
+Copyright 2020 Well Played Games Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/wellplayedgames/taskcluster-operator/pkg/tcclient"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	taskclusterv1beta1 "github.com/wellplayedgames/taskcluster-operator/api/v1beta1"
+)
+
+const (
+	// taskClusterResyncPeriod is how often resources are compared with
+	// TaskCluster to correct changes made outside the operator.
+	taskClusterResyncPeriod = 5 * time.Minute
+)
+
+// RoleReconciler reconciles a Role object
+type RoleReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+
+	// Clients returns the TaskCluster client used for an Instance. It
+	// defaults to RootClients.
+	Clients TaskClusterClientFunc
+}
+
+// +kubebuilder:rbac:groups=taskcluster.wellplayed.games,resources=roles,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=taskcluster.wellplayed.games,resources=roles/status,verbs=get;update;patch
+
+func (r *RoleReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	logger := r.Log.WithValues("role", req.NamespacedName)
+
+	var role taskclusterv1beta1.Role
+	if err := r.Client.Get(ctx, req.NamespacedName, &role); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	instanceName := instanceRefName(&role.Spec.InstanceRef, role.Namespace)
+
+	if role.DeletionTimestamp != nil {
+		if !hasFinalizer(role.Finalizers, taskClusterFinalizer) {
+			return ctrl.Result{}, nil
+		}
+
+		if err := r.deleteRole(ctx, instanceName, &role); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		logger.Info("deleted role", "roleId", role.Status.RoleID)
+		role.Finalizers = removeFinalizer(role.Finalizers, taskClusterFinalizer)
+		return ctrl.Result{}, r.Client.Update(ctx, &role)
+	}
+
+	if !hasFinalizer(role.Finalizers, taskClusterFinalizer) {
+		role.Finalizers = append(role.Finalizers, taskClusterFinalizer)
+		if err := r.Client.Update(ctx, &role); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	now := time.Now()
+	ready := taskclusterv1beta1.SyncCondition{
+		Type:               taskclusterv1beta1.SyncReady,
+		LastTransitionTime: metav1.Time{Time: now},
+		Status:             corev1.ConditionFalse,
+		Reason:             "Unknown",
+	}
+	defer func() {
+		setSyncCondition(&role.Status.Conditions, ready)
+
+		err := r.Client.Status().Update(ctx, &role)
+		if err != nil {
+			logger.Error(err, "failed to update status")
+		}
+	}()
+
+	tc, err := r.Clients(ctx, instanceName)
+	if err != nil {
+		ready.Reason = "InstanceUnavailable"
+		ready.Message = err.Error()
+		return ctrl.Result{}, err
+	}
+
+	// Remove the previous role if the role ID changed.
+	if previous := role.Status.RoleID; previous != "" && previous != role.Spec.RoleID {
+		if err := tc.DeleteRole(ctx, previous); err != nil && !tcclient.IsNotFound(err) {
+			ready.Reason = "DeleteFailed"
+			ready.Message = err.Error()
+			return ctrl.Result{}, err
+		}
+	}
+
+	remote, err := r.syncRole(ctx, tc, &role.Spec)
+	if err != nil {
+		ready.Reason = "SyncFailed"
+		ready.Message = err.Error()
+		return ctrl.Result{}, err
+	}
+
+	role.Status.RoleID = remote.RoleID
+	role.Status.ExpandedScopes = remote.ExpandedScopes
+	role.Status.ObservedGeneration = &role.Generation
+	role.Status.LastSyncTime = &metav1.Time{Time: now}
+
+	ready.Status = corev1.ConditionTrue
+	ready.Reason = "Synced"
+	return ctrl.Result{RequeueAfter: taskClusterResyncPeriod}, nil
+}
+
+// syncRole creates the role or updates it if it differs from the spec.
+func (r *RoleReconciler) syncRole(ctx context.Context, tc *tcclient.Client, spec *taskclusterv1beta1.RoleSpec) (*tcclient.Role, error) {
+	desired := &tcclient.RoleRequest{
+		Description: spec.Description,
+		Scopes:      spec.Scopes,
+	}
+	if desired.Scopes == nil {
+		desired.Scopes = []string{}
+	}
+
+	remote, err := tc.Role(ctx, spec.RoleID)
+	if tcclient.IsNotFound(err) {
+		return tc.CreateRole(ctx, spec.RoleID, desired)
+	} else if err != nil {
+		return nil, err
+	}
+
+	if remote.Description == desired.Description && sameScopes(remote.Scopes, desired.Scopes) {
+		return remote, nil
+	}
+
+	return tc.UpdateRole(ctx, spec.RoleID, desired)
+}
+
+// deleteRole removes a role from TaskCluster. If the Instance no longer
+// exists there is nothing to remove.
+func (r *RoleReconciler) deleteRole(ctx context.Context, instanceName types.NamespacedName, role *taskclusterv1beta1.Role) error {
+	roleID := role.Status.RoleID
+	if roleID == "" {
+		return nil
+	}
+
+	tc, err := r.Clients(ctx, instanceName)
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if err := tc.DeleteRole(ctx, roleID); err != nil && !tcclient.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+func (r *RoleReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Clients == nil {
+		r.Clients = RootClients(r.Log, mgr.GetClient())
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&taskclusterv1beta1.Role{}).
+		Complete(r)
+}
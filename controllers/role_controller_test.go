@@ -0,0 +1,202 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	taskclusterv1beta1 "github.com/wellplayedgames/taskcluster-operator/api/v1beta1"
+	"github.com/wellplayedgames/taskcluster-operator/pkg/tcclient"
+	"github.com/wellplayedgames/taskcluster-operator/pkg/tcclient/tcclienttest"
+)
+
+var testInstanceName = types.NamespacedName{Namespace: "taskcluster", Name: "tc"}
+
+// newTestAuth starts a fake auth service and returns a TaskClusterClientFunc
+// which talks to it. The caller closes the service.
+func newTestAuth(t *testing.T) (*tcclienttest.Auth, TaskClusterClientFunc) {
+	auth := tcclienttest.NewAuth(&tcclient.Credentials{
+		ClientID:    "static/taskcluster/root",
+		AccessToken: "root-token",
+	})
+
+	clients := func(ctx context.Context, instance types.NamespacedName) (*tcclient.Client, error) {
+		if instance != testInstanceName {
+			t.Errorf("unexpected instance %s", instance)
+		}
+		return auth.Client(), nil
+	}
+
+	return auth, clients
+}
+
+func testRole(roleID string, scopes ...string) *taskclusterv1beta1.Role {
+	return &taskclusterv1beta1.Role{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "taskcluster", Name: "role"},
+		Spec: taskclusterv1beta1.RoleSpec{
+			InstanceRef: corev1.ObjectReference{Name: testInstanceName.Name},
+			RoleID:      roleID,
+			Description: "test role",
+			Scopes:      scopes,
+		},
+	}
+}
+
+func reconcileRole(t *testing.T, c client.Client, clients TaskClusterClientFunc) *taskclusterv1beta1.Role {
+	r := &RoleReconciler{Client: c, Log: logf.NullLogger{}, Scheme: newTestScheme(), Clients: clients}
+	name := types.NamespacedName{Namespace: "taskcluster", Name: "role"}
+	if _, err := r.Reconcile(ctrl.Request{NamespacedName: name}); err != nil {
+		t.Fatal(err)
+	}
+
+	var role taskclusterv1beta1.Role
+	if err := c.Get(context.Background(), name, &role); err != nil {
+		t.Fatal(err)
+	}
+
+	return &role
+}
+
+func TestRoleReconciler(t *testing.T) {
+	t.Run("creates the role", func(t *testing.T) {
+		auth, clients := newTestAuth(t)
+		defer auth.Close()
+		c := newFakeClient(testRole("project:test", "queue:create-task:*"))
+
+		role := reconcileRole(t, c, clients)
+
+		remote := auth.Role("project:test")
+		if remote == nil || remote.Description != "test role" || !sameScopes(remote.Scopes, []string{"queue:create-task:*"}) {
+			t.Fatalf("unexpected role %+v", remote)
+		}
+		if !hasFinalizer(role.Finalizers, taskClusterFinalizer) {
+			t.Error("expected the finalizer to be added")
+		}
+		if role.Status.RoleID != "project:test" || len(role.Status.ExpandedScopes) == 0 {
+			t.Errorf("unexpected status %+v", role.Status)
+		}
+		if !syncReady(role.Status.Conditions) {
+			t.Errorf("expected the role to be ready, got %+v", role.Status.Conditions)
+		}
+	})
+
+	t.Run("corrects drift", func(t *testing.T) {
+		auth, clients := newTestAuth(t)
+		defer auth.Close()
+		c := newFakeClient(testRole("project:test", "queue:create-task:*"))
+
+		reconcileRole(t, c, clients)
+		auth.SetRole(tcclient.Role{RoleID: "project:test", Description: "changed", Scopes: []string{"*"}})
+		auth.Requests()
+
+		reconcileRole(t, c, clients)
+
+		remote := auth.Role("project:test")
+		if remote.Description != "test role" || !sameScopes(remote.Scopes, []string{"queue:create-task:*"}) {
+			t.Errorf("expected the role to be corrected, got %+v", remote)
+		}
+		if requests := auth.Requests(); len(requests) != 2 || requests[1] != "POST /api/auth/v1/roles/project:test" {
+			t.Errorf("expected the role to be updated, got %v", requests)
+		}
+
+		// An unchanged role is left alone.
+		reconcileRole(t, c, clients)
+		if requests := auth.Requests(); len(requests) != 1 {
+			t.Errorf("expected only the role to be read, got %v", requests)
+		}
+	})
+
+	t.Run("deletes the role", func(t *testing.T) {
+		auth, clients := newTestAuth(t)
+		defer auth.Close()
+		auth.SetRole(tcclient.Role{RoleID: "project:test"})
+
+		now := metav1.Now()
+		deleted := testRole("project:test")
+		deleted.DeletionTimestamp = &now
+		deleted.Finalizers = []string{taskClusterFinalizer}
+		deleted.Status.RoleID = "project:test"
+		c := newFakeClient(deleted)
+
+		role := reconcileRole(t, c, clients)
+
+		if auth.Role("project:test") != nil {
+			t.Error("expected the role to be deleted")
+		}
+		if hasFinalizer(role.Finalizers, taskClusterFinalizer) {
+			t.Error("expected the finalizer to be removed")
+		}
+	})
+
+	t.Run("renames the role", func(t *testing.T) {
+		auth, clients := newTestAuth(t)
+		defer auth.Close()
+		auth.SetRole(tcclient.Role{RoleID: "project:old"})
+
+		renamed := testRole("project:new")
+		renamed.Status.RoleID = "project:old"
+		c := newFakeClient(renamed)
+
+		role := reconcileRole(t, c, clients)
+
+		if auth.Role("project:old") != nil {
+			t.Error("expected the previous role to be deleted")
+		}
+		if auth.Role("project:new") == nil {
+			t.Error("expected the new role to be created")
+		}
+		if role.Status.RoleID != "project:new" {
+			t.Errorf("expected status to record the new role, got %s", role.Status.RoleID)
+		}
+	})
+}
+
+func TestRootClientsUseCurrentToken(t *testing.T) {
+	ctx := context.Background()
+
+	auth := tcclienttest.NewAuth(&tcclient.Credentials{
+		ClientID:    taskclusterv1beta1.ServiceClientIDPrefix + "root",
+		AccessToken: "current",
+	})
+	defer auth.Close()
+
+	instance := &taskclusterv1beta1.Instance{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testInstanceName.Namespace, Name: testInstanceName.Name},
+		Spec:       taskclusterv1beta1.InstanceSpec{RootURL: auth.URL},
+	}
+	o := newTestOperations(instance)
+	o.source = *instance
+
+	// Auth has not yet rolled out with the pending token.
+	root := o.state.ServiceAccounts["root"]
+	root.AccessToken = "current"
+	root.PendingAccessToken = "pending"
+	if err := o.writeState(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	tc, err := RootClients(logf.NullLogger{}, o.Client)(ctx, testInstanceName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tc.Role(ctx, "missing"); !tcclient.IsNotFound(err) {
+		t.Errorf("expected the current token to be accepted, got %v", err)
+	}
+}
+
+// syncReady returns true if the Ready condition is true.
+func syncReady(conditions []taskclusterv1beta1.SyncCondition) bool {
+	for _, c := range conditions {
+		if c.Type == taskclusterv1beta1.SyncReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	taskclusterv1beta1 "github.com/wellplayedgames/taskcluster-operator/api/v1beta1"
+	"github.com/wellplayedgames/taskcluster-operator/pkg/pwgen"
+)
+
+// rotationDue returns true if a credential last rotated at lastRotated has
+// outlived the given policy.
+func (o *TaskClusterOperations) rotationDue(policy *taskclusterv1beta1.RotationPolicy, lastRotated *time.Time) bool {
+	if policy == nil || policy.Interval.Duration <= 0 || lastRotated == nil {
+		return false
+	}
+
+	return !o.now.Before(lastRotated.Add(policy.Interval.Duration))
+}
+
+// trackRotation returns the last rotation time of a credential, starting the
+// clock now if it has never been recorded, and schedules a requeue for when
+// the credential next becomes due.
+func (o *TaskClusterOperations) trackRotation(policy *taskclusterv1beta1.RotationPolicy, lastRotated *time.Time) *time.Time {
+	if lastRotated == nil {
+		now := o.now
+		lastRotated = &now
+	}
+
+	if policy != nil && policy.Interval.Duration > 0 {
+		o.scheduleRotation(lastRotated.Add(policy.Interval.Duration))
+	}
+
+	return lastRotated
+}
+
+// rotateServicePassword generates a service password if there is none or the
+// service credential policy says it is due. The state is saved before the
+// caller applies the password, as a password lost to a later failure would
+// leave the service locked out.
+func (o *TaskClusterOperations) rotateServicePassword(ctx context.Context, password *string, lastRotated **time.Time) error {
+	policy := o.source.Spec.Rotation.ServiceCredentials
+	if *password == "" || o.rotationDue(policy, *lastRotated) {
+		*password = pwgen.AlphaNumeric(20)
+		*lastRotated = nil
+	}
+	*lastRotated = o.trackRotation(policy, *lastRotated)
+
+	return o.writeState(ctx)
+}
+
+// scheduleRotation records that a reconcile is needed at the given time.
+func (o *TaskClusterOperations) scheduleRotation(at time.Time) {
+	if o.nextRotation.IsZero() || at.Before(o.nextRotation) {
+		o.nextRotation = at
+	}
+}
+
+// RequeueAfter returns how long to wait until the next scheduled rotation,
+// or zero if none is scheduled.
+func (o *TaskClusterOperations) RequeueAfter() time.Duration {
+	if o.nextRotation.IsZero() {
+		return 0
+	}
+
+	d := o.nextRotation.Sub(o.now)
+	if d < time.Second {
+		d = time.Second
+	}
+
+	return d
+}
@@ -0,0 +1,183 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	taskclusterv1beta1 "github.com/wellplayedgames/taskcluster-operator/api/v1beta1"
+)
+
+func TestRotationDue(t *testing.T) {
+	daily := &taskclusterv1beta1.RotationPolicy{Interval: metav1.Duration{Duration: 24 * time.Hour}}
+	dayAgo := testNow.Add(-24 * time.Hour)
+	recently := testNow.Add(-time.Hour)
+
+	tests := []struct {
+		name    string
+		policy  *taskclusterv1beta1.RotationPolicy
+		rotated *time.Time
+
+		due bool
+	}{
+		{
+			name:    "no policy",
+			rotated: &dayAgo,
+		},
+		{
+			name:    "no interval",
+			policy:  &taskclusterv1beta1.RotationPolicy{},
+			rotated: &dayAgo,
+		},
+		{
+			name:   "never tracked",
+			policy: daily,
+		},
+		{
+			name:    "not due",
+			policy:  daily,
+			rotated: &recently,
+		},
+		{
+			name:    "due",
+			policy:  daily,
+			rotated: &dayAgo,
+			due:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newTestOperations()
+			if due := o.rotationDue(tt.policy, tt.rotated); due != tt.due {
+				t.Errorf("expected due %v, got %v", tt.due, due)
+			}
+		})
+	}
+}
+
+func TestTrackRotation(t *testing.T) {
+	daily := &taskclusterv1beta1.RotationPolicy{Interval: metav1.Duration{Duration: 24 * time.Hour}}
+	recently := testNow.Add(-time.Hour)
+
+	tests := []struct {
+		name    string
+		policy  *taskclusterv1beta1.RotationPolicy
+		rotated *time.Time
+
+		lastRotated  time.Time
+		nextRotation time.Time
+	}{
+		{
+			name:        "first time without a policy",
+			lastRotated: testNow,
+		},
+		{
+			name:         "first time",
+			policy:       daily,
+			lastRotated:  testNow,
+			nextRotation: testNow.Add(24 * time.Hour),
+		},
+		{
+			name:         "tracked",
+			policy:       daily,
+			rotated:      &recently,
+			lastRotated:  recently,
+			nextRotation: recently.Add(24 * time.Hour),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newTestOperations()
+
+			lastRotated := o.trackRotation(tt.policy, tt.rotated)
+
+			if lastRotated == nil || !lastRotated.Equal(tt.lastRotated) {
+				t.Errorf("expected last rotation %v, got %v", tt.lastRotated, lastRotated)
+			}
+			if !o.nextRotation.Equal(tt.nextRotation) {
+				t.Errorf("expected next rotation %v, got %v", tt.nextRotation, o.nextRotation)
+			}
+		})
+	}
+}
+
+func TestRotateServicePassword(t *testing.T) {
+	daily := &taskclusterv1beta1.RotationPolicy{Interval: metav1.Duration{Duration: 24 * time.Hour}}
+	dayAgo := testNow.Add(-24 * time.Hour)
+	recently := testNow.Add(-time.Hour)
+
+	tests := []struct {
+		name     string
+		password string
+		rotated  *time.Time
+
+		rotatedPassword bool
+		lastRotated     time.Time
+	}{
+		{
+			name:            "first time",
+			rotatedPassword: true,
+			lastRotated:     testNow,
+		},
+		{
+			name:        "untracked",
+			password:    "current",
+			lastRotated: testNow,
+		},
+		{
+			name:        "not due",
+			password:    "current",
+			rotated:     &recently,
+			lastRotated: recently,
+		},
+		{
+			name:            "due",
+			password:        "current",
+			rotated:         &dayAgo,
+			rotatedPassword: true,
+			lastRotated:     testNow,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			o := newTestOperations()
+			o.source.Spec.Rotation.ServiceCredentials = daily
+			sa := o.ensureServiceAccount("queue")
+			sa.PostgresPassword = tt.password
+			sa.PostgresPasswordRotated = tt.rotated
+
+			if err := o.rotateServicePassword(ctx, &sa.PostgresPassword, &sa.PostgresPasswordRotated); err != nil {
+				t.Fatal(err)
+			}
+
+			if rotated := sa.PostgresPassword != tt.password; rotated != tt.rotatedPassword || sa.PostgresPassword == "" {
+				t.Errorf("expected rotated %v, got password %q", tt.rotatedPassword, sa.PostgresPassword)
+			}
+			if sa.PostgresPasswordRotated == nil || !sa.PostgresPasswordRotated.Equal(tt.lastRotated) {
+				t.Errorf("expected last rotation %v, got %v", tt.lastRotated, sa.PostgresPasswordRotated)
+			}
+
+			// The password must be saved before it is applied.
+			var secret corev1.Secret
+			if err := o.Client.Get(ctx, types.NamespacedName{Namespace: "taskcluster", Name: "tc-state"}, &secret); err != nil {
+				t.Fatal(err)
+			}
+			var state TaskClusterState
+			if err := json.Unmarshal(secret.Data[stateKey], &state); err != nil {
+				t.Fatal(err)
+			}
+			if saved := state.ServiceAccounts["queue"]; saved == nil || saved.PostgresPassword != sa.PostgresPassword {
+				t.Errorf("expected the password to be saved in the state, got %+v", saved)
+			}
+		})
+	}
+}
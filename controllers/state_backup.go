@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	taskclusterv1beta1 "github.com/wellplayedgames/taskcluster-operator/api/v1beta1"
+	"github.com/wellplayedgames/taskcluster-operator/pkg/envelope"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	stateBundleVersion = 1
+)
+
+// StateBundle is a portable copy of the state generated for an Instance,
+// used to restore it after the state Secret is lost or to move it to
+// another cluster.
+type StateBundle struct {
+	Version   int       `json:"version"`
+	Namespace string    `json:"namespace"`
+	Name      string    `json:"name"`
+	Exported  time.Time `json:"exported"`
+
+	// Exactly one of State or Sealed is set, depending on whether the
+	// bundle is encrypted.
+	State  *TaskClusterState `json:"state,omitempty"`
+	Sealed []byte            `json:"sealed,omitempty"`
+}
+
+// ExportState returns a bundle containing the state of an Instance,
+// encrypted with bundleKeys if it is not nil.
+func ExportState(ctx context.Context, logger logr.Logger, c client.Client, name types.NamespacedName, bundleKeys envelope.KeyProvider) ([]byte, error) {
+	o := &TaskClusterOperations{
+		Logger:         logger,
+		Client:         c,
+		NamespacedName: name,
+	}
+	if err := o.Prepare(ctx); err != nil {
+		return nil, err
+	}
+
+	if o.state.ServiceAccounts == nil {
+		return nil, fmt.Errorf("instance %s has no state to export", name)
+	}
+
+	bundle := StateBundle{
+		Version:   stateBundleVersion,
+		Namespace: name.Namespace,
+		Name:      name.Name,
+		Exported:  time.Now().UTC(),
+		State:     &o.state,
+	}
+
+	if bundleKeys != nil {
+		rawState, err := json.Marshal(&o.state)
+		if err != nil {
+			return nil, err
+		}
+
+		bundle.State = nil
+		bundle.Sealed, err = envelope.Seal(ctx, bundleKeys, rawState)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return json.MarshalIndent(&bundle, "", "  ")
+}
+
+// ImportState restores a bundle produced by ExportState as the state of an
+// Instance. Existing state is only replaced if force is set.
+//
+// If the Instance already exists, the state is written using its
+// stateEncryption settings. Otherwise it is written in plaintext, to be
+// encrypted on the first reconcile. Either way, the next reconcile sets the
+// Postgres and Pulse passwords from the restored state.
+func ImportState(ctx context.Context, logger logr.Logger, c client.Client, name types.NamespacedName, data []byte, bundleKeys envelope.KeyProvider, force bool) error {
+	var bundle StateBundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return err
+	}
+
+	if bundle.Version != stateBundleVersion {
+		return fmt.Errorf("unsupported state bundle version %d", bundle.Version)
+	}
+
+	o := &TaskClusterOperations{
+		Logger:         logger,
+		Client:         c,
+		NamespacedName: name,
+	}
+
+	switch {
+	case bundle.Sealed != nil:
+		if bundleKeys == nil {
+			return fmt.Errorf("state bundle is encrypted, a key is required")
+		}
+
+		rawState, err := envelope.Open(ctx, bundleKeys, bundle.Sealed)
+		if err != nil {
+			return err
+		}
+
+		if err := json.Unmarshal(rawState, &o.state); err != nil {
+			return err
+		}
+
+	case bundle.State != nil:
+		o.state = *bundle.State
+
+	default:
+		return fmt.Errorf("state bundle is empty")
+	}
+
+	var instance taskclusterv1beta1.Instance
+	if err := c.Get(ctx, name, &instance); err == nil {
+		o.source = instance
+		if o.stateKeys, err = o.stateKeyProvider(ctx); err != nil {
+			return err
+		}
+	} else if !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	if !force {
+		var secret corev1.Secret
+		secretName := types.NamespacedName{
+			Namespace: name.Namespace,
+			Name:      fmt.Sprintf("%s-state", name.Name),
+		}
+
+		err := c.Get(ctx, secretName, &secret)
+		if err == nil && len(secret.Data[stateKey]) > 0 {
+			return fmt.Errorf("state secret %s already exists", secretName)
+		} else if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return o.writeState(ctx)
+}
@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	taskclusterv1beta1 "github.com/wellplayedgames/taskcluster-operator/api/v1beta1"
+	"github.com/wellplayedgames/taskcluster-operator/pkg/envelope"
+)
+
+func TestStateBundleRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	source := types.NamespacedName{Namespace: "taskcluster", Name: "tc"}
+	target := types.NamespacedName{Namespace: "restored", Name: "tc"}
+
+	bundleKeys, err := envelope.NewLocalKeyProvider([]byte(strings.Repeat("k", 32)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKeys, err := envelope.NewLocalKeyProvider([]byte(strings.Repeat("x", 32)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		keys envelope.KeyProvider
+	}{
+		{name: "plaintext"},
+		{name: "encrypted", keys: bundleKeys},
+	}
+
+	for _, tt := range tests {
+		keys := tt.keys
+		encrypted := keys != nil
+		t.Run(tt.name, func(t *testing.T) {
+			o := newTestOperations(&taskclusterv1beta1.Instance{
+				ObjectMeta: metav1.ObjectMeta{Namespace: source.Namespace, Name: source.Name},
+			})
+			o.state.SessionSecret = "session"
+			o.ensureServiceAccount("auth").PostgresPassword = "postgres"
+			if err := o.writeState(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := ExportState(ctx, logf.NullLogger{}, o.Client, source, keys)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var bundle StateBundle
+			if err := json.Unmarshal(data, &bundle); err != nil {
+				t.Fatal(err)
+			}
+			if bundle.Version != stateBundleVersion || bundle.Namespace != source.Namespace || bundle.Name != source.Name {
+				t.Errorf("unexpected bundle header %+v", bundle)
+			}
+			if encrypted != (bundle.Sealed != nil) || encrypted == (bundle.State != nil) {
+				t.Fatalf("expected encrypted %v, got state %v sealed %v", encrypted, bundle.State != nil, bundle.Sealed != nil)
+			}
+			if encrypted && strings.Contains(string(data), "postgres") {
+				t.Error("encrypted bundle contains plaintext state")
+			}
+
+			if encrypted {
+				if err := ImportState(ctx, logf.NullLogger{}, o.Client, target, data, nil, false); err == nil {
+					t.Error("expected importing an encrypted bundle without a key to fail")
+				}
+				if err := ImportState(ctx, logf.NullLogger{}, o.Client, target, data, otherKeys, false); err == nil {
+					t.Error("expected importing with the wrong key to fail")
+				}
+			}
+
+			if err := ImportState(ctx, logf.NullLogger{}, o.Client, target, data, keys, false); err != nil {
+				t.Fatal(err)
+			}
+			if err := ImportState(ctx, logf.NullLogger{}, o.Client, target, data, keys, false); err == nil {
+				t.Error("expected importing over existing state to fail without force")
+			}
+			if err := ImportState(ctx, logf.NullLogger{}, o.Client, target, data, keys, true); err != nil {
+				t.Errorf("expected importing with force to succeed: %v", err)
+			}
+
+			restored := &TaskClusterOperations{Client: o.Client, NamespacedName: target}
+			if err := restored.readState(ctx); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(restored.state, o.state) {
+				t.Errorf("restored state differs:\n%+v\n%+v", restored.state, o.state)
+			}
+		})
+	}
+
+	t.Run("unsupported version", func(t *testing.T) {
+		c := newFakeClient()
+		err := ImportState(ctx, logf.NullLogger{}, c, target, []byte(`{"version":2,"state":{}}`), nil, false)
+		if err == nil || !strings.Contains(err.Error(), "version 2") {
+			t.Errorf("expected an unsupported version error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/wellplayedgames/taskcluster-operator/pkg/envelope"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// stateKeyProvider returns the key provider configured to encrypt the state
+// secret, or nil if the state is stored in plaintext.
+func (o *TaskClusterOperations) stateKeyProvider(ctx context.Context) (envelope.KeyProvider, error) {
+	spec := o.source.Spec.StateEncryption
+	if spec == nil {
+		return nil, nil
+	}
+
+	switch {
+	case spec.SecretKeyRef != nil:
+		value, err := o.secretValue(ctx, spec.SecretKeyRef)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("state encryption key is not valid base64: %w", err)
+		}
+
+		return envelope.NewLocalKeyProvider(key)
+
+	case spec.VaultTransit != nil:
+		token, err := o.secretValue(ctx, &spec.VaultTransit.TokenSecretRef)
+		if err != nil {
+			return nil, err
+		}
+
+		return &envelope.VaultTransitProvider{
+			Address: spec.VaultTransit.Address,
+			Token:   token,
+			Mount:   spec.VaultTransit.Mount,
+			KeyName: spec.VaultTransit.KeyName,
+		}, nil
+
+	case spec.GCPKMS != nil:
+		return envelope.NewGCPKMSProvider(ctx, spec.GCPKMS.KeyName)
+	}
+
+	return nil, fmt.Errorf("stateEncryption must specify a key provider")
+}
+
+// secretValue fetches a single key of a secret in the instance namespace.
+func (o *TaskClusterOperations) secretValue(ctx context.Context, ref *corev1.SecretKeySelector) (string, error) {
+	var secret corev1.Secret
+	name := types.NamespacedName{
+		Namespace: o.Namespace,
+		Name:      ref.Name,
+	}
+	if err := o.Client.Get(ctx, name, &secret); err != nil {
+		return "", err
+	}
+
+	value, ok := secret.Data[ref.Key]
+	if !ok {
+		return "", fmt.Errorf("secret %s has no key %s", ref.Name, ref.Key)
+	}
+
+	return string(value), nil
+}
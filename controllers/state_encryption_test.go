@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/wellplayedgames/taskcluster-operator/pkg/envelope"
+)
+
+// countingKeys counts the data keys wrapped by a key provider.
+type countingKeys struct {
+	envelope.KeyProvider
+	wrapped int
+}
+
+func (k *countingKeys) WrapKey(ctx context.Context, key []byte) ([]byte, error) {
+	k.wrapped++
+	return k.KeyProvider.WrapKey(ctx, key)
+}
+
+func TestWriteStateOnlyWhenChanged(t *testing.T) {
+	ctx := context.Background()
+	stateName := types.NamespacedName{Namespace: "taskcluster", Name: "tc-state"}
+
+	local, err := envelope.NewLocalKeyProvider(make([]byte, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := &countingKeys{KeyProvider: local}
+
+	readSecret := func(o *TaskClusterOperations) *corev1.Secret {
+		var secret corev1.Secret
+		if err := o.Client.Get(ctx, stateName, &secret); err != nil {
+			t.Fatal(err)
+		}
+		return &secret
+	}
+
+	// reread returns operations which have read the state back.
+	first := newTestOperations()
+	reread := func() *TaskClusterOperations {
+		o := newTestOperations()
+		o.Client = first.Client
+		o.state = TaskClusterState{}
+		o.stateKeys = keys
+		if err := o.readState(ctx); err != nil {
+			t.Fatal(err)
+		}
+		return o
+	}
+
+	// Plaintext state is encrypted even though it has not changed.
+	if err := first.writeState(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if envelope.IsSealed(readSecret(first).Data[stateKey]) {
+		t.Fatal("expected plaintext state without encryption")
+	}
+
+	o := reread()
+	if err := o.writeState(ctx); err != nil {
+		t.Fatal(err)
+	}
+	secret := readSecret(o)
+	if keys.wrapped != 1 || !envelope.IsSealed(secret.Data[stateKey]) {
+		t.Fatalf("expected plaintext state to be encrypted once, wrapped %d keys", keys.wrapped)
+	}
+
+	// Unchanged state is neither sealed nor written.
+	o = reread()
+	for i := 0; i < 3; i++ {
+		if err := o.writeState(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if keys.wrapped != 1 {
+		t.Errorf("expected unchanged state not to be sealed, wrapped %d keys", keys.wrapped)
+	}
+	if rv := readSecret(o).ResourceVersion; rv != secret.ResourceVersion {
+		t.Errorf("expected unchanged state not to be written, resource version %s became %s", secret.ResourceVersion, rv)
+	}
+
+	// Changed state is sealed and written once.
+	o.state.SessionSecret = "changed"
+	for i := 0; i < 2; i++ {
+		if err := o.writeState(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if keys.wrapped != 2 {
+		t.Errorf("expected changed state to be sealed once, wrapped %d keys", keys.wrapped)
+	}
+	if o := reread(); o.state.SessionSecret != "changed" {
+		t.Error("expected changed state to be saved")
+	}
+}
@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// storageMigrationRetryPeriod is how long to wait before retrying a failed
+// storage version migration.
+const storageMigrationRetryPeriod = time.Minute
+
+var customResourceDefinitionGVK = schema.GroupVersionKind{
+	Group:   "apiextensions.k8s.io",
+	Version: "v1",
+	Kind:    "CustomResourceDefinition",
+}
+
+// StorageVersionMigrator rewrites every object of a custom resource so that
+// it is stored in the current storage version, then removes the older
+// versions from the CustomResourceDefinition's storedVersions so that they
+// can eventually stop being served.
+//
+// The CustomResourceDefinition is read unstructured, as the apiextensions
+// types are not registered with the manager's scheme.
+type StorageVersionMigrator struct {
+	Client client.Client
+	Log    logr.Logger
+
+	// Kind is the storage version of the resource.
+	Kind schema.GroupVersionKind
+	// Resource is the plural name of the resource.
+	Resource string
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable
+func (m *StorageVersionMigrator) NeedLeaderElection() bool {
+	return true
+}
+
+// Start implements manager.Runnable. Failed migrations are retried until
+// they succeed or the manager stops.
+func (m *StorageVersionMigrator) Start(stop <-chan struct{}) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-stop
+		cancel()
+	}()
+
+	log := m.Log.WithValues("resource", m.Resource, "version", m.Kind.Version)
+	err := wait.PollImmediateUntil(storageMigrationRetryPeriod, func() (bool, error) {
+		if err := m.migrate(ctx, log); err != nil {
+			log.Error(err, "storage version migration failed")
+			return false, nil
+		}
+
+		return true, nil
+	}, stop)
+	if err == wait.ErrWaitTimeout {
+		// The manager stopped before the migration succeeded.
+		return nil
+	}
+
+	return err
+}
+
+// +kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions,verbs=get;list;watch
+// +kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions/status,verbs=get;update;patch
+
+func (m *StorageVersionMigrator) migrate(ctx context.Context, log logr.Logger) error {
+	crd := &unstructured.Unstructured{}
+	crd.SetGroupVersionKind(customResourceDefinitionGVK)
+	name := types.NamespacedName{Name: fmt.Sprintf("%s.%s", m.Resource, m.Kind.Group)}
+	if err := m.Client.Get(ctx, name, crd); err != nil {
+		return err
+	}
+
+	storedVersions, _, err := unstructured.NestedStringSlice(crd.Object, "status", "storedVersions")
+	if err != nil {
+		return err
+	}
+	if len(storedVersions) == 1 && storedVersions[0] == m.Kind.Version {
+		return nil
+	}
+
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(m.Kind.GroupVersion().WithKind(m.Kind.Kind + "List"))
+	if err := m.Client.List(ctx, list); err != nil {
+		return err
+	}
+
+	// Writing an object back unchanged makes the API server store it in the
+	// storage version. Objects changed or deleted since they were listed
+	// have already been written in the storage version.
+	for idx := range list.Items {
+		obj := &list.Items[idx]
+		if err := m.Client.Update(ctx, obj); err != nil && !apierrors.IsConflict(err) && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("migrating %s/%s: %w", obj.GetNamespace(), obj.GetName(), err)
+		}
+	}
+
+	log.Info("migrated objects to storage version", "count", len(list.Items), "previousVersions", storedVersions)
+	if err := unstructured.SetNestedStringSlice(crd.Object, []string{m.Kind.Version}, "status", "storedVersions"); err != nil {
+		return err
+	}
+
+	return m.Client.Status().Update(ctx, crd)
+}
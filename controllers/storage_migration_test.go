@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	taskclusterv1 "github.com/wellplayedgames/taskcluster-operator/api/v1"
+)
+
+func testInstanceCRD(storedVersions ...interface{}) *unstructured.Unstructured {
+	crd := &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "instances.taskcluster.wellplayed.games"},
+		"status":   map[string]interface{}{"storedVersions": storedVersions},
+	}}
+	crd.SetGroupVersionKind(customResourceDefinitionGVK)
+	return crd
+}
+
+func TestStorageVersionMigrator(t *testing.T) {
+	tests := []struct {
+		name           string
+		storedVersions []interface{}
+
+		migrated bool
+	}{
+		{
+			name:           "older versions stored",
+			storedVersions: []interface{}{"v1beta1", "v1"},
+			migrated:       true,
+		},
+		{
+			name:           "already migrated",
+			storedVersions: []interface{}{"v1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			objs := []runtime.Object{testInstanceCRD(tt.storedVersions...)}
+			for _, name := range []string{"tc", "staging"} {
+				objs = append(objs, &taskclusterv1.Instance{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "taskcluster", Name: name},
+				})
+			}
+			c := newFakeClient(objs...)
+			m := &StorageVersionMigrator{
+				Client:   c,
+				Log:      logf.NullLogger{},
+				Kind:     taskclusterv1.GroupVersion.WithKind("Instance"),
+				Resource: "instances",
+			}
+
+			if err := m.migrate(ctx, m.Log); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, name := range []string{"tc", "staging"} {
+				var instance taskclusterv1.Instance
+				if err := c.Get(ctx, types.NamespacedName{Namespace: "taskcluster", Name: name}, &instance); err != nil {
+					t.Fatal(err)
+				}
+				if written := instance.ResourceVersion != "1"; written != tt.migrated {
+					t.Errorf("expected Instance %s written %v, got resource version %s", name, tt.migrated, instance.ResourceVersion)
+				}
+			}
+
+			crd := testInstanceCRD()
+			if err := c.Get(ctx, types.NamespacedName{Name: crd.GetName()}, crd); err != nil {
+				t.Fatal(err)
+			}
+			storedVersions, _, err := unstructured.NestedStringSlice(crd.Object, "status", "storedVersions")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(storedVersions, []string{"v1"}) {
+				t.Errorf("expected only v1 to be stored, got %v", storedVersions)
+			}
+		})
+	}
+
+	t.Run("missing CustomResourceDefinition", func(t *testing.T) {
+		m := &StorageVersionMigrator{
+			Client:   newFakeClient(),
+			Log:      logf.NullLogger{},
+			Kind:     taskclusterv1.GroupVersion.WithKind("Instance"),
+			Resource: "instances",
+		}
+
+		if err := m.migrate(context.Background(), m.Log); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
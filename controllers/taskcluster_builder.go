@@ -15,12 +15,13 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
-	"strings"
 
 	taskclusterv1beta1 "github.com/wellplayedgames/taskcluster-operator/api/v1beta1"
 	batchv1beta1 "k8s.io/api/batch/v1beta1"
 	batchv2alpha1 "k8s.io/api/batch/v2alpha1"
 	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
 type PostgresAccess struct {
@@ -346,9 +347,9 @@ func (o *TaskClusterOperations) patchResources(objects []runtime.Object) {
 		// Enable cert-manager on ingress.
 		gk := obj.GetObjectKind().GroupVersionKind().GroupKind()
 		extGk := schema.GroupKind{Group: extensionsv1beta1.GroupName, Kind: "Ingress"}
-		appsGk := schema.GroupKind{Group: appsv1.GroupName, Kind: "Ingress"}
+		netGk := schema.GroupKind{Group: networkingv1.GroupName, Kind: "Ingress"}
 
-		if gk == extGk || gk == appsGk {
+		if gk == extGk || gk == netGk {
 			acc, err := meta.Accessor(obj)
 			if err != nil {
 				panic(err)
@@ -376,9 +377,7 @@ func (o *TaskClusterOperations) patchResources(objects []runtime.Object) {
 		// Set Ingress TLS secret natively using ingress fields.
 		tlsSecretRef := o.source.Spec.Ingress.TLSSecretRef
 		if tlsSecretRef != nil {
-			domain := o.source.Spec.RootURL
-			domain = strings.Replace(domain, "https://", "", -1)
-			domain = strings.Replace(domain, "http://", "", -1)
+			domain := ingressHost(o.source.Spec.RootURL)
 
 			if ingress, ok := obj.(*extensionsv1beta1.Ingress); ok {
 				ingress.Spec.TLS = []extensionsv1beta1.IngressTLS{
@@ -388,6 +387,18 @@ func (o *TaskClusterOperations) patchResources(objects []runtime.Object) {
 					},
 				}
 			}
+
+			if ingress, ok := obj.(*unstructured.Unstructured); ok && gk == netGk {
+				tls := []interface{}{
+					map[string]interface{}{
+						"hosts":      []interface{}{domain},
+						"secretName": tlsSecretRef.Name,
+					},
+				}
+				if err := unstructured.SetNestedSlice(ingress.Object, tls, "spec", "tls"); err != nil {
+					panic(err)
+				}
+			}
 		}
 	}
 }
@@ -404,6 +415,7 @@ func (o *TaskClusterOperations) Build(ctx context.Context) ([]runtime.Object, er
 	}
 
 	objects = append(objects, o.createDBUpgradeJob()...)
+	objects = append(objects, o.buildIngress())
 
 	o.patchResources(objects)
 	return objects, nil
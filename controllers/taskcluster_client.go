@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+	taskclusterv1beta1 "github.com/wellplayedgames/taskcluster-operator/api/v1beta1"
+	"github.com/wellplayedgames/taskcluster-operator/pkg/tcclient"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// taskClusterFinalizer is added to resources which must be removed from
+	// TaskCluster before they are deleted.
+	taskClusterFinalizer = resourceOwner + "/taskcluster"
+
+	// Keys of Secrets holding dedicated TaskCluster credentials.
+	keyClientID    = "clientId"
+	keyAccessToken = "accessToken"
+)
+
+// TaskClusterClientFunc returns a TaskCluster client for an Instance.
+type TaskClusterClientFunc func(ctx context.Context, instance types.NamespacedName) (*tcclient.Client, error)
+
+// RootClients returns a TaskClusterClientFunc which authenticates as the
+// root static client the operator generates for each Instance.
+func RootClients(logger logr.Logger, c client.Client) TaskClusterClientFunc {
+	return func(ctx context.Context, instance types.NamespacedName) (*tcclient.Client, error) {
+		o := &TaskClusterOperations{
+			Logger:         logger,
+			Client:         c,
+			NamespacedName: instance,
+		}
+		if err := o.Prepare(ctx); err != nil {
+			return nil, err
+		}
+
+		sa := o.state.ServiceAccounts["root"]
+		if sa == nil || sa.AccessToken == "" {
+			return nil, fmt.Errorf("instance %s has no root credentials yet", instance)
+		}
+
+		// Auth only accepts a pending token once it has rolled out, after
+		// which the rotation makes it the current token.
+		return tcclient.New(o.source.Spec.RootURL, &tcclient.Credentials{
+			ClientID:    taskclusterv1beta1.ServiceClientIDPrefix + "root",
+			AccessToken: sa.AccessToken,
+		}), nil
+	}
+}
+
+// dedicatedClient returns a client for an Instance which authenticates with
+// the credentials stored in a Secret.
+func dedicatedClient(ctx context.Context, c client.Client, instance types.NamespacedName, secretName types.NamespacedName) (*tcclient.Client, error) {
+	var source taskclusterv1beta1.Instance
+	if err := c.Get(ctx, instance, &source); err != nil {
+		return nil, err
+	}
+
+	var secret corev1.Secret
+	if err := c.Get(ctx, secretName, &secret); err != nil {
+		return nil, err
+	}
+
+	clientID := string(secret.Data[keyClientID])
+	accessToken := string(secret.Data[keyAccessToken])
+	if clientID == "" || accessToken == "" {
+		return nil, fmt.Errorf("secret %s must contain %s and %s", secretName, keyClientID, keyAccessToken)
+	}
+
+	return tcclient.New(source.Spec.RootURL, &tcclient.Credentials{
+		ClientID:    clientID,
+		AccessToken: accessToken,
+	}), nil
+}
+
+// instanceRefName resolves an Instance reference, defaulting the namespace
+// to that of the referring object.
+func instanceRefName(ref *corev1.ObjectReference, namespace string) types.NamespacedName {
+	if ref.Namespace != "" {
+		namespace = ref.Namespace
+	}
+
+	return types.NamespacedName{
+		Namespace: namespace,
+		Name:      ref.Name,
+	}
+}
+
+// setSyncCondition updates a condition in a list, only changing the
+// transition time if the condition's status changed.
+func setSyncCondition(conditions *[]taskclusterv1beta1.SyncCondition, condition taskclusterv1beta1.SyncCondition) {
+	for idx := range *conditions {
+		c := &(*conditions)[idx]
+		if c.Type == condition.Type {
+			if c.Status != condition.Status {
+				c.LastTransitionTime = condition.LastTransitionTime
+			}
+
+			c.Status = condition.Status
+			c.Message = condition.Message
+			c.Reason = condition.Reason
+			return
+		}
+	}
+
+	*conditions = append(*conditions, condition)
+}
+
+// hasFinalizer returns true if the object has the given finalizer.
+func hasFinalizer(finalizers []string, finalizer string) bool {
+	for _, f := range finalizers {
+		if f == finalizer {
+			return true
+		}
+	}
+
+	return false
+}
+
+// removeFinalizer returns finalizers without the given finalizer.
+func removeFinalizer(finalizers []string, finalizer string) []string {
+	result := make([]string, 0, len(finalizers))
+	for _, f := range finalizers {
+		if f != finalizer {
+			result = append(result, f)
+		}
+	}
+
+	return result
+}
+
+// sameScopes returns true if two scope lists contain the same scopes,
+// ignoring order and duplicates.
+func sameScopes(a, b []string) bool {
+	return strings.Join(normalizeScopes(a), "\n") == strings.Join(normalizeScopes(b), "\n")
+}
+
+func normalizeScopes(scopes []string) []string {
+	seen := map[string]bool{}
+	result := make([]string, 0, len(scopes))
+	for _, s := range scopes {
+		if !seen[s] {
+			seen[s] = true
+			result = append(result, s)
+		}
+	}
+
+	sort.Strings(result)
+	return result
+}
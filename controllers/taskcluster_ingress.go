@@ -0,0 +1,178 @@
+package controllers
+
+import (
+	"net/url"
+	"strings"
+
+	taskclusterv1beta1 "github.com/wellplayedgames/taskcluster-operator/api/v1beta1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const instanceIngressName = "taskcluster-ingress"
+
+// ingressGVK is the networking.k8s.io/v1 Ingress. That version is not
+// available to this version of client-go, so the Ingress is built
+// unstructured.
+var ingressGVK = networkingv1.SchemeGroupVersion.WithKind("Ingress")
+
+// ingressRoute sends requests for a path to a TaskCluster service.
+type ingressRoute struct {
+	Path    string
+	Service string
+	// Subpaths also routes every path below Path. Otherwise only Path
+	// itself is routed.
+	Subpaths bool
+	// SkipExact does not route Path itself when Subpaths is set. GCE
+	// wildcards cannot express this, so it only affects gce.
+	SkipExact bool
+}
+
+var ingressRoutes = []ingressRoute{
+	{Path: "/", Service: "taskcluster-ui", Subpaths: true, SkipExact: true},
+	{Path: "/references", Service: "taskcluster-references", Subpaths: true},
+	{Path: "/schemas", Service: "taskcluster-references", Subpaths: true},
+	{Path: "/api/auth", Service: "taskcluster-auth", Subpaths: true, SkipExact: true},
+	{Path: "/api/github", Service: "taskcluster-github", Subpaths: true, SkipExact: true},
+	{Path: "/api/hooks", Service: "taskcluster-hooks", Subpaths: true, SkipExact: true},
+	{Path: "/api/index", Service: "taskcluster-index", Subpaths: true, SkipExact: true},
+	{Path: "/api/notify", Service: "taskcluster-notify", Subpaths: true, SkipExact: true},
+	{Path: "/api/object", Service: "taskcluster-object", Subpaths: true, SkipExact: true},
+	{Path: "/api/purge-cache", Service: "taskcluster-purge-cache", Subpaths: true, SkipExact: true},
+	{Path: "/api/queue", Service: "taskcluster-queue", Subpaths: true, SkipExact: true},
+	{Path: "/api/secrets", Service: "taskcluster-secrets", Subpaths: true, SkipExact: true},
+	{Path: "/api/worker-manager", Service: "taskcluster-worker-manager", Subpaths: true, SkipExact: true},
+	{Path: "/login", Service: "taskcluster-web-server", Subpaths: true},
+	{Path: "/subscription", Service: "taskcluster-web-server"},
+	{Path: "/graphql", Service: "taskcluster-web-server"},
+}
+
+// ingressController returns the controller an Instance's Ingress is written
+// for.
+func ingressController(spec *taskclusterv1beta1.InstanceIngressSpec) taskclusterv1beta1.IngressController {
+	if spec.Controller == "" {
+		return taskclusterv1beta1.IngressControllerGCE
+	}
+
+	return spec.Controller
+}
+
+// ingressHost returns the host an Instance is served on.
+func ingressHost(rootURL string) string {
+	if u, err := url.Parse(rootURL); err == nil && u.Host != "" {
+		return u.Host
+	}
+
+	host := strings.TrimPrefix(rootURL, "https://")
+	host = strings.TrimPrefix(host, "http://")
+	return strings.TrimSuffix(host, "/")
+}
+
+// ingressPaths renders the HTTP paths of the Ingress for a controller. GCE
+// only understands trailing /* wildcards, while other controllers use Prefix
+// paths.
+func ingressPaths(controller taskclusterv1beta1.IngressController) []interface{} {
+	var paths []interface{}
+	add := func(path, pathType, service string) {
+		paths = append(paths, map[string]interface{}{
+			"path":     path,
+			"pathType": pathType,
+			"backend": map[string]interface{}{
+				"service": map[string]interface{}{
+					"name": service,
+					"port": map[string]interface{}{
+						"number": int64(80),
+					},
+				},
+			},
+		})
+	}
+
+	for _, route := range ingressRoutes {
+		if controller != taskclusterv1beta1.IngressControllerGCE {
+			if route.Subpaths {
+				add(route.Path, "Prefix", route.Service)
+			} else {
+				add(route.Path, "Exact", route.Service)
+			}
+			continue
+		}
+
+		if !route.Subpaths || !route.SkipExact {
+			add(route.Path, "ImplementationSpecific", route.Service)
+		}
+		if route.Subpaths {
+			add(strings.TrimSuffix(route.Path, "/")+"/*", "ImplementationSpecific", route.Service)
+		}
+	}
+
+	return paths
+}
+
+// ingressAnnotations returns the annotations a controller needs.
+func ingressAnnotations(spec *taskclusterv1beta1.InstanceIngressSpec) map[string]string {
+	annotations := map[string]string{}
+
+	switch ingressController(spec) {
+	case taskclusterv1beta1.IngressControllerGCE:
+		if spec.StaticIPName != "" {
+			annotations["kubernetes.io/ingress.global-static-ip-name"] = spec.StaticIPName
+		}
+
+	case taskclusterv1beta1.IngressControllerNginx:
+		// Keep GraphQL subscriptions open.
+		annotations["nginx.ingress.kubernetes.io/proxy-read-timeout"] = "3600"
+		annotations["nginx.ingress.kubernetes.io/proxy-send-timeout"] = "3600"
+
+	case taskclusterv1beta1.IngressControllerTraefik:
+		if spec.TLSSecretRef != nil {
+			annotations["traefik.ingress.kubernetes.io/router.tls"] = "true"
+		}
+	}
+
+	for k, v := range spec.Annotations {
+		annotations[k] = v
+	}
+
+	return annotations
+}
+
+// buildIngress builds the Ingress in front of the TaskCluster services. TLS
+// and cert-manager annotations are added by patchResources.
+func (o *TaskClusterOperations) buildIngress() *unstructured.Unstructured {
+	ingressSpec := &o.source.Spec.Ingress
+	controller := ingressController(ingressSpec)
+
+	spec := map[string]interface{}{
+		"rules": []interface{}{
+			map[string]interface{}{
+				"host": ingressHost(o.source.Spec.RootURL),
+				"http": map[string]interface{}{
+					"paths": ingressPaths(controller),
+				},
+			},
+		},
+	}
+
+	className := ingressSpec.ClassName
+	if className == "" && controller != taskclusterv1beta1.IngressControllerGCE {
+		className = string(controller)
+	}
+	if className != "" {
+		spec["ingressClassName"] = className
+	}
+
+	ingress := &unstructured.Unstructured{}
+	ingress.SetGroupVersionKind(ingressGVK)
+	ingress.SetName(instanceIngressName)
+	ingress.SetNamespace(o.source.Namespace)
+	ingress.SetLabels(map[string]string{
+		"app.kubernetes.io/name":      instanceIngressName,
+		"app.kubernetes.io/instance":  o.source.Name,
+		"app.kubernetes.io/component": "taskcluster-ingress-ingress",
+		"app.kubernetes.io/part-of":   "taskcluster",
+	})
+	ingress.SetAnnotations(ingressAnnotations(ingressSpec))
+	ingress.Object["spec"] = spec
+	return ingress
+}
@@ -0,0 +1,172 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	taskclusterv1beta1 "github.com/wellplayedgames/taskcluster-operator/api/v1beta1"
+)
+
+func TestIngressHost(t *testing.T) {
+	tests := map[string]string{
+		"https://tc.example.com":      "tc.example.com",
+		"https://tc.example.com/":     "tc.example.com",
+		"https://tc.example.com:8443": "tc.example.com:8443",
+		"http://tc.example.com":       "tc.example.com",
+		"tc.example.com/":             "tc.example.com",
+	}
+
+	for rootURL, expected := range tests {
+		if host := ingressHost(rootURL); host != expected {
+			t.Errorf("expected %s to be served on %s, got %s", rootURL, expected, host)
+		}
+	}
+}
+
+func TestIngressPaths(t *testing.T) {
+	type route struct {
+		pathType string
+		service  string
+	}
+
+	tests := []struct {
+		controller taskclusterv1beta1.IngressController
+
+		routes  map[string]route
+		missing []string
+	}{
+		{
+			controller: taskclusterv1beta1.IngressControllerGCE,
+			routes: map[string]route{
+				"/*":             {"ImplementationSpecific", "taskcluster-ui"},
+				"/references":    {"ImplementationSpecific", "taskcluster-references"},
+				"/references/*":  {"ImplementationSpecific", "taskcluster-references"},
+				"/api/queue/*":   {"ImplementationSpecific", "taskcluster-queue"},
+				"/login/*":       {"ImplementationSpecific", "taskcluster-web-server"},
+				"/graphql":       {"ImplementationSpecific", "taskcluster-web-server"},
+				"/subscription":  {"ImplementationSpecific", "taskcluster-web-server"},
+				"/api/secrets/*": {"ImplementationSpecific", "taskcluster-secrets"},
+			},
+			missing: []string{"/", "/api/queue", "/graphql/*"},
+		},
+		{
+			controller: taskclusterv1beta1.IngressControllerNginx,
+			routes: map[string]route{
+				"/":           {"Prefix", "taskcluster-ui"},
+				"/references": {"Prefix", "taskcluster-references"},
+				"/api/queue":  {"Prefix", "taskcluster-queue"},
+				"/login":      {"Prefix", "taskcluster-web-server"},
+				"/graphql":    {"Exact", "taskcluster-web-server"},
+			},
+			missing: []string{"/*", "/api/queue/*"},
+		},
+		{
+			controller: taskclusterv1beta1.IngressControllerTraefik,
+			routes: map[string]route{
+				"/":             {"Prefix", "taskcluster-ui"},
+				"/api/auth":     {"Prefix", "taskcluster-auth"},
+				"/subscription": {"Exact", "taskcluster-web-server"},
+			},
+			missing: []string{"/*", "/api/auth/*"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.controller), func(t *testing.T) {
+			routes := map[string]route{}
+			for _, raw := range ingressPaths(tt.controller) {
+				path := raw.(map[string]interface{})
+				backend := path["backend"].(map[string]interface{})["service"].(map[string]interface{})
+				if port := backend["port"].(map[string]interface{})["number"]; port != int64(80) {
+					t.Errorf("expected %s to use port 80, got %v", path["path"], port)
+				}
+
+				name := path["path"].(string)
+				if _, ok := routes[name]; ok {
+					t.Errorf("expected %s to be routed once", name)
+				}
+				routes[name] = route{path["pathType"].(string), backend["name"].(string)}
+			}
+
+			for name, expected := range tt.routes {
+				if actual, ok := routes[name]; !ok || actual != expected {
+					t.Errorf("expected %s to route to %+v, got %+v", name, expected, actual)
+				}
+			}
+			for _, name := range tt.missing {
+				if _, ok := routes[name]; ok {
+					t.Errorf("expected %s not to be routed", name)
+				}
+			}
+		})
+	}
+}
+
+func TestIngressAnnotations(t *testing.T) {
+	tests := []struct {
+		name string
+		spec taskclusterv1beta1.InstanceIngressSpec
+
+		expected map[string]string
+	}{
+		{
+			name:     "gce",
+			expected: map[string]string{},
+		},
+		{
+			name: "gce with a static IP",
+			spec: taskclusterv1beta1.InstanceIngressSpec{StaticIPName: "tc"},
+			expected: map[string]string{
+				"kubernetes.io/ingress.global-static-ip-name": "tc",
+			},
+		},
+		{
+			name: "nginx",
+			spec: taskclusterv1beta1.InstanceIngressSpec{Controller: taskclusterv1beta1.IngressControllerNginx},
+			expected: map[string]string{
+				"nginx.ingress.kubernetes.io/proxy-read-timeout": "3600",
+				"nginx.ingress.kubernetes.io/proxy-send-timeout": "3600",
+			},
+		},
+		{
+			name: "nginx with overridden annotations",
+			spec: taskclusterv1beta1.InstanceIngressSpec{
+				Controller: taskclusterv1beta1.IngressControllerNginx,
+				Annotations: map[string]string{
+					"nginx.ingress.kubernetes.io/proxy-read-timeout": "60",
+					"example.com/team": "ci",
+				},
+			},
+			expected: map[string]string{
+				"nginx.ingress.kubernetes.io/proxy-read-timeout": "60",
+				"nginx.ingress.kubernetes.io/proxy-send-timeout": "3600",
+				"example.com/team": "ci",
+			},
+		},
+		{
+			name:     "traefik",
+			spec:     taskclusterv1beta1.InstanceIngressSpec{Controller: taskclusterv1beta1.IngressControllerTraefik},
+			expected: map[string]string{},
+		},
+		{
+			name: "traefik with TLS",
+			spec: taskclusterv1beta1.InstanceIngressSpec{
+				Controller:   taskclusterv1beta1.IngressControllerTraefik,
+				TLSSecretRef: &corev1.LocalObjectReference{Name: "tc-tls"},
+			},
+			expected: map[string]string{
+				"traefik.ingress.kubernetes.io/router.tls": "true",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if annotations := ingressAnnotations(&tt.spec); !reflect.DeepEqual(annotations, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, annotations)
+			}
+		})
+	}
+}
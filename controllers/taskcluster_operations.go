@@ -1,8 +1,8 @@
 package controllers
 
 import (
+	"bytes"
 	"context"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"github.com/go-logr/logr"
@@ -10,15 +10,16 @@ import (
 	rabbithole "github.com/michaelklishin/rabbit-hole"
 	taskclusterv1beta1 "github.com/wellplayedgames/taskcluster-operator/api/v1beta1"
 	sqlv1beta1 "github.com/wellplayedgames/taskcluster-operator/pkg/cnrm/sql/v1beta1"
+	"github.com/wellplayedgames/taskcluster-operator/pkg/envelope"
 	"github.com/wellplayedgames/taskcluster-operator/pkg/pwgen"
 	"github.com/wellplayedgames/tiny-operator/pkg/errors"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"strconv"
 	"strings"
@@ -26,11 +27,9 @@ import (
 )
 
 const (
-	defaultDockerRepo = "taskcluster/taskcluster"
-	defaultVersion    = "42.1.1"
-	stateKey          = "state"
-	fieldOwner        = "taskcluster.wellplayed.games"
-	hashAnnotation    = fieldOwner + "/hash"
+	stateKey       = "state"
+	fieldOwner     = "taskcluster.wellplayed.games"
+	hashAnnotation = fieldOwner + "/hash"
 )
 
 var (
@@ -86,15 +85,23 @@ var (
 )
 
 type ServiceAccount struct {
-	AccessToken      string `json:"accessToken,omitempty"`
-	PostgresPassword string `json:"postgresPassword,omitempty"`
-	PulsePassword    string `json:"pulsePassword,omitempty"`
+	AccessToken        string `json:"accessToken,omitempty"`
+	PendingAccessToken string `json:"pendingAccessToken,omitempty"`
+	PostgresPassword   string `json:"postgresPassword,omitempty"`
+	PulsePassword      string `json:"pulsePassword,omitempty"`
 	CryptoConfig
+
+	PostgresPasswordRotated *time.Time `json:"postgresPasswordRotated,omitempty"`
+	PulsePasswordRotated    *time.Time `json:"pulsePasswordRotated,omitempty"`
 }
 
 type TaskClusterState struct {
 	ServiceAccounts map[string]*ServiceAccount `json:"serviceAccounts,omitempty"`
 	SessionSecret   string                     `json:"sessionSecret,omitempty"`
+
+	AccessTokenRotation        *AccessTokenRotation `json:"accessTokenRotation,omitempty"`
+	AccessTokensRotated        *time.Time           `json:"accessTokensRotated,omitempty"`
+	AccessTokenRotationRequest string               `json:"accessTokenRotationRequest,omitempty"`
 }
 
 type PostgresDatabase struct {
@@ -130,23 +137,48 @@ type TaskClusterOperations struct {
 	ChartPath    string
 	UsePublicIPs bool
 
-	source taskclusterv1beta1.Instance
-	state  TaskClusterState
-	dbInfo PostgresDatabase
-	db     *pgx.Conn
-	pulse  *rabbithole.Client
+	source    taskclusterv1beta1.Instance
+	state     TaskClusterState
+	stateKeys envelope.KeyProvider
+	dbInfo    PostgresDatabase
+	db        *pgx.Conn
+	pulse     *rabbithole.Client
+
+	// columnStore is the database's encrypted columns, connected to on
+	// first use.
+	columnStore encryptedColumnStore
+
+	// storedState is the plaintext of the state as last read or written,
+	// and storedSealed whether it was stored encrypted.
+	storedState  []byte
+	storedSealed bool
 
 	dbUpgradeHash string
 	dbUpgradeJob  *batchv1.Job
 
+	now          time.Time
+	nextRotation time.Time
+
+	webSockTunnelSecret string
+	WebSockTunnelURL    string
+
+	accessTokens       []*taskclusterv1beta1.AccessToken
 	accessTokenObjects []taskclusterv1beta1.StaticAccessToken
 }
 
 func (o *TaskClusterOperations) Prepare(ctx context.Context) error {
+	o.now = time.Now().UTC()
+
 	if err := o.Client.Get(ctx, o.NamespacedName, &o.source); err != nil {
 		return err
 	}
 
+	stateKeys, err := o.stateKeyProvider(ctx)
+	if err != nil {
+		return err
+	}
+	o.stateKeys = stateKeys
+
 	if err := o.readState(ctx); err != nil {
 		return err
 	}
@@ -290,7 +322,12 @@ func (o *TaskClusterOperations) connectToPulse(ctx context.Context) (*rabbithole
 	username := "guest"
 	password := "guest"
 
-	if pulseSecretRef := o.source.Spec.Pulse.AdminSecretRef; pulseSecretRef != nil {
+	pulseSpec := &o.source.Spec.Pulse
+	if pulseSpec.AdminSecretRef == nil && pulseSpec.UseGuestCredentials != nil && !*pulseSpec.UseGuestCredentials {
+		return nil, fmt.Errorf("pulse.adminSecretRef is required unless useGuestCredentials is set")
+	}
+
+	if pulseSecretRef := pulseSpec.AdminSecretRef; pulseSecretRef != nil {
 		pulseSecretName := types.NamespacedName{
 			Namespace: o.Namespace,
 			Name:      pulseSecretRef.Name,
@@ -339,15 +376,52 @@ func (o *TaskClusterOperations) readState(ctx context.Context) error {
 		return nil
 	}
 
-	return json.Unmarshal(rawState, &o.state)
+	// Plaintext state is accepted even when encryption is enabled, so that
+	// it is encrypted on the next write.
+	sealed := envelope.IsSealed(rawState)
+	if sealed {
+		if o.stateKeys == nil {
+			return fmt.Errorf("state is encrypted but no stateEncryption is configured")
+		}
+
+		plaintext, err := envelope.Open(ctx, o.stateKeys, rawState)
+		if err != nil {
+			return fmt.Errorf("error decrypting state: %w", err)
+		}
+		rawState = plaintext
+	}
+
+	if err := json.Unmarshal(rawState, &o.state); err != nil {
+		return err
+	}
+
+	o.storedState = rawState
+	o.storedSealed = sealed
+	return nil
 }
 
+// writeState saves the state if it has changed since it was read, or if it
+// needs to be encrypted or decrypted. Every seal is a call to the key
+// provider, so unchanged state is not resealed.
 func (o *TaskClusterOperations) writeState(ctx context.Context) error {
-	rawState, err := json.Marshal(&o.state)
+	plaintext, err := json.Marshal(&o.state)
 	if err != nil {
 		return err
 	}
 
+	sealed := o.stateKeys != nil
+	if o.storedState != nil && bytes.Equal(plaintext, o.storedState) && sealed == o.storedSealed {
+		return nil
+	}
+
+	rawState := plaintext
+	if sealed {
+		rawState, err = envelope.Seal(ctx, o.stateKeys, plaintext)
+		if err != nil {
+			return fmt.Errorf("error encrypting state: %w", err)
+		}
+	}
+
 	// Do not set the controller of this secret, as if the instance gets
 	// deleted, the DB will become inaccessible.
 	var secret corev1.Secret
@@ -358,7 +432,13 @@ func (o *TaskClusterOperations) writeState(ctx context.Context) error {
 		stateKey: rawState,
 	}
 
-	return o.Client.Patch(ctx, &secret, client.Apply, client.ForceOwnership, client.FieldOwner(fieldOwner))
+	if err := o.Client.Patch(ctx, &secret, client.Apply, client.ForceOwnership, client.FieldOwner(fieldOwner)); err != nil {
+		return err
+	}
+
+	o.storedState = plaintext
+	o.storedSealed = sealed
+	return nil
 }
 
 func (o *TaskClusterOperations) migrateAccessTokenResources(ctx context.Context) error {
@@ -374,65 +454,92 @@ func (o *TaskClusterOperations) migrateAccessTokenResources(ctx context.Context)
 
 	for idx := range accessTokens.Items {
 		accessToken := &accessTokens.Items[idx]
+		// Expired tokens are retired by the AccessTokenReconciler.
+		if isDynamicAccessToken(accessToken) || accessTokenExpired(accessToken, o.now) {
+			continue
+		}
+
+		if err := validateAccessToken(accessToken, &o.source); err != nil {
+			accessToken.Status.InstanceRef = instanceReference(&o.source)
+			if setAccessTokenReady(accessToken, corev1.ConditionFalse, "InvalidSpec", err.Error(), o.now) {
+				if err := o.Client.Status().Update(ctx, accessToken); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
 		accessTokenName := types.NamespacedName{
 			Namespace: accessToken.Namespace,
 			Name:      accessToken.Name,
 		}
 
-		needsUpdate := false
-		needsCreate := false
-
-		var secret corev1.Secret
-		err := o.Client.Get(ctx, accessTokenName, &secret)
-		if apierrors.IsNotFound(err) {
-			needsUpdate = true
-			needsCreate = true
-
-			secret.Namespace = accessToken.Namespace
-			secret.Name = accessToken.Name
-			secret.Data = map[string][]byte{}
-
-			if err := controllerutil.SetControllerReference(accessToken, &secret, o.Scheme); err != nil {
-				return err
-			}
-		} else if err != nil {
+		secret, err := getAccessTokenSecret(ctx, o.Client, accessToken)
+		if err != nil {
 			return err
 		}
 
-		oldClientID := string(secret.Data["client-id"])
-		accessTokenStr := string(secret.Data["access-token"])
+		oldClientID, accessTokenStr := readAccessTokenSecret(accessToken, secret)
+		needsUpdate := accessTokenStr == "" || oldClientID != accessToken.Spec.ClientID
 
-		if !needsUpdate && (oldClientID != accessToken.Spec.ClientID) {
+		rotate := !needsUpdate && accessTokenRotationDue(accessToken, o.now)
+		if rotate {
+			o.Logger.Info("rotating access token", "accessToken", accessTokenName)
 			needsUpdate = true
 		}
 
 		if needsUpdate {
 			accessTokenStr = pwgen.AlphaNumeric(30)
-			secret.Data["client-id"] = []byte(accessToken.Spec.ClientID)
-			secret.Data["access-token"] = []byte(accessTokenStr)
+		}
 
-			if needsCreate {
-				if err := o.Client.Create(ctx, &secret); err != nil {
-					return err
-				}
-			} else {
-				if err := o.Client.Update(ctx, &secret); err != nil {
-					return err
-				}
+		if err := writeAccessTokenSecret(ctx, o.Client, o.Scheme, accessToken, secret, accessTokenStr, o.source.Spec.RootURL); err != nil {
+			return err
+		}
+
+		secretName := accessTokenSecretName(accessToken).Name
+		instanceRef := instanceReference(&o.source)
+		generationChanged := accessToken.Status.ObservedGeneration == nil ||
+			*accessToken.Status.ObservedGeneration != accessToken.Generation
+		statusChanged := !accessToken.Status.Created ||
+			generationChanged ||
+			accessToken.Status.SecretName != secretName ||
+			!equality.Semantic.DeepEqual(accessToken.Status.InstanceRef, instanceRef)
+		if needsUpdate || accessToken.Status.LastRotationTime == nil {
+			recordAccessTokenRotation(accessToken, o.now)
+			statusChanged = true
+		}
+
+		// The token becomes Ready once auth has rolled out with it. Auth
+		// rejects a rotated token until then, so its consumers are only
+		// restarted at that point.
+		reason, message := "WaitingForAuth", "Waiting for the auth service to pick up the client"
+		if (needsUpdate && accessToken.Status.Created) || accessTokenRestartPending(accessToken) {
+			reason, message = accessTokenRotatingReason, "Waiting for the auth service to pick up the rotated token"
+		}
+		if needsUpdate || generationChanged || !accessTokenReady(accessToken) {
+			if setAccessTokenReady(accessToken, corev1.ConditionFalse, reason, message, o.now) {
+				statusChanged = true
 			}
 		}
 
-		if !accessToken.Status.Created ||
-			accessToken.Status.ObservedGeneration == nil ||
-			*accessToken.Status.ObservedGeneration != accessToken.Generation {
+		if statusChanged {
 			accessToken.Status.Created = true
 			accessToken.Status.ObservedGeneration = &accessToken.Generation
+			accessToken.Status.SecretName = secretName
+			accessToken.Status.InstanceRef = instanceRef
 
 			if err := o.Client.Status().Update(ctx, accessToken); err != nil {
 				return err
 			}
 		}
 
+		for _, next := range []time.Time{nextAccessTokenRotation(accessToken), accessTokenExpiry(accessToken)} {
+			if !next.IsZero() {
+				o.scheduleRotation(next)
+			}
+		}
+
+		o.accessTokens = append(o.accessTokens, accessToken)
 		o.accessTokenObjects = append(o.accessTokenObjects, taskclusterv1beta1.StaticAccessToken{
 			ClientID:    accessToken.Spec.ClientID,
 			AccessToken: accessTokenStr,
@@ -444,6 +551,42 @@ func (o *TaskClusterOperations) migrateAccessTokenResources(ctx context.Context)
 	return nil
 }
 
+// AccessTokensReady marks the static AccessTokens of the Instance Ready once
+// the auth service has rolled out with them, restarting the consumers of
+// rotated tokens. It returns false while any are still waiting for auth.
+func (o *TaskClusterOperations) AccessTokensReady(ctx context.Context, objects []runtime.Object) (bool, error) {
+	var pending []*taskclusterv1beta1.AccessToken
+	for _, accessToken := range o.accessTokens {
+		if !accessTokenReady(accessToken) {
+			pending = append(pending, accessToken)
+		}
+	}
+
+	if len(pending) == 0 {
+		return true, nil
+	}
+
+	ready, err := o.deploymentsRolledOut(ctx, objects, "auth")
+	if err != nil || !ready {
+		return false, err
+	}
+
+	for _, accessToken := range pending {
+		if accessTokenRestartPending(accessToken) {
+			if err := restartAccessTokenConsumers(ctx, o.Client, accessToken, o.now); err != nil {
+				return false, err
+			}
+		}
+
+		setAccessTokenReady(accessToken, corev1.ConditionTrue, "Synced", "", o.now)
+		if err := o.Client.Status().Update(ctx, accessToken); err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
 func (o *TaskClusterOperations) MigrateState(ctx context.Context) error {
 	// Ensure VHost
 	pulse, err := o.connectToPulse(ctx)
@@ -485,6 +628,7 @@ func (o *TaskClusterOperations) MigrateState(ctx context.Context) error {
 	for _, svc := range accessTokenServices {
 		o.ensureAccessToken(svc)
 	}
+	o.startAccessTokenRotation()
 
 	if err := o.migrateAccessTokenResources(ctx); err != nil {
 		return err
@@ -494,15 +638,7 @@ func (o *TaskClusterOperations) MigrateState(ctx context.Context) error {
 }
 
 func (o *TaskClusterOperations) dockerImage() string {
-	src := o.source.Spec.DockerImage
-
-	if src == "" {
-		return fmt.Sprintf("%s:v%s", defaultDockerRepo, defaultVersion)
-	} else if !strings.Contains(src, ":") {
-		return fmt.Sprintf("%s:v%s", src, defaultVersion)
-	} else {
-		return src
-	}
+	return taskclusterv1beta1.DefaultDockerImage(o.source.Spec.DockerImage)
 }
 
 func (o *TaskClusterOperations) RenderValues(ctx context.Context) (*TaskClusterValues, error) {
@@ -616,19 +752,8 @@ func (o *TaskClusterOperations) RenderValues(ctx context.Context) (*TaskClusterV
 
 	values.Auth.StaticAccounts = append(values.Auth.StaticAccounts, o.accessTokenObjects...)
 
-	// Fetch WST secret
-	if ref := spec.WebSockTunnelSecretRef; ref != nil {
-		var secret corev1.Secret
-		name := types.NamespacedName{
-			Namespace: o.Namespace,
-			Name:      ref.Name,
-		}
-		if err := o.Client.Get(ctx, name, &secret); err != nil {
-			return nil, err
-		}
-
-		values.Auth.WebSockTunnelSecret = (string)(secret.Data["secret"])
-	}
+	// The WST secret is fetched by ResolveWebSockTunnel.
+	values.Auth.WebSockTunnelSecret = o.webSockTunnelSecret
 
 	// Fetch Azure credentials.
 	azureAccounts := map[string]string{}
@@ -792,8 +917,8 @@ func (o *TaskClusterOperations) ensurePostgresAccess(ctx context.Context, name s
 		username = fmt.Sprintf("%s_%s", username, name)
 	}
 
-	if sa.PostgresPassword == "" {
-		sa.PostgresPassword = pwgen.AlphaNumeric(20)
+	if err := o.rotateServicePassword(ctx, &sa.PostgresPassword, &sa.PostgresPasswordRotated); err != nil {
+		return err
 	}
 
 	db, err := o.connectToPostgres(ctx)
@@ -846,8 +971,8 @@ func (o *TaskClusterOperations) ensurePulseAccess(ctx context.Context, name stri
 	dashName := strings.Replace(name, "_", "-", -1)
 	username := fmt.Sprintf("%s-taskcluster-%s", o.source.Spec.Pulse.Vhost, dashName)
 
-	if sa.PulsePassword == "" {
-		sa.PulsePassword = pwgen.AlphaNumeric(20)
+	if err := o.rotateServicePassword(ctx, &sa.PulsePassword, &sa.PulsePasswordRotated); err != nil {
+		return err
 	}
 
 	pulse, err := o.connectToPulse(ctx)
@@ -903,11 +1028,17 @@ func (o *TaskClusterOperations) getTaskClusterAccess(name string) TaskClusterAcc
 
 func (o *TaskClusterOperations) getStaticAccessToken(name string) taskclusterv1beta1.StaticAccessToken {
 	sa := o.ensureServiceAccount(name)
-	clientID := fmt.Sprintf("static/taskcluster/%s", strings.Replace(name, "_", "-", -1))
+	clientID := taskclusterv1beta1.ServiceClientIDPrefix + strings.Replace(name, "_", "-", -1)
+
+	// During a rotation auth is given the new token before the service.
+	accessToken := sa.AccessToken
+	if sa.PendingAccessToken != "" {
+		accessToken = sa.PendingAccessToken
+	}
 
 	return taskclusterv1beta1.StaticAccessToken{
 		ClientID:    clientID,
-		AccessToken: sa.AccessToken,
+		AccessToken: accessToken,
 	}
 }
 
@@ -915,7 +1046,7 @@ func (o *TaskClusterOperations) ensureCrypto(name string) {
 	sa := o.ensureServiceAccount(name)
 
 	if sa.AzureCryptoKey != "" {
-		id := strconv.Itoa(int(time.Now().Unix()))
+		id := strconv.Itoa(int(o.now.Unix()))
 		sa.DBCryptoKeys = append(sa.DBCryptoKeys, DBCryptoKey{
 			ID:        id,
 			Algorithm: "aes-256",
@@ -925,17 +1056,23 @@ func (o *TaskClusterOperations) ensureCrypto(name string) {
 	}
 
 	if len(sa.DBCryptoKeys) < 1 {
-		id := strconv.Itoa(int(time.Now().Unix()))
-		rawKey := pwgen.AlphaNumeric(32)
-		key := base64.StdEncoding.EncodeToString([]byte(rawKey))
-
-		sa.DBCryptoKeys = []DBCryptoKey{
-			{
-				ID:        id,
-				Algorithm: "aes-256",
-				Key:       key,
-			},
+		sa.DBCryptoKeys = []DBCryptoKey{newCryptoKey(o.now)}
+	}
+
+	// Prepend a new key when the active one is due, keeping the old keys
+	// around so that existing values can still be decrypted.
+	if policy := o.source.Spec.Rotation.DBCryptoKeys; policy != nil {
+		created, ok := cryptoKeyCreated(sa.DBCryptoKeys[0])
+		if !ok {
+			return
 		}
+
+		if o.rotationDue(&policy.RotationPolicy, &created) {
+			sa.DBCryptoKeys = append([]DBCryptoKey{newCryptoKey(o.now)}, sa.DBCryptoKeys...)
+			created = o.now
+		}
+
+		o.trackRotation(&policy.RotationPolicy, &created)
 	}
 }
 
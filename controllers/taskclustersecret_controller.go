@@ -0,0 +1,294 @@
+/*
+Copyright 2020 Well Played Games Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/wellplayedgames/taskcluster-operator/pkg/tcclient"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	taskclusterv1beta1 "github.com/wellplayedgames/taskcluster-operator/api/v1beta1"
+)
+
+const (
+	fieldSourceRef = ".spec.sourceRef"
+
+	defaultSecretExpiresAfter = 365 * 24 * time.Hour
+)
+
+// TaskClusterSecretReconciler reconciles a TaskClusterSecret object
+type TaskClusterSecretReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+
+	// Clients returns the TaskCluster client used for an Instance. It
+	// defaults to RootClients.
+	Clients TaskClusterClientFunc
+
+	// now returns the current time. It defaults to time.Now.
+	now func() time.Time
+}
+
+// +kubebuilder:rbac:groups=taskcluster.wellplayed.games,resources=taskclustersecrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=taskcluster.wellplayed.games,resources=taskclustersecrets/status,verbs=get;update;patch
+
+func (r *TaskClusterSecretReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	logger := r.Log.WithValues("taskclustersecret", req.NamespacedName)
+
+	var tcSecret taskclusterv1beta1.TaskClusterSecret
+	if err := r.Client.Get(ctx, req.NamespacedName, &tcSecret); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	instanceName := instanceRefName(&tcSecret.Spec.InstanceRef, tcSecret.Namespace)
+
+	if tcSecret.DeletionTimestamp != nil {
+		if !hasFinalizer(tcSecret.Finalizers, taskClusterFinalizer) {
+			return ctrl.Result{}, nil
+		}
+
+		if name := tcSecret.Status.SecretName; name != "" {
+			tc, err := r.Clients(ctx, instanceName)
+			if err != nil && !apierrors.IsNotFound(err) {
+				return ctrl.Result{}, err
+			}
+
+			if tc != nil {
+				if err := tc.RemoveSecret(ctx, name); err != nil && !tcclient.IsNotFound(err) {
+					return ctrl.Result{}, err
+				}
+			}
+		}
+
+		logger.Info("removed secret", "secretName", tcSecret.Status.SecretName)
+		tcSecret.Finalizers = removeFinalizer(tcSecret.Finalizers, taskClusterFinalizer)
+		return ctrl.Result{}, r.Client.Update(ctx, &tcSecret)
+	}
+
+	if !hasFinalizer(tcSecret.Finalizers, taskClusterFinalizer) {
+		tcSecret.Finalizers = append(tcSecret.Finalizers, taskClusterFinalizer)
+		if err := r.Client.Update(ctx, &tcSecret); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	now := time.Now()
+	if r.now != nil {
+		now = r.now()
+	}
+	now = now.UTC().Truncate(time.Second)
+	ready := taskclusterv1beta1.SyncCondition{
+		Type:               taskclusterv1beta1.SyncReady,
+		LastTransitionTime: metav1.Time{Time: now},
+		Status:             corev1.ConditionFalse,
+		Reason:             "Unknown",
+	}
+	defer func() {
+		setSyncCondition(&tcSecret.Status.Conditions, ready)
+
+		err := r.Client.Status().Update(ctx, &tcSecret)
+		if err != nil {
+			logger.Error(err, "failed to update status")
+		}
+	}()
+
+	spec := &tcSecret.Spec
+
+	// The secrets service refuses secrets which have already expired, so
+	// retrying would never succeed.
+	if spec.Expires != nil && !spec.Expires.Time.After(now) {
+		ready.Reason = "InvalidSpec"
+		ready.Message = fmt.Sprintf("expires %s is in the past", spec.Expires.Time.UTC().Format(time.RFC3339))
+		return ctrl.Result{}, nil
+	}
+
+	var source corev1.Secret
+	sourceName := types.NamespacedName{
+		Namespace: tcSecret.Namespace,
+		Name:      spec.SourceRef.Name,
+	}
+	if err := r.Client.Get(ctx, sourceName, &source); apierrors.IsNotFound(err) {
+		// The source Secret is watched, so wait for it to be created.
+		ready.Reason = "SourceNotFound"
+		ready.Message = fmt.Sprintf("Secret %s does not exist", spec.SourceRef.Name)
+		return ctrl.Result{}, nil
+	} else if err != nil {
+		ready.Reason = "SourceUnavailable"
+		ready.Message = err.Error()
+		return ctrl.Result{}, err
+	}
+
+	values, err := taskClusterSecretValues(spec, &source)
+	if err != nil {
+		ready.Reason = "InvalidSource"
+		ready.Message = err.Error()
+		return ctrl.Result{}, nil
+	}
+
+	tc, err := r.Clients(ctx, instanceName)
+	if err != nil {
+		ready.Reason = "InstanceUnavailable"
+		ready.Message = err.Error()
+		return ctrl.Result{}, err
+	}
+
+	// Remove the previous secret if it was renamed.
+	if previous := tcSecret.Status.SecretName; previous != "" && previous != spec.SecretName {
+		if err := tc.RemoveSecret(ctx, previous); err != nil && !tcclient.IsNotFound(err) {
+			ready.Reason = "RemoveFailed"
+			ready.Message = err.Error()
+			return ctrl.Result{}, err
+		}
+	}
+
+	expires, err := syncTaskClusterSecret(ctx, tc, spec, values, now)
+	if err != nil {
+		ready.Reason = "SyncFailed"
+		ready.Message = err.Error()
+		return ctrl.Result{}, err
+	}
+
+	tcSecret.Status.SecretName = spec.SecretName
+	tcSecret.Status.Expires = &metav1.Time{Time: expires}
+	tcSecret.Status.ObservedGeneration = &tcSecret.Generation
+	tcSecret.Status.LastSyncTime = &metav1.Time{Time: now}
+
+	ready.Status = corev1.ConditionTrue
+	ready.Reason = "Synced"
+	return ctrl.Result{RequeueAfter: taskClusterResyncPeriod}, nil
+}
+
+// taskClusterSecretValues returns the values to store in TaskCluster.
+func taskClusterSecretValues(spec *taskclusterv1beta1.TaskClusterSecretSpec, source *corev1.Secret) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+
+	if len(spec.Keys) == 0 {
+		for k, v := range source.Data {
+			values[k] = string(v)
+		}
+		return values, nil
+	}
+
+	for _, key := range spec.Keys {
+		value, ok := source.Data[key.Key]
+		if !ok {
+			return nil, fmt.Errorf("secret %s has no key %s", source.Name, key.Key)
+		}
+
+		property := key.Property
+		if property == "" {
+			property = key.Key
+		}
+		values[property] = string(value)
+	}
+
+	return values, nil
+}
+
+// syncTaskClusterSecret writes the secret if its values differ or its
+// expiry needs to be moved, and returns when it expires.
+func syncTaskClusterSecret(ctx context.Context, tc *tcclient.Client, spec *taskclusterv1beta1.TaskClusterSecretSpec, values map[string]interface{}, now time.Time) (time.Time, error) {
+	expiresAfter := defaultSecretExpiresAfter
+	if spec.ExpiresAfter != nil {
+		expiresAfter = spec.ExpiresAfter.Duration
+	}
+
+	desiredExpires := now.Add(expiresAfter)
+	if spec.Expires != nil {
+		desiredExpires = spec.Expires.Time.UTC().Truncate(time.Second)
+	}
+
+	remote, err := tc.Secret(ctx, spec.SecretName)
+	if err != nil && !tcclient.IsNotFound(err) {
+		return time.Time{}, err
+	}
+
+	if remote != nil && reflect.DeepEqual(remote.Secret, values) {
+		if spec.Expires != nil && remote.Expires.Equal(desiredExpires) {
+			return remote.Expires, nil
+		}
+
+		if spec.Expires == nil && remote.Expires.After(now.Add(expiresAfter/2)) {
+			return remote.Expires, nil
+		}
+	}
+
+	err = tc.SetSecret(ctx, spec.SecretName, &tcclient.Secret{
+		Secret:  values,
+		Expires: desiredExpires,
+	})
+	return desiredExpires, err
+}
+
+// secretsForSource maps a Secret to the TaskClusterSecrets copying it.
+func (r *TaskClusterSecretReconciler) secretsForSource(obj handler.MapObject) []reconcile.Request {
+	var secrets taskclusterv1beta1.TaskClusterSecretList
+	if err := r.Client.List(context.Background(), &secrets, client.InNamespace(obj.Meta.GetNamespace()), client.MatchingFields{fieldSourceRef: obj.Meta.GetName()}); err != nil {
+		r.Log.Error(err, "failed to list taskcluster secrets")
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(secrets.Items))
+	for _, secret := range secrets.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: secret.Namespace,
+				Name:      secret.Name,
+			},
+		})
+	}
+
+	return requests
+}
+
+func (r *TaskClusterSecretReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	ctx := context.Background()
+
+	if r.Clients == nil {
+		r.Clients = RootClients(r.Log, mgr.GetClient())
+	}
+
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &taskclusterv1beta1.TaskClusterSecret{}, fieldSourceRef, func(obj runtime.Object) []string {
+		secret := obj.(*taskclusterv1beta1.TaskClusterSecret)
+		return []string{secret.Spec.SourceRef.Name}
+	}); err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&taskclusterv1beta1.TaskClusterSecret{}).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.secretsForSource),
+		}).
+		Complete(r)
+}
@@ -0,0 +1,300 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	taskclusterv1beta1 "github.com/wellplayedgames/taskcluster-operator/api/v1beta1"
+	"github.com/wellplayedgames/taskcluster-operator/pkg/tcclient"
+)
+
+const testSecretPath = "/api/secrets/v1/secret/project-ci"
+
+func testTaskClusterSecret(secretName string, keys ...taskclusterv1beta1.TaskClusterSecretKey) *taskclusterv1beta1.TaskClusterSecret {
+	return &taskclusterv1beta1.TaskClusterSecret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "taskcluster", Name: "secret"},
+		Spec: taskclusterv1beta1.TaskClusterSecretSpec{
+			InstanceRef: corev1.ObjectReference{Name: testInstanceName.Name},
+			SecretName:  secretName,
+			SourceRef:   corev1.LocalObjectReference{Name: "source"},
+			Keys:        keys,
+		},
+	}
+}
+
+func testSourceSecret() *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "taskcluster", Name: "source"},
+		Data: map[string][]byte{
+			"username": []byte("ci"),
+			"password": []byte("hunter2"),
+		},
+	}
+}
+
+func reconcileTaskClusterSecret(t *testing.T, c client.Client, clients TaskClusterClientFunc) *taskclusterv1beta1.TaskClusterSecret {
+	r := &TaskClusterSecretReconciler{
+		Client:  c,
+		Log:     logf.NullLogger{},
+		Scheme:  newTestScheme(),
+		Clients: clients,
+		now:     func() time.Time { return testNow },
+	}
+	name := types.NamespacedName{Namespace: "taskcluster", Name: "secret"}
+	if _, err := r.Reconcile(ctrl.Request{NamespacedName: name}); err != nil {
+		t.Fatal(err)
+	}
+
+	var tcSecret taskclusterv1beta1.TaskClusterSecret
+	if err := c.Get(context.Background(), name, &tcSecret); err != nil {
+		t.Fatal(err)
+	}
+
+	return &tcSecret
+}
+
+// syncReadyReason returns the reason of the Ready condition.
+func syncReadyReason(conditions []taskclusterv1beta1.SyncCondition) string {
+	for _, c := range conditions {
+		if c.Type == taskclusterv1beta1.SyncReady {
+			return c.Reason
+		}
+	}
+
+	return ""
+}
+
+func TestTaskClusterSecretValues(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []taskclusterv1beta1.TaskClusterSecretKey
+
+		values map[string]interface{}
+		err    bool
+	}{
+		{
+			name:   "all keys",
+			values: map[string]interface{}{"username": "ci", "password": "hunter2"},
+		},
+		{
+			name:   "selected key",
+			keys:   []taskclusterv1beta1.TaskClusterSecretKey{{Key: "password"}},
+			values: map[string]interface{}{"password": "hunter2"},
+		},
+		{
+			name:   "renamed key",
+			keys:   []taskclusterv1beta1.TaskClusterSecretKey{{Key: "password", Property: "token"}},
+			values: map[string]interface{}{"token": "hunter2"},
+		},
+		{
+			name: "missing key",
+			keys: []taskclusterv1beta1.TaskClusterSecretKey{{Key: "password"}, {Key: "token"}},
+			err:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := testTaskClusterSecret("project-ci", tt.keys...).Spec
+
+			values, err := taskClusterSecretValues(&spec, testSourceSecret())
+			if (err != nil) != tt.err {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+			if !reflect.DeepEqual(values, tt.values) && !tt.err {
+				t.Errorf("expected %v, got %v", tt.values, values)
+			}
+		})
+	}
+}
+
+func TestTaskClusterSecretExpiry(t *testing.T) {
+	values := map[string]interface{}{"username": "ci", "password": "hunter2"}
+	fixed := testNow.Add(30 * 24 * time.Hour)
+
+	tests := []struct {
+		name          string
+		expires       *time.Time
+		remoteValues  map[string]interface{}
+		remoteExpires time.Time
+
+		written bool
+		expiry  time.Time
+	}{
+		{
+			name:          "before half-life",
+			remoteValues:  values,
+			remoteExpires: testNow.Add(200 * 24 * time.Hour),
+			expiry:        testNow.Add(200 * 24 * time.Hour),
+		},
+		{
+			name:          "after half-life",
+			remoteValues:  values,
+			remoteExpires: testNow.Add(100 * 24 * time.Hour),
+			written:       true,
+			expiry:        testNow.Add(defaultSecretExpiresAfter),
+		},
+		{
+			name:          "changed values",
+			remoteValues:  map[string]interface{}{"username": "ci"},
+			remoteExpires: testNow.Add(200 * 24 * time.Hour),
+			written:       true,
+			expiry:        testNow.Add(defaultSecretExpiresAfter),
+		},
+		{
+			name:          "fixed expiry",
+			expires:       &fixed,
+			remoteValues:  values,
+			remoteExpires: fixed,
+			expiry:        fixed,
+		},
+		{
+			name:          "fixed expiry after half-life",
+			expires:       &fixed,
+			remoteValues:  values,
+			remoteExpires: testNow.Add(200 * 24 * time.Hour),
+			written:       true,
+			expiry:        fixed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth, clients := newTestAuth(t)
+			defer auth.Close()
+			auth.SetSecret("project-ci", tcclient.Secret{Secret: tt.remoteValues, Expires: tt.remoteExpires})
+
+			tcSecret := testTaskClusterSecret("project-ci")
+			if tt.expires != nil {
+				tcSecret.Spec.Expires = &metav1.Time{Time: *tt.expires}
+			}
+			c := newFakeClient(tcSecret, testSourceSecret())
+
+			tcSecret = reconcileTaskClusterSecret(t, c, clients)
+
+			if written := countRequests(auth.Requests(), "PUT "+testSecretPath) == 1; written != tt.written {
+				t.Errorf("expected written %v, got %v", tt.written, written)
+			}
+			remote := auth.Secret("project-ci")
+			if !reflect.DeepEqual(remote.Secret, values) || !remote.Expires.Equal(tt.expiry) {
+				t.Errorf("expected %v expiring at %v, got %+v", values, tt.expiry, remote)
+			}
+			if tcSecret.Status.Expires == nil || !tcSecret.Status.Expires.Time.Equal(tt.expiry) {
+				t.Errorf("expected status expiry %v, got %v", tt.expiry, tcSecret.Status.Expires)
+			}
+			if !syncReady(tcSecret.Status.Conditions) {
+				t.Errorf("expected the secret to be ready, got %+v", tcSecret.Status.Conditions)
+			}
+		})
+	}
+}
+
+func TestTaskClusterSecretReconciler(t *testing.T) {
+	t.Run("creates the secret", func(t *testing.T) {
+		auth, clients := newTestAuth(t)
+		defer auth.Close()
+		c := newFakeClient(testTaskClusterSecret("project-ci", taskclusterv1beta1.TaskClusterSecretKey{Key: "password"}), testSourceSecret())
+
+		tcSecret := reconcileTaskClusterSecret(t, c, clients)
+
+		remote := auth.Secret("project-ci")
+		if remote == nil || !reflect.DeepEqual(remote.Secret, map[string]interface{}{"password": "hunter2"}) {
+			t.Fatalf("unexpected secret %+v", remote)
+		}
+		if !hasFinalizer(tcSecret.Finalizers, taskClusterFinalizer) {
+			t.Error("expected the finalizer to be added")
+		}
+		if tcSecret.Status.SecretName != "project-ci" || !syncReady(tcSecret.Status.Conditions) {
+			t.Errorf("unexpected status %+v", tcSecret.Status)
+		}
+	})
+
+	t.Run("missing key", func(t *testing.T) {
+		auth, clients := newTestAuth(t)
+		defer auth.Close()
+		c := newFakeClient(testTaskClusterSecret("project-ci", taskclusterv1beta1.TaskClusterSecretKey{Key: "token"}), testSourceSecret())
+
+		tcSecret := reconcileTaskClusterSecret(t, c, clients)
+
+		if auth.Secret("project-ci") != nil {
+			t.Error("expected no secret to be written")
+		}
+		if reason := syncReadyReason(tcSecret.Status.Conditions); syncReady(tcSecret.Status.Conditions) || reason != "InvalidSource" {
+			t.Errorf("expected the source to be invalid, got %+v", tcSecret.Status.Conditions)
+		}
+	})
+
+	t.Run("expiry in the past", func(t *testing.T) {
+		auth, clients := newTestAuth(t)
+		defer auth.Close()
+		tcSecret := testTaskClusterSecret("project-ci")
+		tcSecret.Spec.Expires = &metav1.Time{Time: testNow.Add(-time.Hour)}
+		c := newFakeClient(tcSecret, testSourceSecret())
+
+		tcSecret = reconcileTaskClusterSecret(t, c, clients)
+
+		if requests := auth.Requests(); len(requests) != 0 {
+			t.Errorf("expected the secrets service not to be called, got %v", requests)
+		}
+		if reason := syncReadyReason(tcSecret.Status.Conditions); syncReady(tcSecret.Status.Conditions) || reason != "InvalidSpec" {
+			t.Errorf("expected the spec to be invalid, got %+v", tcSecret.Status.Conditions)
+		}
+	})
+
+	t.Run("renames the secret", func(t *testing.T) {
+		auth, clients := newTestAuth(t)
+		defer auth.Close()
+		auth.SetSecret("project-old", tcclient.Secret{Secret: map[string]interface{}{}, Expires: testNow.Add(time.Hour)})
+
+		renamed := testTaskClusterSecret("project-ci")
+		renamed.Finalizers = []string{taskClusterFinalizer}
+		renamed.Status.SecretName = "project-old"
+		c := newFakeClient(renamed, testSourceSecret())
+
+		tcSecret := reconcileTaskClusterSecret(t, c, clients)
+
+		if auth.Secret("project-old") != nil {
+			t.Error("expected the old secret to be removed")
+		}
+		if auth.Secret("project-ci") == nil {
+			t.Error("expected the new secret to be written")
+		}
+		if tcSecret.Status.SecretName != "project-ci" {
+			t.Errorf("expected the status to track the new secret, got %+v", tcSecret.Status)
+		}
+	})
+
+	t.Run("removes the secret", func(t *testing.T) {
+		auth, clients := newTestAuth(t)
+		defer auth.Close()
+		auth.SetSecret("project-ci", tcclient.Secret{Secret: map[string]interface{}{}, Expires: testNow.Add(time.Hour)})
+
+		now := metav1.Now()
+		deleted := testTaskClusterSecret("project-ci")
+		deleted.DeletionTimestamp = &now
+		deleted.Finalizers = []string{taskClusterFinalizer}
+		deleted.Status.SecretName = "project-ci"
+		c := newFakeClient(deleted, testSourceSecret())
+
+		tcSecret := reconcileTaskClusterSecret(t, c, clients)
+
+		if requests := auth.Requests(); countRequests(requests, "DELETE "+testSecretPath) != 1 {
+			t.Errorf("expected the secret to be removed, got %v", requests)
+		}
+		if auth.Secret("project-ci") != nil {
+			t.Error("expected the secret to be removed")
+		}
+		if hasFinalizer(tcSecret.Finalizers, taskClusterFinalizer) {
+			t.Error("expected the finalizer to be removed")
+		}
+	})
+}
@@ -6,8 +6,12 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"strconv"
+	"time"
 
 	certmanagerv1alpha2 "github.com/jetstack/cert-manager/pkg/apis/certmanager/v1alpha2"
 	taskclusterv1beta1 "github.com/wellplayedgames/taskcluster-operator/api/v1beta1"
@@ -17,242 +21,609 @@ import (
 const (
 	image = "taskcluster/websocktunnel"
 
-	envoyConfig = `
-admin:
-  access_log_path: /dev/stdout
-  address:
-    socket_address: { address: 0.0.0.0, port_value: 9901 }
-
-static_resources:
-  secrets:
-  - name: server_cert
-    tls_certificate:
-      certificate_chain:
-        filename: /tls/tls.crt
-      private_key:
-        filename: /tls/tls.key
-  listeners:
-  - name: listener_0
-    address:
-      socket_address: { address: 0.0.0.0, port_value: 443 }
-    filter_chains:
-    - filters:
-      - name: envoy.tcp_proxy
-        config:
-          stat_prefix: ingress_tcp
-          cluster: service
-          max_connect_attempts: 100
-          access_log:
-            - name: envoy.file_access_log
-              config:
-                path: /dev/stdout
-      tls_context:
-        common_tls_context:
-          tls_certificate_sds_secret_configs:
-          - name: server_cert
-  clusters:
-  - name: service
-    connect_timeout: 120s
-    type: STATIC
-    dns_lookup_family: V4_ONLY
-    lb_policy: ROUND_ROBIN
-    load_assignment:
-      cluster_name: service
-      endpoints:
-      - lb_endpoints:
-        - endpoint:
-            address:
-              socket_address:
-                address: 127.0.0.1
-                port_value: 80
-`
+	secretRotatedAnnotation = fieldOwner + "/secret-rotated"
+	metricsServiceLabel     = fieldOwner + "/metrics"
+
+	defaultDrainPeriod = 30 * time.Second
+	// drainGracePeriod is how long containers get to exit after draining.
+	drainGracePeriod = 30 * time.Second
+)
+
+var (
+	httpRouteGVK = schema.GroupVersionKind{
+		Group:   "gateway.networking.k8s.io",
+		Version: "v1",
+		Kind:    "HTTPRoute",
+	}
+	podDisruptionBudgetGVK = schema.GroupVersionKind{
+		Group:   "policy",
+		Version: "v1",
+		Kind:    "PodDisruptionBudget",
+	}
+	serviceMonitorGVK = schema.GroupVersionKind{
+		Group:   "monitoring.coreos.com",
+		Version: "v1",
+		Kind:    "ServiceMonitor",
+	}
 )
 
 type WebSockTunnelBuilder struct {
 	logr.Logger
 
-	Source *taskclusterv1beta1.WebSockTunnel
+	Source      *taskclusterv1beta1.WebSockTunnel
+	LastRotated time.Time
+	// ServiceMonitors is true if the ServiceMonitor CRD is installed.
+	ServiceMonitors bool
 }
 
 func (b *WebSockTunnelBuilder) Build() ([]runtime.Object, error) {
 	name := b.Source.Name
 	namespace := b.Source.Namespace
 	spec := &b.Source.Spec
-	tlsSecretName := fmt.Sprintf("%s-tls", name)
-	envoyConfigName := fmt.Sprintf("%s-envoy", name)
+	exposure := webSockTunnelExposure(b.Source)
+	labels := map[string]string{
+		"app.kubernetes.io/component": "websocktunnel",
+		"app.kubernetes.io/name":      name,
+	}
+
+	deployment := b.buildDeployment(labels)
+	service := b.buildService(labels)
+	objects := []runtime.Object{deployment, service}
+
+	if spec.PodDisruptionBudget != nil {
+		objects = append(objects, b.buildPodDisruptionBudget(labels))
+	}
+
+	switch exposure {
+	case taskclusterv1beta1.WebSockTunnelExposeLoadBalancer:
+		config, err := webSockTunnelEnvoyConfig(&spec.Envoy)
+		if err != nil {
+			return nil, err
+		}
 
-	objects := []runtime.Object{
-		&certmanagerv1alpha2.Certificate{
+		objects = append(objects, b.buildCertificate(), &corev1.ConfigMap{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:      name,
+				Name:      b.envoyConfigName(),
 				Namespace: namespace,
 			},
-			Spec: certmanagerv1alpha2.CertificateSpec{
-				SecretName: tlsSecretName,
-				DNSNames:   []string{spec.DomainName},
-				IssuerRef:  spec.CertificateIssuerRef,
-			},
+			Data: config,
+		})
+		deployment.Spec.Template.Annotations[envoyConfigAnnotation] = envoyConfigChecksum(config)
+		b.addEnvoySidecar(&deployment.Spec.Template.Spec)
+
+		objects = append(objects, b.buildMetricsService(labels))
+		if spec.Metrics.ServiceMonitor != nil {
+			if b.ServiceMonitors {
+				objects = append(objects, b.buildServiceMonitor())
+			} else {
+				b.Logger.Info("ServiceMonitor requested but monitoring.coreos.com is not installed")
+			}
+		}
+
+	case taskclusterv1beta1.WebSockTunnelExposeIngress:
+		objects = append(objects, b.buildCertificate(), b.buildIngress())
+
+	case taskclusterv1beta1.WebSockTunnelExposeHTTPRoute:
+		if spec.HTTPRoute == nil || len(spec.HTTPRoute.ParentRefs) == 0 {
+			return nil, fmt.Errorf("httpRoute.parentRefs is required when exposure is %s", exposure)
+		}
+
+		objects = append(objects, b.buildHTTPRoute())
+
+	default:
+		return nil, fmt.Errorf("unknown exposure %q", exposure)
+	}
+
+	return objects, nil
+}
+
+func (b *WebSockTunnelBuilder) tlsSecretName() string {
+	return fmt.Sprintf("%s-tls", b.Source.Name)
+}
+
+func (b *WebSockTunnelBuilder) envoyConfigName() string {
+	return fmt.Sprintf("%s-envoy", b.Source.Name)
+}
+
+func (b *WebSockTunnelBuilder) buildCertificate() *certmanagerv1alpha2.Certificate {
+	spec := &b.Source.Spec
+	return &certmanagerv1alpha2.Certificate{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      b.Source.Name,
+			Namespace: b.Source.Namespace,
 		},
-		&corev1.ConfigMap{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      envoyConfigName,
-				Namespace: namespace,
-			},
-			Data: map[string]string{
-				"config.yaml": envoyConfig,
-			},
+		Spec: certmanagerv1alpha2.CertificateSpec{
+			SecretName: b.tlsSecretName(),
+			DNSNames:   []string{spec.DomainName},
+			IssuerRef:  spec.CertificateIssuerRef,
 		},
-		&appsv1.Deployment{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      name,
-				Namespace: namespace,
+	}
+}
+
+func (b *WebSockTunnelBuilder) buildDeployment(labels map[string]string) *appsv1.Deployment {
+	spec := &b.Source.Spec
+	maxUnavailable := intstr.FromInt(0)
+	maxSurge := intstr.FromInt(1)
+	gracePeriod := int64((b.drainPeriod() + drainGracePeriod).Seconds())
+	secretRef := corev1.LocalObjectReference{Name: webSockTunnelSecretName(b.Source)}
+
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      b.Source.Name,
+			Namespace: b.Source.Namespace,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: spec.Replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: labels,
 			},
-			Spec: appsv1.DeploymentSpec{
-				Selector: &metav1.LabelSelector{
-					MatchLabels: map[string]string{
-						"app.kubernetes.io/component": "websocktunnel",
-						"app.kubernetes.io/name":      name,
-					},
+			// Bring up new pods before draining old ones, so capacity for
+			// reconnecting clients is never reduced.
+			Strategy: appsv1.DeploymentStrategy{
+				Type: appsv1.RollingUpdateDeploymentStrategyType,
+				RollingUpdate: &appsv1.RollingUpdateDeployment{
+					MaxUnavailable: &maxUnavailable,
+					MaxSurge:       &maxSurge,
 				},
-				Template: corev1.PodTemplateSpec{
-					ObjectMeta: metav1.ObjectMeta{
-						Labels: map[string]string{
-							"app.kubernetes.io/component": "websocktunnel",
-							"app.kubernetes.io/name":      name,
-						},
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+					Annotations: map[string]string{
+						// Restart the tunnel to pick up rotated secrets.
+						secretRotatedAnnotation: b.LastRotated.Format(time.RFC3339),
 					},
-					Spec: corev1.PodSpec{
-						Containers: []corev1.Container{
-							{
-								Name:  "websocktunnel",
-								Image: fmt.Sprintf("%s:%s", image, defaultVersion),
-								Env: []corev1.EnvVar{
-									{Name: "ENV", Value: "production"},
-									{Name: "URL_PREFIX", Value: fmt.Sprintf("https://%s", spec.DomainName)},
-									{Name: "AUDIENCE", Value: "taskcluster"},
-									{
-										Name: "TASKCLUSTER_PROXY_SECRET_A",
-										ValueFrom: &corev1.EnvVarSource{
-											SecretKeyRef: &corev1.SecretKeySelector{
-												LocalObjectReference: spec.SecretRef,
-												Key:                  keySecret,
-											},
-										},
-									},
-									{
-										Name: "TASKCLUSTER_PROXY_SECRET_B",
-										ValueFrom: &corev1.EnvVarSource{
-											SecretKeyRef: &corev1.SecretKeySelector{
-												LocalObjectReference: spec.SecretRef,
-												Key:                  keySecretLast,
-											},
-										},
-									},
-								},
-								Resources: corev1.ResourceRequirements{
-									Requests: corev1.ResourceList{
-										corev1.ResourceCPU: *resource.NewMilliQuantity(10, resource.BinarySI),
-									},
-								},
-								ReadinessProbe: &corev1.Probe{
-									Handler: corev1.Handler{
-										HTTPGet: &corev1.HTTPGetAction{
-											Path:   "/__lbheartbeat__",
-											Port:   intstr.FromInt(80),
-											Scheme: corev1.URISchemeHTTP,
+				},
+				Spec: corev1.PodSpec{
+					TerminationGracePeriodSeconds: &gracePeriod,
+					Affinity:                      webSockTunnelAffinity(spec.AntiAffinity, labels),
+					Containers: []corev1.Container{
+						{
+							Name:  "websocktunnel",
+							Image: fmt.Sprintf("%s:%s", image, taskclusterv1beta1.DefaultVersion),
+							Env: []corev1.EnvVar{
+								{Name: "ENV", Value: "production"},
+								{Name: "URL_PREFIX", Value: webSockTunnelURL(b.Source)},
+								{Name: "AUDIENCE", Value: "taskcluster"},
+								{
+									Name: "TASKCLUSTER_PROXY_SECRET_A",
+									ValueFrom: &corev1.EnvVarSource{
+										SecretKeyRef: &corev1.SecretKeySelector{
+											LocalObjectReference: secretRef,
+											Key:                  keySecret,
 										},
 									},
-									InitialDelaySeconds: 3,
-									PeriodSeconds:       3,
 								},
-								LivenessProbe: &corev1.Probe{
-									Handler: corev1.Handler{
-										HTTPGet: &corev1.HTTPGetAction{
-											Path:   "/__lbheartbeat__",
-											Port:   intstr.FromInt(80),
-											Scheme: corev1.URISchemeHTTP,
+								{
+									Name: "TASKCLUSTER_PROXY_SECRET_B",
+									ValueFrom: &corev1.EnvVarSource{
+										SecretKeyRef: &corev1.SecretKeySelector{
+											LocalObjectReference: secretRef,
+											Key:                  keySecretLast,
 										},
 									},
-									InitialDelaySeconds: 30,
-									PeriodSeconds:       3,
-								},
-							},
-							{
-								Name:  "tls-terminate",
-								Image: "envoyproxy/envoy:v1.11.1",
-								Args:  []string{"-c", "/etc/envoy/config.yaml"},
-								VolumeMounts: []corev1.VolumeMount{
-									{
-										Name:      "tls",
-										MountPath: "/tls",
-										ReadOnly:  true,
-									},
-									{
-										Name:      "envoy-config",
-										MountPath: "/etc/envoy",
-										ReadOnly:  true,
-									},
-								},
-								Resources: corev1.ResourceRequirements{
-									Requests: corev1.ResourceList{
-										corev1.ResourceCPU: *resource.NewMilliQuantity(10, resource.BinarySI),
-									},
 								},
 							},
-						},
-						Volumes: []corev1.Volume{
-							{
-								Name: "tls",
-								VolumeSource: corev1.VolumeSource{
-									Secret: &corev1.SecretVolumeSource{
-										SecretName: tlsSecretName,
+							Resources: webSockTunnelResources(spec.Resources),
+							Lifecycle: b.drainLifecycle(),
+							ReadinessProbe: &corev1.Probe{
+								Handler: corev1.Handler{
+									HTTPGet: &corev1.HTTPGetAction{
+										Path:   "/__lbheartbeat__",
+										Port:   intstr.FromInt(80),
+										Scheme: corev1.URISchemeHTTP,
 									},
 								},
+								InitialDelaySeconds: 3,
+								PeriodSeconds:       3,
 							},
-							{
-								Name: "envoy-config",
-								VolumeSource: corev1.VolumeSource{
-									ConfigMap: &corev1.ConfigMapVolumeSource{
-										LocalObjectReference: corev1.LocalObjectReference{
-											Name: envoyConfigName,
-										},
+							LivenessProbe: &corev1.Probe{
+								Handler: corev1.Handler{
+									HTTPGet: &corev1.HTTPGetAction{
+										Path:   "/__lbheartbeat__",
+										Port:   intstr.FromInt(80),
+										Scheme: corev1.URISchemeHTTP,
 									},
 								},
+								InitialDelaySeconds: 30,
+								PeriodSeconds:       3,
 							},
 						},
 					},
 				},
 			},
 		},
-		&corev1.Service{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      name,
-				Namespace: namespace,
-				Labels: map[string]string{
-					"app.kubernetes.io/component": "websocktunnel",
-					"app.kubernetes.io/name":      name,
+	}
+}
+
+// addEnvoySidecar adds the TLS terminating envoy container to the pod.
+func (b *WebSockTunnelBuilder) addEnvoySidecar(pod *corev1.PodSpec) {
+	sidecarImage := envoyImage
+	if b.Source.Spec.Envoy.Image != "" {
+		sidecarImage = b.Source.Spec.Envoy.Image
+	}
+
+	pod.Containers = append(pod.Containers, corev1.Container{
+		Name:  "tls-terminate",
+		Image: sidecarImage,
+		Args:  []string{"-c", envoyConfigDir + "/" + envoyConfigFile},
+		Ports: []corev1.ContainerPort{
+			{
+				Name:          "metrics",
+				ContainerPort: envoyMetricsPort,
+				Protocol:      corev1.ProtocolTCP,
+			},
+		},
+		Env: []corev1.EnvVar{
+			// Newer envoy images drop to an unprivileged user, which
+			// cannot bind port 443.
+			{Name: "ENVOY_UID", Value: "0"},
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      "tls",
+				MountPath: envoyTLSDir,
+				ReadOnly:  true,
+			},
+			{
+				Name:      "envoy-config",
+				MountPath: envoyConfigDir,
+				ReadOnly:  true,
+			},
+		},
+		Resources: webSockTunnelResources(b.Source.Spec.Envoy.Resources),
+		Lifecycle: b.drainLifecycle(),
+	})
+	pod.Volumes = append(pod.Volumes,
+		corev1.Volume{
+			Name: "tls",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: b.tlsSecretName(),
+				},
+			},
+		},
+		corev1.Volume{
+			Name: "envoy-config",
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: b.envoyConfigName(),
+					},
 				},
-				Annotations: map[string]string{
-					"external-dns.alpha.kubernetes.io/hostname": spec.DomainName,
+			},
+		},
+	)
+}
+
+func (b *WebSockTunnelBuilder) buildService(labels map[string]string) *corev1.Service {
+	spec := &b.Source.Spec
+	serviceSpec := &spec.Service
+
+	annotations := map[string]string{}
+	for k, v := range serviceSpec.Annotations {
+		annotations[k] = v
+	}
+
+	port := corev1.ServicePort{
+		Name:       "http",
+		Protocol:   corev1.ProtocolTCP,
+		Port:       80,
+		TargetPort: intstr.FromInt(80),
+	}
+	serviceType := corev1.ServiceTypeClusterIP
+
+	if webSockTunnelExposure(b.Source) == taskclusterv1beta1.WebSockTunnelExposeLoadBalancer {
+		annotations["external-dns.alpha.kubernetes.io/hostname"] = spec.DomainName
+		port = corev1.ServicePort{
+			Name:       "https",
+			Protocol:   corev1.ProtocolTCP,
+			Port:       443,
+			TargetPort: intstr.FromInt(443),
+		}
+		serviceType = corev1.ServiceTypeLoadBalancer
+	}
+
+	if serviceSpec.Type != "" {
+		serviceType = serviceSpec.Type
+	}
+
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        b.Source.Name,
+			Namespace:   b.Source.Namespace,
+			Labels:      labels,
+			Annotations: annotations,
+		},
+		Spec: corev1.ServiceSpec{
+			Type:                     serviceType,
+			LoadBalancerIP:           serviceSpec.LoadBalancerIP,
+			LoadBalancerSourceRanges: serviceSpec.LoadBalancerSourceRanges,
+			Selector:                 labels,
+			Ports:                    []corev1.ServicePort{port},
+		},
+	}
+}
+
+func (b *WebSockTunnelBuilder) metricsServiceName() string {
+	return fmt.Sprintf("%s-metrics", b.Source.Name)
+}
+
+// buildMetricsService builds a ClusterIP Service for scraping, so metrics
+// are never exposed through the tunnel load balancer.
+func (b *WebSockTunnelBuilder) buildMetricsService(labels map[string]string) *corev1.Service {
+	serviceLabels := map[string]string{
+		metricsServiceLabel: b.Source.Name,
+	}
+	for k, v := range labels {
+		serviceLabels[k] = v
+	}
+
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      b.metricsServiceName(),
+			Namespace: b.Source.Namespace,
+			Labels:    serviceLabels,
+		},
+		Spec: corev1.ServiceSpec{
+			Type:     corev1.ServiceTypeClusterIP,
+			Selector: labels,
+			Ports: []corev1.ServicePort{
+				{
+					Name:       "metrics",
+					Protocol:   corev1.ProtocolTCP,
+					Port:       envoyMetricsPort,
+					TargetPort: intstr.FromString("metrics"),
 				},
 			},
-			Spec: corev1.ServiceSpec{
-				Type: corev1.ServiceTypeLoadBalancer,
-				Selector: map[string]string{
-					"app.kubernetes.io/component": "websocktunnel",
-					"app.kubernetes.io/name":      name,
+		},
+	}
+}
+
+// buildServiceMonitor builds a Prometheus operator ServiceMonitor. Its types
+// are not a dependency of this operator, so it is built unstructured.
+func (b *WebSockTunnelBuilder) buildServiceMonitor() *unstructured.Unstructured {
+	monitorSpec := b.Source.Spec.Metrics.ServiceMonitor
+
+	endpoint := map[string]interface{}{
+		"port": "metrics",
+		"path": envoyMetricsPath,
+	}
+	if monitorSpec.Interval != nil {
+		endpoint["interval"] = monitorSpec.Interval.Duration.String()
+	}
+
+	monitor := &unstructured.Unstructured{}
+	monitor.SetGroupVersionKind(serviceMonitorGVK)
+	monitor.SetName(b.Source.Name)
+	monitor.SetNamespace(b.Source.Namespace)
+	if len(monitorSpec.Labels) > 0 {
+		monitor.SetLabels(monitorSpec.Labels)
+	}
+	monitor.Object["spec"] = map[string]interface{}{
+		"selector": map[string]interface{}{
+			"matchLabels": map[string]interface{}{
+				metricsServiceLabel: b.Source.Name,
+			},
+		},
+		"endpoints": []interface{}{endpoint},
+	}
+
+	return monitor
+}
+
+// buildIngress builds a networking.k8s.io/v1 Ingress, which is not
+// available to this version of client-go, so it is built unstructured.
+func (b *WebSockTunnelBuilder) buildIngress() *unstructured.Unstructured {
+	spec := &b.Source.Spec
+
+	ingressSpec := map[string]interface{}{
+		"tls": []interface{}{
+			map[string]interface{}{
+				"hosts":      []interface{}{spec.DomainName},
+				"secretName": b.tlsSecretName(),
+			},
+		},
+		"rules": []interface{}{
+			map[string]interface{}{
+				"host": spec.DomainName,
+				"http": map[string]interface{}{
+					"paths": []interface{}{
+						map[string]interface{}{
+							"path":     "/",
+							"pathType": "Prefix",
+							"backend": map[string]interface{}{
+								"service": map[string]interface{}{
+									"name": b.Source.Name,
+									"port": map[string]interface{}{
+										"number": int64(80),
+									},
+								},
+							},
+						},
+					},
 				},
-				Ports: []corev1.ServicePort{
-					{
-						Name:       "https",
-						Protocol:   corev1.ProtocolTCP,
-						Port:       443,
-						TargetPort: intstr.FromInt(443),
+			},
+		},
+	}
+
+	ingress := &unstructured.Unstructured{}
+	ingress.SetGroupVersionKind(ingressGVK)
+	ingress.SetName(b.Source.Name)
+	ingress.SetNamespace(b.Source.Namespace)
+	if spec.Ingress != nil {
+		if len(spec.Ingress.Annotations) > 0 {
+			ingress.SetAnnotations(spec.Ingress.Annotations)
+		}
+		if spec.Ingress.ClassName != "" {
+			ingressSpec["ingressClassName"] = spec.Ingress.ClassName
+		}
+	}
+	ingress.Object["spec"] = ingressSpec
+
+	return ingress
+}
+
+// buildHTTPRoute builds a Gateway API HTTPRoute. The Gateway API types are
+// not available to this version of client-go, so it is built unstructured.
+func (b *WebSockTunnelBuilder) buildHTTPRoute() *unstructured.Unstructured {
+	spec := &b.Source.Spec
+	routeSpec := spec.HTTPRoute
+
+	parentRefs := make([]interface{}, 0, len(routeSpec.ParentRefs))
+	for _, ref := range routeSpec.ParentRefs {
+		parentRef := map[string]interface{}{
+			"name": ref.Name,
+		}
+		if ref.Namespace != "" {
+			parentRef["namespace"] = ref.Namespace
+		}
+		if ref.SectionName != "" {
+			parentRef["sectionName"] = ref.SectionName
+		}
+		parentRefs = append(parentRefs, parentRef)
+	}
+
+	route := &unstructured.Unstructured{}
+	route.SetGroupVersionKind(httpRouteGVK)
+	route.SetName(b.Source.Name)
+	route.SetNamespace(b.Source.Namespace)
+	if len(routeSpec.Annotations) > 0 {
+		route.SetAnnotations(routeSpec.Annotations)
+	}
+	route.Object["spec"] = map[string]interface{}{
+		"parentRefs": parentRefs,
+		"hostnames":  []interface{}{spec.DomainName},
+		"rules": []interface{}{
+			map[string]interface{}{
+				"backendRefs": []interface{}{
+					map[string]interface{}{
+						"name": b.Source.Name,
+						"port": int64(80),
 					},
 				},
 			},
 		},
 	}
 
-	return objects, nil
+	return route
+}
+
+// buildPodDisruptionBudget builds a policy/v1 PodDisruptionBudget. That
+// version is not available to this version of client-go, so it is built
+// unstructured.
+func (b *WebSockTunnelBuilder) buildPodDisruptionBudget(labels map[string]string) *unstructured.Unstructured {
+	pdbSpec := b.Source.Spec.PodDisruptionBudget
+
+	matchLabels := map[string]interface{}{}
+	for k, v := range labels {
+		matchLabels[k] = v
+	}
+
+	spec := map[string]interface{}{
+		"selector": map[string]interface{}{
+			"matchLabels": matchLabels,
+		},
+	}
+	if v := pdbSpec.MinAvailable; v != nil {
+		spec["minAvailable"] = intOrStringValue(*v)
+	}
+	if v := pdbSpec.MaxUnavailable; v != nil {
+		spec["maxUnavailable"] = intOrStringValue(*v)
+	}
+
+	pdb := &unstructured.Unstructured{}
+	pdb.SetGroupVersionKind(podDisruptionBudgetGVK)
+	pdb.SetName(b.Source.Name)
+	pdb.SetNamespace(b.Source.Namespace)
+	pdb.Object["spec"] = spec
+	return pdb
+}
+
+func (b *WebSockTunnelBuilder) drainPeriod() time.Duration {
+	if p := b.Source.Spec.DrainPeriod; p != nil {
+		return p.Duration
+	}
+
+	return defaultDrainPeriod
+}
+
+// drainLifecycle delays termination of a container so that it keeps
+// serving existing websockets while the pod is removed from endpoints.
+func (b *WebSockTunnelBuilder) drainLifecycle() *corev1.Lifecycle {
+	seconds := int64(b.drainPeriod().Seconds())
+	if seconds <= 0 {
+		return nil
+	}
+
+	return &corev1.Lifecycle{
+		PreStop: &corev1.Handler{
+			Exec: &corev1.ExecAction{
+				Command: []string{"sleep", strconv.FormatInt(seconds, 10)},
+			},
+		},
+	}
+}
+
+func intOrStringValue(v intstr.IntOrString) interface{} {
+	if v.Type == intstr.String {
+		return v.StrVal
+	}
+
+	return int64(v.IntVal)
+}
+
+// webSockTunnelResources returns the requested resources, defaulting to a
+// small CPU request.
+func webSockTunnelResources(resources *corev1.ResourceRequirements) corev1.ResourceRequirements {
+	if resources != nil {
+		return *resources
+	}
+
+	return corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU: *resource.NewMilliQuantity(10, resource.BinarySI),
+		},
+	}
+}
+
+// webSockTunnelAffinity returns pod anti-affinity spreading tunnel pods
+// across nodes.
+func webSockTunnelAffinity(mode taskclusterv1beta1.WebSockTunnelAntiAffinity, labels map[string]string) *corev1.Affinity {
+	term := corev1.PodAffinityTerm{
+		LabelSelector: &metav1.LabelSelector{
+			MatchLabels: labels,
+		},
+		TopologyKey: corev1.LabelHostname,
+	}
+
+	switch mode {
+	case taskclusterv1beta1.WebSockTunnelAntiAffinityRequired:
+		return &corev1.Affinity{
+			PodAntiAffinity: &corev1.PodAntiAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{term},
+			},
+		}
+
+	case taskclusterv1beta1.WebSockTunnelAntiAffinityPreferred:
+		return &corev1.Affinity{
+			PodAntiAffinity: &corev1.PodAntiAffinity{
+				PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{
+					{Weight: 100, PodAffinityTerm: term},
+				},
+			},
+		}
+	}
+
+	return nil
+}
+
+// webSockTunnelExposure returns how a tunnel is exposed, applying the
+// default.
+func webSockTunnelExposure(wst *taskclusterv1beta1.WebSockTunnel) taskclusterv1beta1.WebSockTunnelExposure {
+	if wst.Spec.Exposure == "" {
+		return taskclusterv1beta1.WebSockTunnelExposeLoadBalancer
+	}
+
+	return wst.Spec.Exposure
 }
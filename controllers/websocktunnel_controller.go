@@ -22,13 +22,16 @@ import (
 	"github.com/wellplayedgames/taskcluster-operator/pkg/pwgen"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"time"
 
 	"github.com/go-logr/logr"
+	certmanagerv1alpha2 "github.com/jetstack/cert-manager/pkg/apis/certmanager/v1alpha2"
 	"github.com/wellplayedgames/tiny-operator/pkg/composite"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -38,11 +41,16 @@ import (
 const (
 	resourceOwner = "taskcluster.wellplayed.games"
 
-	fieldInstanceRef = ".spec.instanceRef"
+	fieldInstanceRef            = ".spec.instanceRef"
+	fieldWebSockTunnelSecretRef = ".spec.webSockTunnelSecretRef"
+	fieldWebSockTunnelRef       = ".spec.webSockTunnelRef"
+	fieldSecretRef              = ".spec.secretRef"
 
 	keyLastRotated = "last-rotated"
 	keySecret      = "secret"
 	keySecretLast  = "secret-last"
+
+	readinessPollInterval = 30 * time.Second
 )
 
 // WebSockTunnelReconciler reconciles a WebSockTunnel object
@@ -50,35 +58,64 @@ type WebSockTunnelReconciler struct {
 	client.Client
 	Log    logr.Logger
 	Scheme *runtime.Scheme
+
+	restMapper meta.RESTMapper
 }
 
 // +kubebuilder:rbac:groups=taskcluster.wellplayed.games,resources=websocktunnels,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=taskcluster.wellplayed.games,resources=websocktunnels/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=httproutes,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=monitoring.coreos.com,resources=servicemonitors,verbs=get;list;watch;create;update;patch;delete
+
+// webSockTunnelSecretName returns the name of the Secret holding a tunnel's
+// secrets. The tunnel reads it, the operator rotates it and Instances sign
+// with it.
+func webSockTunnelSecretName(wst *taskclusterv1beta1.WebSockTunnel) string {
+	if wst.Spec.SecretRef.Name != "" {
+		return wst.Spec.SecretRef.Name
+	}
+
+	return wst.Name
+}
 
-func (r *WebSockTunnelReconciler) reconcileSecret(ctx context.Context, source *taskclusterv1beta1.WebSockTunnel) error {
+// reconcileSecret ensures the tunnel secret exists, rotating it if it is
+// due, and returns when it was last rotated.
+func (r *WebSockTunnelReconciler) reconcileSecret(ctx context.Context, source *taskclusterv1beta1.WebSockTunnel, now time.Time) (time.Time, error) {
 	create := false
 	var secret corev1.Secret
-	secret.Name = source.Name
+	secret.Name = webSockTunnelSecretName(source)
 	secret.Namespace = source.Namespace
 	secret.Data = map[string][]byte{}
 
 	name := types.NamespacedName{
 		Namespace: source.Namespace,
-		Name:      source.Name,
+		Name:      secret.Name,
 	}
 	err := r.Client.Get(ctx, name, &secret)
 	if errors.IsNotFound(err) {
 		create = true
 	} else if err != nil {
-		return err
+		return time.Time{}, err
 	}
 
 	// This has to be done afterwards as it would be wiped by the get.
 	ctrl.SetControllerReference(source, &secret, r.Scheme)
 
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+
+	lastRotated, err := time.Parse(time.RFC3339, string(secret.Data[keyLastRotated]))
+	if err != nil {
+		lastRotated = now
+		secret.Data[keyLastRotated] = []byte(now.Format(time.RFC3339))
+	}
+
 	// Check if we have both keys at all.
 	if len(secret.Data[keySecret]) == 0 || len(secret.Data[keySecretLast]) == 0 {
-		now := time.Now().UTC()
+		lastRotated = now
 		secret.Data[keyLastRotated] = []byte(now.Format(time.RFC3339))
 
 		if len(secret.Data[keySecret]) == 0 {
@@ -90,11 +127,21 @@ func (r *WebSockTunnelReconciler) reconcileSecret(ctx context.Context, source *t
 		}
 	}
 
+	// Rotate the secret, keeping the previous one valid.
+	if interval := source.Spec.RotationInterval; interval != nil && interval.Duration > 0 {
+		if !now.Before(lastRotated.Add(interval.Duration)) {
+			lastRotated = now
+			secret.Data[keyLastRotated] = []byte(now.Format(time.RFC3339))
+			secret.Data[keySecretLast] = secret.Data[keySecret]
+			secret.Data[keySecret] = []byte(pwgen.AlphaNumeric(20))
+		}
+	}
+
 	if create {
-		return r.Client.Create(ctx, &secret)
+		return lastRotated, r.Client.Create(ctx, &secret)
 	}
 
-	return r.Client.Update(ctx, &secret)
+	return lastRotated, r.Client.Update(ctx, &secret)
 }
 
 func (r *WebSockTunnelReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
@@ -106,37 +153,25 @@ func (r *WebSockTunnelReconciler) Reconcile(req ctrl.Request) (ctrl.Result, erro
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
-	now := time.Now()
+	now := time.Now().UTC().Truncate(time.Second)
 	mnow := metav1.Time{Time: now}
 
-	// Configure the Progressing status condition.
+	// Configure the Progressing and Ready status conditions.
 	progressing := taskclusterv1beta1.WebSockTunnelCondition{
 		Type:               taskclusterv1beta1.WebSockTunnelProgressing,
 		LastTransitionTime: mnow,
 		Status:             corev1.ConditionFalse,
 		Reason:             "Unknown",
 	}
+	ready := taskclusterv1beta1.WebSockTunnelCondition{
+		Type:               taskclusterv1beta1.WebSockTunnelReady,
+		LastTransitionTime: mnow,
+		Status:             corev1.ConditionFalse,
+		Reason:             "NotReconciled",
+	}
 	defer func() {
-		hasSet := false
-		for idx := range wst.Status.Conditions {
-			c := &wst.Status.Conditions[idx]
-			if c.Type == progressing.Type {
-				hasSet = true
-
-				if c.Status != progressing.Status {
-					c.LastTransitionTime = progressing.LastTransitionTime
-				}
-
-				c.Status = progressing.Status
-				c.Message = progressing.Message
-				c.Reason = progressing.Reason
-				break
-			}
-		}
-
-		if !hasSet {
-			wst.Status.Conditions = append(wst.Status.Conditions, progressing)
-		}
+		setWebSockTunnelCondition(&wst.Status, progressing)
+		setWebSockTunnelCondition(&wst.Status, ready)
 
 		err := r.Client.Status().Update(ctx, &wst)
 		if err != nil {
@@ -145,15 +180,28 @@ func (r *WebSockTunnelReconciler) Reconcile(req ctrl.Request) (ctrl.Result, erro
 	}()
 
 	// Update secret
-	if err := r.reconcileSecret(ctx, &wst); err != nil {
+	lastRotated, err := r.reconcileSecret(ctx, &wst, now)
+	if err != nil {
 		progressing.Reason = "ReconcileSecretFailed"
 		progressing.Message = err.Error()
 		return ctrl.Result{}, err
 	}
 
+	var result ctrl.Result
+	wst.Status.URL = webSockTunnelURL(&wst)
+	wst.Status.LastRotationTime = &metav1.Time{Time: lastRotated}
+	wst.Status.NextRotationTime = nil
+	if interval := wst.Spec.RotationInterval; interval != nil && interval.Duration > 0 {
+		next := lastRotated.Add(interval.Duration)
+		wst.Status.NextRotationTime = &metav1.Time{Time: next}
+		result.RequeueAfter = next.Sub(now)
+	}
+
 	builder := &WebSockTunnelBuilder{
-		Logger: logger,
-		Source: &wst,
+		Logger:          logger,
+		Source:          &wst,
+		LastRotated:     lastRotated,
+		ServiceMonitors: r.hasKind(serviceMonitorGVK),
 	}
 	objects, err := builder.Build()
 	if err != nil {
@@ -175,21 +223,101 @@ func (r *WebSockTunnelReconciler) Reconcile(req ctrl.Request) (ctrl.Result, erro
 
 	progressing.Status = corev1.ConditionTrue
 	progressing.Reason = "Reconciled"
-	return ctrl.Result{}, nil
+
+	issued, err := r.observeCertificate(ctx, &wst)
+	if err != nil {
+		ready.Reason = "CertificateUnknown"
+		ready.Message = err.Error()
+		return ctrl.Result{}, err
+	}
+
+	hasAddress, err := r.observeAddress(ctx, &wst, objects)
+	if err != nil {
+		ready.Reason = "AddressUnknown"
+		ready.Message = err.Error()
+		return ctrl.Result{}, err
+	}
+
+	// Ingress status is not watched, so poll until everything is ready.
+	if !issued || !hasAddress {
+		if result.RequeueAfter == 0 || result.RequeueAfter > readinessPollInterval {
+			result.RequeueAfter = readinessPollInterval
+		}
+	}
+
+	if !issued {
+		ready.Reason = "WaitingForCertificate"
+		ready.Message = "Waiting for the certificate to be issued"
+		return result, nil
+	}
+
+	if !hasAddress {
+		ready.Reason = "WaitingForAddress"
+		ready.Message = "Waiting for an external address to be assigned"
+		return result, nil
+	}
+
+	ready.Status = corev1.ConditionTrue
+	ready.Reason = "Ready"
+	return result, nil
+}
+
+// setWebSockTunnelCondition updates a condition in the status, only
+// changing the transition time if the condition's status changed.
+func setWebSockTunnelCondition(status *taskclusterv1beta1.WebSockTunnelStatus, condition taskclusterv1beta1.WebSockTunnelCondition) {
+	for idx := range status.Conditions {
+		c := &status.Conditions[idx]
+		if c.Type == condition.Type {
+			if c.Status != condition.Status {
+				c.LastTransitionTime = condition.LastTransitionTime
+			}
+
+			c.Status = condition.Status
+			c.Message = condition.Message
+			c.Reason = condition.Reason
+			return
+		}
+	}
+
+	status.Conditions = append(status.Conditions, condition)
+}
+
+// webSockTunnelURL returns the URL clients connect to a tunnel on.
+func webSockTunnelURL(wst *taskclusterv1beta1.WebSockTunnel) string {
+	return fmt.Sprintf("https://%s", wst.Spec.DomainName)
+}
+
+// webSockTunnelReady returns true if a tunnel's Ready condition is true.
+func webSockTunnelReady(wst *taskclusterv1beta1.WebSockTunnel) bool {
+	for _, c := range wst.Status.Conditions {
+		if c.Type == taskclusterv1beta1.WebSockTunnelReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+
+	return false
+}
+
+// hasKind returns true if the API server serves the given kind.
+func (r *WebSockTunnelReconciler) hasKind(gvk schema.GroupVersionKind) bool {
+	_, err := r.restMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	return err == nil
 }
 
 func (r *WebSockTunnelReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	ctx := context.Background()
+	r.restMapper = mgr.GetRESTMapper()
 
 	if err := mgr.GetFieldIndexer().IndexField(ctx, &taskclusterv1beta1.AccessToken{}, fieldInstanceRef, func(obj runtime.Object) []string {
 		token := obj.(*taskclusterv1beta1.AccessToken)
-		instance := fmt.Sprintf("%s/%s", token.Spec.InstanceRef.Namespace, token.Spec.InstanceRef.Name)
-		return []string{instance}
+		return []string{instanceRefName(&token.Spec.InstanceRef, token.Namespace).String()}
 	}); err != nil {
 		return err
 	}
 
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&taskclusterv1beta1.WebSockTunnel{}).
+		Owns(&certmanagerv1alpha2.Certificate{}).
+		Owns(&corev1.Service{}).
 		Complete(r)
 }
@@ -0,0 +1,294 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	taskclusterv1beta1 "github.com/wellplayedgames/taskcluster-operator/api/v1beta1"
+)
+
+func TestWebSockTunnelSecretRotation(t *testing.T) {
+	ctx := context.Background()
+	wst := &taskclusterv1beta1.WebSockTunnel{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "taskcluster", Name: "wst", UID: "wst-uid"},
+		Spec: taskclusterv1beta1.WebSockTunnelSpec{
+			DomainName:       "websocktunnel.example.com",
+			SecretRef:        corev1.LocalObjectReference{Name: "tunnel-secret"},
+			RotationInterval: &metav1.Duration{Duration: time.Hour},
+		},
+	}
+
+	c := newFakeClient(wst)
+	r := &WebSockTunnelReconciler{Client: c, Log: logf.NullLogger{}, Scheme: newTestScheme()}
+
+	readSecret := func() map[string][]byte {
+		var secret corev1.Secret
+		if err := c.Get(ctx, types.NamespacedName{Namespace: "taskcluster", Name: "tunnel-secret"}, &secret); err != nil {
+			t.Fatal(err)
+		}
+		return secret.Data
+	}
+
+	created := testNow
+	lastRotated, err := r.reconcileSecret(ctx, wst, created)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !lastRotated.Equal(created) {
+		t.Errorf("expected new secret to be rotated at %v, got %v", created, lastRotated)
+	}
+
+	initial := readSecret()
+	if len(initial[keySecret]) == 0 || len(initial[keySecretLast]) == 0 {
+		t.Fatalf("expected both secrets to be generated, got %v", initial)
+	}
+
+	var other corev1.Secret
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "taskcluster", Name: "wst"}, &other); err == nil {
+		t.Error("expected no Secret named after the WebSockTunnel")
+	}
+
+	// Not yet due.
+	lastRotated, err = r.reconcileSecret(ctx, wst, created.Add(30*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !lastRotated.Equal(created) || string(readSecret()[keySecret]) != string(initial[keySecret]) {
+		t.Error("expected the secret not to be rotated before it is due")
+	}
+
+	// Due.
+	rotated := created.Add(time.Hour)
+	lastRotated, err = r.reconcileSecret(ctx, wst, rotated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := readSecret()
+	if !lastRotated.Equal(rotated) || string(data[keyLastRotated]) != rotated.Format(time.RFC3339) {
+		t.Errorf("expected rotation at %v, got %v", rotated, lastRotated)
+	}
+	if string(data[keySecretLast]) != string(initial[keySecret]) {
+		t.Error("expected the previous secret to be kept")
+	}
+	if len(data[keySecret]) == 0 || string(data[keySecret]) == string(initial[keySecret]) {
+		t.Error("expected a new secret")
+	}
+
+	// The tunnel reads the rotated Secret.
+	objects, err := (&WebSockTunnelBuilder{Logger: logf.NullLogger{}, Source: wst, LastRotated: lastRotated}).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var deployment *appsv1.Deployment
+	for _, obj := range objects {
+		if d, ok := obj.(*appsv1.Deployment); ok {
+			deployment = d
+		}
+	}
+	if deployment == nil {
+		t.Fatal("expected a Deployment")
+	}
+
+	secretEnv := 0
+	for _, env := range deployment.Spec.Template.Spec.Containers[0].Env {
+		if env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil {
+			secretEnv++
+			if env.ValueFrom.SecretKeyRef.Name != "tunnel-secret" {
+				t.Errorf("expected %s to come from tunnel-secret, got %s", env.Name, env.ValueFrom.SecretKeyRef.Name)
+			}
+		}
+	}
+	if secretEnv != 2 {
+		t.Errorf("expected both secrets in the environment, got %d", secretEnv)
+	}
+}
+
+func TestResolveWebSockTunnel(t *testing.T) {
+	rotated := testNow.Add(-time.Hour)
+	previous := testNow.Add(-2 * time.Hour)
+
+	wst := &taskclusterv1beta1.WebSockTunnel{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "taskcluster", Name: "wst"},
+		Spec: taskclusterv1beta1.WebSockTunnelSpec{
+			SecretRef: corev1.LocalObjectReference{Name: "tunnel-secret"},
+		},
+		Status: taskclusterv1beta1.WebSockTunnelStatus{
+			URL: "https://websocktunnel.example.com",
+			Conditions: []taskclusterv1beta1.WebSockTunnelCondition{
+				{Type: taskclusterv1beta1.WebSockTunnelReady, Status: corev1.ConditionTrue},
+			},
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "taskcluster", Name: "tunnel-secret"},
+		Data: map[string][]byte{
+			keyLastRotated: []byte(rotated.Format(time.RFC3339)),
+			keySecret:      []byte("new"),
+			keySecretLast:  []byte("old"),
+		},
+	}
+
+	// tunnelDeployment returns the tunnel's Deployment, restarted for the
+	// secret rotated at the given time.
+	tunnelDeployment := func(deployed time.Time, rolledOut bool) *appsv1.Deployment {
+		d := testDeployment("websocktunnel", "", rolledOut)
+		d.Name = wst.Name
+		d.Spec.Template.Annotations = map[string]string{secretRotatedAnnotation: deployed.Format(time.RFC3339)}
+		return d
+	}
+
+	tests := []struct {
+		name       string
+		deployment *appsv1.Deployment
+		secret     string
+	}{
+		{
+			name:       "rolled out",
+			deployment: tunnelDeployment(rotated, true),
+			secret:     "new",
+		},
+		{
+			name:       "rolling out",
+			deployment: tunnelDeployment(rotated, false),
+			secret:     "old",
+		},
+		{
+			name:       "not yet restarted",
+			deployment: tunnelDeployment(previous, true),
+			secret:     "old",
+		},
+		{
+			name:   "no deployment",
+			secret: "old",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := []runtime.Object{wst.DeepCopy(), secret.DeepCopy()}
+			if tt.deployment != nil {
+				objs = append(objs, tt.deployment)
+			}
+
+			o := newTestOperations(objs...)
+			o.source.Spec.WebSockTunnelRef = &corev1.LocalObjectReference{Name: wst.Name}
+
+			ready, err := o.ResolveWebSockTunnel(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !ready {
+				t.Fatal("expected the tunnel to be ready")
+			}
+			if o.webSockTunnelSecret != tt.secret {
+				t.Errorf("expected to sign with the %s secret, got %q", tt.secret, o.webSockTunnelSecret)
+			}
+			if o.WebSockTunnelURL != wst.Status.URL {
+				t.Errorf("expected URL %s, got %s", wst.Status.URL, o.WebSockTunnelURL)
+			}
+		})
+	}
+
+	// An Instance referencing the secret directly waits for the tunnel
+	// reading it in the same way.
+	for _, tt := range tests {
+		t.Run("secret ref "+tt.name, func(t *testing.T) {
+			objs := []runtime.Object{wst.DeepCopy(), secret.DeepCopy()}
+			if tt.deployment != nil {
+				objs = append(objs, tt.deployment)
+			}
+
+			o := newTestOperations(objs...)
+			o.source.Spec.WebSockTunnelSecretRef = &corev1.LocalObjectReference{Name: secret.Name}
+
+			if ready, err := o.ResolveWebSockTunnel(context.Background()); err != nil || !ready {
+				t.Fatalf("expected the secret to be resolved, got %v", err)
+			}
+			if o.webSockTunnelSecret != tt.secret {
+				t.Errorf("expected to sign with the %s secret, got %q", tt.secret, o.webSockTunnelSecret)
+			}
+		})
+	}
+
+	t.Run("secret ref without a tunnel", func(t *testing.T) {
+		o := newTestOperations(secret.DeepCopy())
+		o.source.Spec.WebSockTunnelSecretRef = &corev1.LocalObjectReference{Name: secret.Name}
+
+		if ready, err := o.ResolveWebSockTunnel(context.Background()); err != nil || !ready {
+			t.Fatalf("expected the secret to be resolved, got %v", err)
+		}
+		if o.webSockTunnelSecret != "new" {
+			t.Errorf("expected to sign with the new secret, got %q", o.webSockTunnelSecret)
+		}
+	})
+}
+
+func TestWebSockTunnelIngress(t *testing.T) {
+	wst := &taskclusterv1beta1.WebSockTunnel{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "taskcluster", Name: "wst"},
+		Spec: taskclusterv1beta1.WebSockTunnelSpec{
+			DomainName: "websocktunnel.example.com",
+			Exposure:   taskclusterv1beta1.WebSockTunnelExposeIngress,
+			Ingress: &taskclusterv1beta1.WebSockTunnelIngressSpec{
+				ClassName:   "nginx",
+				Annotations: map[string]string{"nginx.ingress.kubernetes.io/proxy-read-timeout": "3600"},
+			},
+		},
+	}
+
+	objects, err := (&WebSockTunnelBuilder{Logger: logf.NullLogger{}, Source: wst, LastRotated: testNow}).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ingress *unstructured.Unstructured
+	for _, obj := range objects {
+		if u, ok := obj.(*unstructured.Unstructured); ok && u.GroupVersionKind() == ingressGVK {
+			ingress = u
+		}
+	}
+	if ingress == nil {
+		t.Fatal("expected a networking.k8s.io/v1 Ingress")
+	}
+
+	if className, _, _ := unstructured.NestedString(ingress.Object, "spec", "ingressClassName"); className != "nginx" {
+		t.Errorf("expected ingress class nginx, got %q", className)
+	}
+	if ingress.GetAnnotations()["nginx.ingress.kubernetes.io/proxy-read-timeout"] != "3600" {
+		t.Errorf("expected the configured annotations, got %v", ingress.GetAnnotations())
+	}
+
+	rules, _, _ := unstructured.NestedSlice(ingress.Object, "spec", "rules")
+	if len(rules) != 1 {
+		t.Fatalf("expected one rule, got %v", rules)
+	}
+	paths, _, _ := unstructured.NestedSlice(rules[0].(map[string]interface{}), "http", "paths")
+	if len(paths) != 1 {
+		t.Fatalf("expected one path, got %v", paths)
+	}
+
+	path := paths[0].(map[string]interface{})
+	if path["path"] != "/" || path["pathType"] != "Prefix" {
+		t.Errorf("expected a / Prefix path, got %v", path)
+	}
+	service, _, _ := unstructured.NestedString(path, "backend", "service", "name")
+	port, _, _ := unstructured.NestedInt64(path, "backend", "service", "port", "number")
+	if service != "wst" || port != 80 {
+		t.Errorf("expected backend wst:80, got %s:%d", service, port)
+	}
+
+	tls, _, _ := unstructured.NestedSlice(ingress.Object, "spec", "tls")
+	if len(tls) != 1 || tls[0].(map[string]interface{})["secretName"] != "wst-tls" {
+		t.Errorf("expected TLS from wst-tls, got %v", tls)
+	}
+}
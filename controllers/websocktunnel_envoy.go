@@ -0,0 +1,192 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	taskclusterv1beta1 "github.com/wellplayedgames/taskcluster-operator/api/v1beta1"
+	"github.com/wellplayedgames/taskcluster-operator/pkg/envoy"
+)
+
+const (
+	envoyImage = "envoyproxy/envoy:v1.31.2"
+
+	envoyConfigAnnotation = fieldOwner + "/envoy-config"
+
+	envoyConfigDir  = "/etc/envoy"
+	envoyConfigFile = "envoy.json"
+	envoySDSFile    = "sds.json"
+	envoyTLSDir     = "/tls"
+
+	envoyServerCert = "server_cert"
+
+	envoyAdminPort   = 9901
+	envoyMetricsPort = 9902
+	envoyMetricsPath = "/stats/prometheus"
+
+	defaultEnvoyIdleTimeout    = time.Hour
+	defaultEnvoyConnectTimeout = 120 * time.Second
+)
+
+// webSockTunnelEnvoyConfig generates the envoy bootstrap and SDS files for a
+// tunnel.
+//
+// The certificate is delivered through file based SDS with a watched
+// directory, so envoy reloads it when cert-manager renews the TLS Secret
+// without needing a restart.
+func webSockTunnelEnvoyConfig(spec *taskclusterv1beta1.WebSockTunnelEnvoySpec) (map[string]string, error) {
+	idleTimeout := defaultEnvoyIdleTimeout
+	if spec.IdleTimeout != nil {
+		idleTimeout = spec.IdleTimeout.Duration
+	}
+
+	connectTimeout := defaultEnvoyConnectTimeout
+	if spec.ConnectTimeout != nil {
+		connectTimeout = spec.ConnectTimeout.Duration
+	}
+
+	minVersion := envoy.TLSv1_2
+	if spec.TLSMinimumVersion != "" {
+		minVersion = spec.TLSMinimumVersion
+	}
+
+	bootstrap := envoy.Bootstrap{
+		Admin: &envoy.Admin{
+			AccessLog: []envoy.AccessLog{envoy.NewStdoutAccessLog()},
+			Address: envoy.Address{
+				SocketAddress: envoy.SocketAddress{Address: "127.0.0.1", PortValue: envoyAdminPort},
+			},
+		},
+		StaticResources: envoy.StaticResources{
+			Listeners: []envoy.Listener{
+				{
+					Name: "listener_0",
+					Address: envoy.Address{
+						SocketAddress: envoy.SocketAddress{Address: "0.0.0.0", PortValue: 443},
+					},
+					FilterChains: []envoy.FilterChain{
+						{
+							Filters: []envoy.Filter{
+								{
+									Name: "envoy.filters.network.tcp_proxy",
+									TypedConfig: envoy.TCPProxy{
+										Type:               envoy.TypeTCPProxy,
+										StatPrefix:         "ingress_tcp",
+										Cluster:            "service",
+										IdleTimeout:        envoy.Duration(idleTimeout),
+										MaxConnectAttempts: 100,
+										AccessLog:          []envoy.AccessLog{envoy.NewStdoutAccessLog()},
+									},
+								},
+							},
+							TransportSocket: &envoy.TransportSocket{
+								Name: "envoy.transport_sockets.tls",
+								TypedConfig: envoy.DownstreamTLSContext{
+									Type: envoy.TypeDownstreamTLSContext,
+									CommonTLSContext: envoy.CommonTLSContext{
+										TLSParams: &envoy.TLSParameters{
+											TLSMinimumProtocolVersion: minVersion,
+											CipherSuites:              spec.CipherSuites,
+										},
+										TLSCertificateSDSSecretConfigs: []envoy.SDSSecretConfig{
+											{
+												Name: envoyServerCert,
+												SDSConfig: &envoy.ConfigSource{
+													PathConfigSource: &envoy.PathConfigSource{
+														Path:             envoyConfigDir + "/" + envoySDSFile,
+														WatchedDirectory: &envoy.WatchedDirectory{Path: envoyConfigDir},
+													},
+													ResourceAPIVersion: "V3",
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+				// Only the Prometheus stats are exposed, since the admin
+				// interface can also reconfigure or stop envoy.
+				{
+					Name: "metrics",
+					Address: envoy.Address{
+						SocketAddress: envoy.SocketAddress{Address: "0.0.0.0", PortValue: envoyMetricsPort},
+					},
+					FilterChains: []envoy.FilterChain{
+						{
+							Filters: []envoy.Filter{
+								{
+									Name: "envoy.filters.network.http_connection_manager",
+									TypedConfig: envoy.HTTPConnectionManager{
+										Type:       envoy.TypeHTTPConnectionManager,
+										StatPrefix: "metrics",
+										RouteConfig: envoy.RouteConfiguration{
+											Name: "metrics",
+											VirtualHosts: []envoy.VirtualHost{
+												{
+													Name:    "metrics",
+													Domains: []string{"*"},
+													Routes: []envoy.Route{
+														{
+															Match: envoy.RouteMatch{Path: envoyMetricsPath},
+															Route: envoy.RouteAction{Cluster: "admin"},
+														},
+													},
+												},
+											},
+										},
+										HTTPFilters: []envoy.HTTPFilter{envoy.NewRouter()},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			Clusters: []envoy.Cluster{
+				envoy.NewStaticCluster("service", "127.0.0.1", 80, connectTimeout),
+				envoy.NewStaticCluster("admin", "127.0.0.1", envoyAdminPort, defaultEnvoyConnectTimeout),
+			},
+		},
+	}
+
+	sds := envoy.DiscoveryResponse{
+		Resources: []interface{}{
+			envoy.Secret{
+				Type: envoy.TypeSecret,
+				Name: envoyServerCert,
+				TLSCertificate: &envoy.TLSCertificate{
+					CertificateChain: envoy.DataSource{Filename: envoyTLSDir + "/tls.crt"},
+					PrivateKey:       envoy.DataSource{Filename: envoyTLSDir + "/tls.key"},
+					WatchedDirectory: &envoy.WatchedDirectory{Path: envoyTLSDir},
+				},
+			},
+		},
+	}
+
+	bootstrapJSON, err := json.MarshalIndent(&bootstrap, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	sdsJSON, err := json.MarshalIndent(&sds, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]string{
+		envoyConfigFile: string(bootstrapJSON),
+		envoySDSFile:    string(sdsJSON),
+	}, nil
+}
+
+// envoyConfigChecksum hashes the envoy configuration so that pods restart
+// when the bootstrap changes, which envoy does not reload itself.
+func envoyConfigChecksum(config map[string]string) string {
+	h := sha256.New()
+	h.Write([]byte(config[envoyConfigFile]))
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	taskclusterv1beta1 "github.com/wellplayedgames/taskcluster-operator/api/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ResolveWebSockTunnel fetches the secret and URL of the WebSockTunnel
+// referenced by the instance, or the secret it references directly. It
+// returns false if the tunnel is not yet ready.
+func (o *TaskClusterOperations) ResolveWebSockTunnel(ctx context.Context) (bool, error) {
+	ref := o.source.Spec.WebSockTunnelRef
+	if ref == nil {
+		return true, o.resolveWebSockTunnelSecret(ctx)
+	}
+
+	var wst taskclusterv1beta1.WebSockTunnel
+	name := types.NamespacedName{
+		Namespace: o.Namespace,
+		Name:      ref.Name,
+	}
+	if err := o.Client.Get(ctx, name, &wst); err != nil {
+		return false, err
+	}
+
+	if !webSockTunnelReady(&wst) {
+		return false, nil
+	}
+
+	var secret corev1.Secret
+	secretName := types.NamespacedName{
+		Namespace: o.Namespace,
+		Name:      webSockTunnelSecretName(&wst),
+	}
+	if err := o.Client.Get(ctx, secretName, &secret); err != nil {
+		return false, err
+	}
+
+	rolledOut, err := o.webSockTunnelRolledOut(ctx, &wst, &secret)
+	if err != nil {
+		return false, err
+	}
+
+	// Until every tunnel pod has the rotated secret, keep signing with the
+	// previous one, which old and new pods both accept.
+	key := keySecretLast
+	if rolledOut {
+		key = keySecret
+	}
+
+	o.webSockTunnelSecret = string(secret.Data[key])
+	o.WebSockTunnelURL = wst.Status.URL
+	return true, nil
+}
+
+// resolveWebSockTunnelSecret fetches the secret referenced directly by the
+// instance. If a WebSockTunnel reads that secret, the previous secret is used
+// until the tunnel has rolled out, as for a referenced WebSockTunnel.
+func (o *TaskClusterOperations) resolveWebSockTunnelSecret(ctx context.Context) error {
+	ref := o.source.Spec.WebSockTunnelSecretRef
+	if ref == nil {
+		return nil
+	}
+
+	var secret corev1.Secret
+	name := types.NamespacedName{
+		Namespace: o.Namespace,
+		Name:      ref.Name,
+	}
+	if err := o.Client.Get(ctx, name, &secret); err != nil {
+		return err
+	}
+
+	var tunnels taskclusterv1beta1.WebSockTunnelList
+	if err := o.Client.List(ctx, &tunnels, client.InNamespace(o.Namespace), client.MatchingFields{fieldSecretRef: ref.Name}); err != nil {
+		return err
+	}
+
+	o.webSockTunnelSecret = string(secret.Data[keySecret])
+	for idx := range tunnels.Items {
+		wst := &tunnels.Items[idx]
+		if webSockTunnelSecretName(wst) != ref.Name {
+			continue
+		}
+
+		rolledOut, err := o.webSockTunnelRolledOut(ctx, wst, &secret)
+		if err != nil {
+			return err
+		}
+		if !rolledOut {
+			o.webSockTunnelSecret = string(secret.Data[keySecretLast])
+			break
+		}
+	}
+
+	return nil
+}
+
+// webSockTunnelRolledOut returns true if the tunnel's pods have all been
+// restarted since its secret was last rotated.
+func (o *TaskClusterOperations) webSockTunnelRolledOut(ctx context.Context, wst *taskclusterv1beta1.WebSockTunnel, secret *corev1.Secret) (bool, error) {
+	var deployment appsv1.Deployment
+	name := types.NamespacedName{
+		Namespace: wst.Namespace,
+		Name:      wst.Name,
+	}
+	if err := o.Client.Get(ctx, name, &deployment); apierrors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	lastRotated, err := time.Parse(time.RFC3339, string(secret.Data[keyLastRotated]))
+	if err != nil {
+		return false, nil
+	}
+
+	deployed, err := time.Parse(time.RFC3339, deployment.Spec.Template.Annotations[secretRotatedAnnotation])
+	if err != nil || deployed.Before(lastRotated) {
+		return false, nil
+	}
+
+	return deploymentRolledOut(&deployment), nil
+}
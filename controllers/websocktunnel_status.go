@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"context"
+
+	certmanagerv1alpha2 "github.com/jetstack/cert-manager/pkg/apis/certmanager/v1alpha2"
+	cmmeta "github.com/jetstack/cert-manager/pkg/apis/meta/v1"
+	taskclusterv1beta1 "github.com/wellplayedgames/taskcluster-operator/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// observeCertificate records the expiry of the tunnel certificate and
+// returns true once it has been issued. Tunnels which do not manage their
+// own certificate are always considered issued.
+func (r *WebSockTunnelReconciler) observeCertificate(ctx context.Context, wst *taskclusterv1beta1.WebSockTunnel) (bool, error) {
+	wst.Status.CertificateExpiry = nil
+	if webSockTunnelExposure(wst) == taskclusterv1beta1.WebSockTunnelExposeHTTPRoute {
+		return true, nil
+	}
+
+	var cert certmanagerv1alpha2.Certificate
+	name := types.NamespacedName{
+		Namespace: wst.Namespace,
+		Name:      wst.Name,
+	}
+	if err := r.Client.Get(ctx, name, &cert); errors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	wst.Status.CertificateExpiry = cert.Status.NotAfter
+
+	for _, c := range cert.Status.Conditions {
+		if c.Type == certmanagerv1alpha2.CertificateConditionReady {
+			return c.Status == cmmeta.ConditionTrue, nil
+		}
+	}
+
+	return false, nil
+}
+
+// observeAddress records the external address of the object exposing the
+// tunnel and returns true once one is assigned, or if none is expected.
+func (r *WebSockTunnelReconciler) observeAddress(ctx context.Context, wst *taskclusterv1beta1.WebSockTunnel, objects []runtime.Object) (bool, error) {
+	wst.Status.ExternalAddress = ""
+
+	var exposed runtime.Object
+	for _, obj := range objects {
+		switch o := obj.(type) {
+		case *corev1.Service:
+			if webSockTunnelExposure(wst) == taskclusterv1beta1.WebSockTunnelExposeLoadBalancer && o.Spec.Type == corev1.ServiceTypeLoadBalancer {
+				exposed = obj
+			}
+		default:
+			if obj.GetObjectKind().GroupVersionKind().Kind == "Ingress" {
+				exposed = obj
+			}
+		}
+	}
+
+	if exposed == nil {
+		return true, nil
+	}
+
+	acc, err := meta.Accessor(exposed)
+	if err != nil {
+		return false, err
+	}
+
+	var current unstructured.Unstructured
+	current.SetGroupVersionKind(exposed.GetObjectKind().GroupVersionKind())
+	name := types.NamespacedName{
+		Namespace: acc.GetNamespace(),
+		Name:      acc.GetName(),
+	}
+	if err := r.Client.Get(ctx, name, &current); errors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	ingress, _, _ := unstructured.NestedSlice(current.Object, "status", "loadBalancer", "ingress")
+	for _, item := range ingress {
+		entry, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		for _, key := range []string{"ip", "hostname"} {
+			if address, ok := entry[key].(string); ok && address != "" {
+				wst.Status.ExternalAddress = address
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
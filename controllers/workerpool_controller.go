@@ -0,0 +1,286 @@
+/*
+Copyright 2020 Well Played Games Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/wellplayedgames/taskcluster-operator/pkg/tcclient"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	taskclusterv1beta1 "github.com/wellplayedgames/taskcluster-operator/api/v1beta1"
+)
+
+const (
+	// workerPoolResyncPeriod is how often worker pool capacity is refreshed.
+	workerPoolResyncPeriod = time.Minute
+
+	// maxWorkerPoolErrors is how many recent errors are kept in status.
+	maxWorkerPoolErrors = 5
+)
+
+// WorkerPoolReconciler reconciles a WorkerPool object
+type WorkerPoolReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+
+	// Clients returns the TaskCluster client used for an Instance when the
+	// pool has no dedicated credentials. It defaults to RootClients.
+	Clients TaskClusterClientFunc
+}
+
+// +kubebuilder:rbac:groups=taskcluster.wellplayed.games,resources=workerpools,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=taskcluster.wellplayed.games,resources=workerpools/status,verbs=get;update;patch
+
+func (r *WorkerPoolReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	logger := r.Log.WithValues("workerpool", req.NamespacedName)
+
+	var pool taskclusterv1beta1.WorkerPool
+	if err := r.Client.Get(ctx, req.NamespacedName, &pool); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if pool.DeletionTimestamp != nil {
+		if !hasFinalizer(pool.Finalizers, taskClusterFinalizer) {
+			return ctrl.Result{}, nil
+		}
+
+		if err := r.deleteWorkerPool(ctx, &pool); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		logger.Info("deleted worker pool", "workerPoolId", pool.Status.WorkerPoolID)
+		pool.Finalizers = removeFinalizer(pool.Finalizers, taskClusterFinalizer)
+		return ctrl.Result{}, r.Client.Update(ctx, &pool)
+	}
+
+	if !hasFinalizer(pool.Finalizers, taskClusterFinalizer) {
+		pool.Finalizers = append(pool.Finalizers, taskClusterFinalizer)
+		if err := r.Client.Update(ctx, &pool); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	now := time.Now()
+	ready := taskclusterv1beta1.SyncCondition{
+		Type:               taskclusterv1beta1.SyncReady,
+		LastTransitionTime: metav1.Time{Time: now},
+		Status:             corev1.ConditionFalse,
+		Reason:             "Unknown",
+	}
+	defer func() {
+		setSyncCondition(&pool.Status.Conditions, ready)
+
+		err := r.Client.Status().Update(ctx, &pool)
+		if err != nil {
+			logger.Error(err, "failed to update status")
+		}
+	}()
+
+	desired, err := workerPoolRequest(&pool.Spec)
+	if err != nil {
+		ready.Reason = "InvalidSpec"
+		ready.Message = err.Error()
+		return ctrl.Result{}, nil
+	}
+
+	tc, err := r.clientFor(ctx, &pool)
+	if err != nil {
+		ready.Reason = "InstanceUnavailable"
+		ready.Message = err.Error()
+		return ctrl.Result{}, err
+	}
+
+	// Remove the previous pool if the worker pool ID changed.
+	if previous := pool.Status.WorkerPoolID; previous != "" && previous != pool.Spec.WorkerPoolID {
+		if err := tc.DeleteWorkerPool(ctx, previous); err != nil && !tcclient.IsNotFound(err) {
+			ready.Reason = "DeleteFailed"
+			ready.Message = err.Error()
+			return ctrl.Result{}, err
+		}
+	}
+
+	remote, err := syncWorkerPool(ctx, tc, pool.Spec.WorkerPoolID, desired)
+	if err != nil {
+		ready.Reason = "SyncFailed"
+		ready.Message = err.Error()
+		return ctrl.Result{}, err
+	}
+
+	poolErrors, err := tc.WorkerPoolErrors(ctx, pool.Spec.WorkerPoolID)
+	if err != nil {
+		ready.Reason = "ListErrorsFailed"
+		ready.Message = err.Error()
+		return ctrl.Result{}, err
+	}
+
+	pool.Status.WorkerPoolID = remote.WorkerPoolID
+	pool.Status.CurrentCapacity = int32(remote.CurrentCapacity)
+	pool.Status.RequestedCapacity = int32(remote.RequestedCapacity)
+	pool.Status.RunningCapacity = int32(remote.RunningCapacity)
+	pool.Status.StoppingCapacity = int32(remote.StoppingCapacity)
+	pool.Status.RecentErrors = recentWorkerPoolErrors(poolErrors)
+	pool.Status.ObservedGeneration = &pool.Generation
+	pool.Status.LastSyncTime = &metav1.Time{Time: now}
+
+	ready.Status = corev1.ConditionTrue
+	ready.Reason = "Synced"
+	return ctrl.Result{RequeueAfter: workerPoolResyncPeriod}, nil
+}
+
+func (r *WorkerPoolReconciler) clientFor(ctx context.Context, pool *taskclusterv1beta1.WorkerPool) (*tcclient.Client, error) {
+	instanceName := instanceRefName(&pool.Spec.InstanceRef, pool.Namespace)
+	if ref := pool.Spec.CredentialsSecretRef; ref != nil {
+		secretName := types.NamespacedName{
+			Namespace: pool.Namespace,
+			Name:      ref.Name,
+		}
+		return dedicatedClient(ctx, r.Client, instanceName, secretName)
+	}
+
+	return r.Clients(ctx, instanceName)
+}
+
+// deleteWorkerPool marks a pool for deletion in TaskCluster. If the
+// Instance no longer exists there is nothing to remove.
+func (r *WorkerPoolReconciler) deleteWorkerPool(ctx context.Context, pool *taskclusterv1beta1.WorkerPool) error {
+	workerPoolID := pool.Status.WorkerPoolID
+	if workerPoolID == "" {
+		return nil
+	}
+
+	tc, err := r.clientFor(ctx, pool)
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if err := tc.DeleteWorkerPool(ctx, workerPoolID); err != nil && !tcclient.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+// workerPoolRequest builds the worker-manager definition of a pool.
+func workerPoolRequest(spec *taskclusterv1beta1.WorkerPoolSpec) (*tcclient.WorkerPoolRequest, error) {
+	var config []byte
+	switch {
+	case spec.Config != nil && spec.RawConfig != nil:
+		return nil, fmt.Errorf("only one of config and rawConfig may be set")
+
+	case spec.Config != nil:
+		var err error
+		config, err = json.Marshal(spec.Config)
+		if err != nil {
+			return nil, err
+		}
+
+	case spec.RawConfig != nil:
+		config = spec.RawConfig.Raw
+
+	default:
+		return nil, fmt.Errorf("one of config and rawConfig must be set")
+	}
+
+	return &tcclient.WorkerPoolRequest{
+		ProviderID:   spec.ProviderID,
+		Description:  spec.Description,
+		Config:       config,
+		Owner:        spec.Owner,
+		EmailOnError: spec.EmailOnError,
+	}, nil
+}
+
+// syncWorkerPool creates the pool or updates it if it differs from the
+// desired definition.
+func syncWorkerPool(ctx context.Context, tc *tcclient.Client, workerPoolID string, desired *tcclient.WorkerPoolRequest) (*tcclient.WorkerPool, error) {
+	remote, err := tc.WorkerPool(ctx, workerPoolID)
+	if tcclient.IsNotFound(err) {
+		return tc.CreateWorkerPool(ctx, workerPoolID, desired)
+	} else if err != nil {
+		return nil, err
+	}
+
+	if remote.ProviderID == desired.ProviderID &&
+		remote.Description == desired.Description &&
+		remote.Owner == desired.Owner &&
+		remote.EmailOnError == desired.EmailOnError &&
+		sameJSON(remote.Config, desired.Config) {
+		return remote, nil
+	}
+
+	return tc.UpdateWorkerPool(ctx, workerPoolID, desired)
+}
+
+// recentWorkerPoolErrors returns the latest errors, newest first.
+func recentWorkerPoolErrors(errors []tcclient.WorkerPoolError) []taskclusterv1beta1.WorkerPoolError {
+	sort.Slice(errors, func(i, j int) bool {
+		return errors[i].Reported.After(errors[j].Reported)
+	})
+
+	if len(errors) > maxWorkerPoolErrors {
+		errors = errors[:maxWorkerPoolErrors]
+	}
+
+	result := make([]taskclusterv1beta1.WorkerPoolError, 0, len(errors))
+	for _, e := range errors {
+		result = append(result, taskclusterv1beta1.WorkerPoolError{
+			Reported:    metav1.Time{Time: e.Reported},
+			Kind:        e.Kind,
+			Title:       e.Title,
+			Description: e.Description,
+		})
+	}
+
+	return result
+}
+
+// sameJSON returns true if two JSON documents are equivalent.
+func sameJSON(a, b []byte) bool {
+	var av, bv interface{}
+	if json.Unmarshal(a, &av) != nil || json.Unmarshal(b, &bv) != nil {
+		return false
+	}
+
+	return reflect.DeepEqual(av, bv)
+}
+
+func (r *WorkerPoolReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Clients == nil {
+		r.Clients = RootClients(r.Log, mgr.GetClient())
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&taskclusterv1beta1.WorkerPool{}).
+		Complete(r)
+}
@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+
+	taskclusterv1beta1 "github.com/wellplayedgames/taskcluster-operator/api/v1beta1"
+)
+
+func TestWorkerPoolRequest(t *testing.T) {
+	ratio := 0.5
+	timeout := int64(900)
+
+	tests := []struct {
+		name   string
+		config *taskclusterv1beta1.WorkerPoolConfig
+		raw    string
+
+		expected string
+		err      bool
+	}{
+		{
+			name: "typed config",
+			config: &taskclusterv1beta1.WorkerPoolConfig{
+				MinCapacity:  1,
+				MaxCapacity:  10,
+				ScalingRatio: &ratio,
+				Lifecycle:    &taskclusterv1beta1.WorkerPoolLifecycle{RegistrationTimeout: &timeout},
+				LaunchConfigs: []taskclusterv1beta1.WorkerPoolLaunchConfig{
+					{RawExtension: runtime.RawExtension{Raw: []byte(`{"machineType":"n1-standard-4"}`)}},
+				},
+			},
+			expected: `{
+				"minCapacity": 1,
+				"maxCapacity": 10,
+				"scalingRatio": 0.5,
+				"lifecycle": {"registrationTimeout": 900},
+				"launchConfigs": [{"machineType": "n1-standard-4"}]
+			}`,
+		},
+		{
+			name:     "typed config without a scaling ratio",
+			config:   &taskclusterv1beta1.WorkerPoolConfig{MaxCapacity: 2},
+			expected: `{"minCapacity": 0, "maxCapacity": 2}`,
+		},
+		{
+			name:     "raw config",
+			raw:      `{"scalingRatio": 1, "custom": true}`,
+			expected: `{"scalingRatio": 1, "custom": true}`,
+		},
+		{
+			name:   "both configs",
+			config: &taskclusterv1beta1.WorkerPoolConfig{},
+			raw:    `{}`,
+			err:    true,
+		},
+		{
+			name: "no config",
+			err:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := &taskclusterv1beta1.WorkerPoolSpec{
+				WorkerPoolID: "proj/pool",
+				ProviderID:   "gcp",
+				Description:  "test pool",
+				Owner:        "owner@example.com",
+				EmailOnError: true,
+				Config:       tt.config,
+			}
+			if tt.raw != "" {
+				spec.RawConfig = &runtime.RawExtension{Raw: []byte(tt.raw)}
+			}
+
+			req, err := workerPoolRequest(spec)
+			if tt.err {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if req.ProviderID != "gcp" || req.Description != "test pool" || req.Owner != "owner@example.com" || !req.EmailOnError {
+				t.Errorf("unexpected request %+v", req)
+			}
+
+			var actual, expected interface{}
+			if err := json.Unmarshal(req.Config, &actual); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(tt.expected), &expected); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(actual, expected) {
+				t.Errorf("expected config %v, got %s", expected, req.Config)
+			}
+		})
+	}
+}
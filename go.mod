@@ -5,6 +5,7 @@ go 1.13
 require (
 	cloud.google.com/go v0.57.0 // indirect
 	github.com/go-logr/logr v0.1.0
+	github.com/google/gofuzz v1.1.0
 	github.com/imdario/mergo v0.3.10 // indirect
 	github.com/jackc/pgx/v4 v4.8.1
 	github.com/jetstack/cert-manager v0.16.1
@@ -15,6 +16,7 @@ require (
 	github.com/streadway/amqp v1.0.0 // indirect
 	github.com/wellplayedgames/tiny-operator v0.0.0-20200908164425-0b20788cc4c0
 	golang.org/x/crypto v0.0.0-20200709230013-948cd5f35899 // indirect
+	golang.org/x/oauth2 v0.0.0-20200107190931-bf48bf16ab8d
 	golang.org/x/sys v0.0.0-20200625212154-ddb9806d33ae // indirect
 	golang.org/x/time v0.0.0-20200630173020-3af7569d3a1e // indirect
 	golang.org/x/tools v0.0.0-20200512131952-2bc93b1c0c88 // indirect
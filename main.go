@@ -18,6 +18,7 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	certmanagerv1alpha2 "github.com/jetstack/cert-manager/pkg/apis/certmanager/v1alpha2"
 	"os"
 
@@ -27,7 +28,9 @@ import (
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/conversion"
 
+	taskclusterv1 "github.com/wellplayedgames/taskcluster-operator/api/v1"
 	taskclusterv1beta1 "github.com/wellplayedgames/taskcluster-operator/api/v1beta1"
 	sqlv1beta1 "github.com/wellplayedgames/taskcluster-operator/pkg/cnrm/sql/v1beta1"
 	// +kubebuilder:scaffold:imports
@@ -44,20 +47,33 @@ func init() {
 	_ = certmanagerv1alpha2.AddToScheme(scheme)
 	_ = sqlv1beta1.AddToScheme(scheme)
 	_ = taskclusterv1beta1.AddToScheme(scheme)
+	_ = taskclusterv1.AddToScheme(scheme)
 	// +kubebuilder:scaffold:scheme
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "state" {
+		ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
+		if err := runStateCommand(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	var metricsAddr string
 	var enableLeaderElection bool
 	var chartPath string
 	var usePublicIPs bool
+	var enableWebhooks bool
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
 	flag.StringVar(&chartPath, "chart-path", os.Getenv("CHART_PATH"), "The path to the TaskCluster chart")
 	flag.BoolVar(&usePublicIPs, "use-public-ips", false, "Connect using public IPs")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", os.Getenv("ENABLE_WEBHOOKS") == "true",
+		"Serve the admission webhooks. This requires a serving certificate.")
 	flag.Parse()
 
 	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
@@ -92,6 +108,75 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "WebSockTunnel")
 		os.Exit(1)
 	}
+	if err = (&controllers.RoleReconciler{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName("controllers").WithName("Role"),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Role")
+		os.Exit(1)
+	}
+	if err = (&controllers.WorkerPoolReconciler{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName("controllers").WithName("WorkerPool"),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "WorkerPool")
+		os.Exit(1)
+	}
+	if err = (&controllers.HookReconciler{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName("controllers").WithName("Hook"),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Hook")
+		os.Exit(1)
+	}
+	if err = (&controllers.TaskClusterSecretReconciler{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName("controllers").WithName("TaskClusterSecret"),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "TaskClusterSecret")
+		os.Exit(1)
+	}
+	if err = (&controllers.AccessTokenReconciler{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName("controllers").WithName("AccessToken"),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "AccessToken")
+		os.Exit(1)
+	}
+	// Instances are stored as v1, so v1beta1 Instances can only be read
+	// through the conversion webhook. Unlike the admission webhooks it is
+	// always served, so the manager always needs a serving certificate. It is
+	// registered first so the Instance webhook builder does not register it
+	// again.
+	mgr.GetWebhookServer().Register("/convert", &conversion.Webhook{})
+	if err = mgr.Add(&controllers.StorageVersionMigrator{
+		Client:   mgr.GetClient(),
+		Log:      ctrl.Log.WithName("migrations").WithName("Instance"),
+		Kind:     taskclusterv1.GroupVersion.WithKind("Instance"),
+		Resource: "instances",
+	}); err != nil {
+		setupLog.Error(err, "unable to create storage version migrator", "resource", "instances")
+		os.Exit(1)
+	}
+	if enableWebhooks {
+		if err = (&taskclusterv1beta1.Instance{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Instance")
+			os.Exit(1)
+		}
+		if err = (&taskclusterv1beta1.WebSockTunnel{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "WebSockTunnel")
+			os.Exit(1)
+		}
+		if err = (&taskclusterv1beta1.AccessToken{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "AccessToken")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	setupLog.Info("starting manager")
@@ -0,0 +1,167 @@
+// Package dbcrypto implements the envelope format TaskCluster uses for
+// encrypted database columns, so that values can be moved between keys.
+package dbcrypto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	keySize     = 32
+	formatV0    = 0
+	chunksField = "__bufchunks_val"
+)
+
+type envelope struct {
+	KeyID   string `json:"kid"`
+	Version int    `json:"v"`
+}
+
+// ParseKey decodes a base64 encoded aes-256 key.
+func ParseKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(key) != keySize {
+		return nil, fmt.Errorf("crypto key must be %d bytes, got %d", keySize, len(key))
+	}
+
+	return key, nil
+}
+
+// KeyID returns the ID of the key an encrypted value was written with.
+func KeyID(value []byte) (string, error) {
+	var env envelope
+	if err := json.Unmarshal(value, &env); err != nil {
+		return "", err
+	}
+
+	return env.KeyID, nil
+}
+
+// Decrypt returns the plaintext of an encrypted value.
+func Decrypt(value []byte, key []byte) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(value, &fields); err != nil {
+		return nil, err
+	}
+
+	var env envelope
+	if err := json.Unmarshal(value, &env); err != nil {
+		return nil, err
+	}
+
+	if env.Version != formatV0 {
+		return nil, fmt.Errorf("unsupported encrypted value version %d", env.Version)
+	}
+
+	var numChunks int
+	if err := json.Unmarshal(fields[chunksField], &numChunks); err != nil {
+		return nil, fmt.Errorf("invalid chunk count: %w", err)
+	}
+
+	var buf bytes.Buffer
+	for idx := 0; idx < numChunks; idx++ {
+		var chunk string
+		if err := json.Unmarshal(fields[fmt.Sprintf("__buf%d_val", idx)], &chunk); err != nil {
+			return nil, fmt.Errorf("invalid chunk %d: %w", idx, err)
+		}
+
+		by, err := base64.StdEncoding.DecodeString(chunk)
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(by)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	data := buf.Bytes()
+	if len(data) < 2*aes.BlockSize || len(data)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("encrypted value has invalid length %d", len(data))
+	}
+
+	iv, ciphertext := data[:aes.BlockSize], data[aes.BlockSize:]
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext)
+
+	padding := int(plaintext[len(plaintext)-1])
+	if padding < 1 || padding > aes.BlockSize {
+		return nil, fmt.Errorf("invalid padding, wrong key?")
+	}
+
+	for _, b := range plaintext[len(plaintext)-padding:] {
+		if int(b) != padding {
+			return nil, fmt.Errorf("invalid padding, wrong key?")
+		}
+	}
+
+	return plaintext[:len(plaintext)-padding], nil
+}
+
+// Encrypt encrypts plaintext with the given key.
+func Encrypt(plaintext []byte, keyID string, key []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	padding := aes.BlockSize - len(plaintext)%aes.BlockSize
+	data := make([]byte, aes.BlockSize+len(plaintext)+padding)
+	iv := data[:aes.BlockSize]
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+
+	body := data[aes.BlockSize:]
+	copy(body, plaintext)
+	for idx := len(plaintext); idx < len(body); idx++ {
+		body[idx] = byte(padding)
+	}
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(body, body)
+
+	return json.Marshal(map[string]interface{}{
+		"kid":        keyID,
+		"v":          formatV0,
+		chunksField:  1,
+		"__buf0_val": base64.StdEncoding.EncodeToString(data),
+	})
+}
+
+// Reencrypt decrypts value with whichever of keys it was written with and
+// encrypts it again with the key identified by keyID.
+func Reencrypt(value []byte, keys map[string][]byte, keyID string) ([]byte, error) {
+	oldKeyID, err := KeyID(value)
+	if err != nil {
+		return nil, err
+	}
+
+	oldKey, ok := keys[oldKeyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown crypto key %q", oldKeyID)
+	}
+
+	newKey, ok := keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown crypto key %q", keyID)
+	}
+
+	plaintext, err := Decrypt(value, oldKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return Encrypt(plaintext, keyID, newKey)
+}
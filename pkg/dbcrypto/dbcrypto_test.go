@@ -0,0 +1,89 @@
+package dbcrypto
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var (
+	keyA = []byte("0123456789abcdefghijklmnopqrstuv")
+	keyB = []byte("ABCDEFGHIJKLMNOPQRSTUVWXYZ012345")
+)
+
+var _ = Describe("ParseKey", func() {
+	It("should accept 32 byte keys", func() {
+		key, err := ParseKey(base64.StdEncoding.EncodeToString(keyA))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(key).To(Equal(keyA))
+	})
+
+	It("should reject short keys", func() {
+		_, err := ParseKey(base64.StdEncoding.EncodeToString([]byte("short")))
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Encrypt", func() {
+	It("should round-trip through Decrypt", func() {
+		for _, plaintext := range []string{"", "x", "exactly16bytes!!", `{"secret":"value"}`} {
+			value, err := Encrypt([]byte(plaintext), "a", keyA)
+			Expect(err).NotTo(HaveOccurred())
+
+			kid, err := KeyID(value)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(kid).To(Equal("a"))
+
+			out, err := Decrypt(value, keyA)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(out)).To(Equal(plaintext))
+		}
+	})
+
+	It("should decrypt values split into several chunks", func() {
+		value, err := Encrypt([]byte("some chunked plaintext value"), "a", keyA)
+		Expect(err).NotTo(HaveOccurred())
+
+		var fields map[string]interface{}
+		Expect(json.Unmarshal(value, &fields)).To(Succeed())
+		data, err := base64.StdEncoding.DecodeString(fields["__buf0_val"].(string))
+		Expect(err).NotTo(HaveOccurred())
+
+		chunked := []byte(`{"kid":"a","v":0,"__bufchunks_val":2,"__buf0_val":"` +
+			base64.StdEncoding.EncodeToString(data[:20]) + `","__buf1_val":"` +
+			base64.StdEncoding.EncodeToString(data[20:]) + `"}`)
+		out, err := Decrypt(chunked, keyA)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(out)).To(Equal("some chunked plaintext value"))
+	})
+})
+
+var _ = Describe("Reencrypt", func() {
+	It("should move a value to the new key", func() {
+		keys := map[string][]byte{"a": keyA, "b": keyB}
+
+		value, err := Encrypt([]byte("hello"), "a", keyA)
+		Expect(err).NotTo(HaveOccurred())
+
+		value, err = Reencrypt(value, keys, "b")
+		Expect(err).NotTo(HaveOccurred())
+
+		kid, err := KeyID(value)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kid).To(Equal("b"))
+
+		out, err := Decrypt(value, keyB)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(out)).To(Equal("hello"))
+	})
+
+	It("should fail for unknown keys", func() {
+		value, err := Encrypt([]byte("hello"), "c", keyA)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = Reencrypt(value, map[string][]byte{"a": keyA}, "a")
+		Expect(err).To(HaveOccurred())
+	})
+})
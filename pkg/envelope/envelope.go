@@ -0,0 +1,130 @@
+// Package envelope implements envelope encryption, where data is encrypted
+// with a random data key which is itself encrypted by an external key
+// provider.
+package envelope
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	dataKeySize    = 32
+	currentVersion = 1
+)
+
+// KeyProvider wraps and unwraps data keys.
+type KeyProvider interface {
+	// Name identifies the provider in sealed data.
+	Name() string
+	// WrapKey encrypts a data key.
+	WrapKey(ctx context.Context, key []byte) ([]byte, error)
+	// UnwrapKey decrypts a data key previously returned by WrapKey.
+	UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error)
+}
+
+type sealed struct {
+	Version    int    `json:"envelopeVersion"`
+	Provider   string `json:"provider"`
+	WrappedKey []byte `json:"wrappedKey"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// IsSealed returns true if data was produced by Seal.
+func IsSealed(data []byte) bool {
+	var s struct {
+		Version int `json:"envelopeVersion"`
+	}
+
+	return json.Unmarshal(data, &s) == nil && s.Version > 0
+}
+
+// Seal encrypts plaintext with a new data key wrapped by provider.
+func Seal(ctx context.Context, provider KeyProvider, plaintext []byte) ([]byte, error) {
+	key := make([]byte, dataKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+
+	nonce, ciphertext, err := encrypt(key, plaintext)
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped, err := provider.WrapKey(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("error wrapping data key: %w", err)
+	}
+
+	return json.Marshal(&sealed{
+		Version:    currentVersion,
+		Provider:   provider.Name(),
+		WrappedKey: wrapped,
+		Nonce:      nonce,
+		Ciphertext: ciphertext,
+	})
+}
+
+// Open decrypts data produced by Seal.
+func Open(ctx context.Context, provider KeyProvider, data []byte) ([]byte, error) {
+	var s sealed
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+
+	if s.Version != currentVersion {
+		return nil, fmt.Errorf("unsupported envelope version %d", s.Version)
+	}
+
+	if s.Provider != provider.Name() {
+		return nil, fmt.Errorf("data was sealed by %s, not %s", s.Provider, provider.Name())
+	}
+
+	key, err := provider.UnwrapKey(ctx, s.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("error unwrapping data key: %w", err)
+	}
+
+	return decrypt(key, s.Nonce, s.Ciphertext)
+}
+
+func encrypt(key, plaintext []byte) ([]byte, []byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, err
+	}
+
+	return nonce, aead.Seal(nil, nonce, plaintext, nil), nil
+}
+
+func decrypt(key, nonce, ciphertext []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce length %d", len(nonce))
+	}
+
+	return aead.Open(nil, nonce, ciphertext, nil)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,147 @@
+package envelope
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var localKey = []byte("0123456789abcdefghijklmnopqrstuv")
+
+var _ = Describe("Seal", func() {
+	ctx := context.Background()
+
+	It("should round-trip through Open", func() {
+		provider, err := NewLocalKeyProvider(localKey)
+		Expect(err).NotTo(HaveOccurred())
+
+		data, err := Seal(ctx, provider, []byte(`{"sessionSecret":"abc"}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(IsSealed(data)).To(BeTrue())
+		Expect(string(data)).NotTo(ContainSubstring("sessionSecret"))
+
+		plaintext, err := Open(ctx, provider, data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(plaintext)).To(Equal(`{"sessionSecret":"abc"}`))
+	})
+
+	It("should fail to open with a different key", func() {
+		provider, _ := NewLocalKeyProvider(localKey)
+		other, _ := NewLocalKeyProvider([]byte("ABCDEFGHIJKLMNOPQRSTUVWXYZ012345"))
+
+		data, err := Seal(ctx, provider, []byte("secret"))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = Open(ctx, other, data)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should fail to open data sealed by another provider", func() {
+		provider, _ := NewLocalKeyProvider(localKey)
+		data, err := Seal(ctx, provider, []byte("secret"))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = Open(ctx, &VaultTransitProvider{}, data)
+		Expect(err).To(MatchError(ContainSubstring("sealed by local")))
+	})
+})
+
+var _ = Describe("IsSealed", func() {
+	It("should not match plaintext state", func() {
+		Expect(IsSealed([]byte(`{"serviceAccounts":{}}`))).To(BeFalse())
+		Expect(IsSealed([]byte(`not json`))).To(BeFalse())
+	})
+})
+
+var _ = Describe("VaultTransitProvider", func() {
+	It("should wrap keys with the transit API", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.Header.Get("X-Vault-Token")).To(Equal("token"))
+
+			var req map[string]string
+			Expect(json.NewDecoder(r.Body).Decode(&req)).To(Succeed())
+
+			switch r.URL.Path {
+			case "/v1/transit/encrypt/state":
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"data": map[string]string{"ciphertext": "vault:v1:" + req["plaintext"]},
+				})
+			case "/v1/transit/decrypt/state":
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"data": map[string]string{"plaintext": strings.TrimPrefix(req["ciphertext"], "vault:v1:")},
+				})
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		defer server.Close()
+
+		provider := &VaultTransitProvider{
+			Address: server.URL,
+			Token:   "token",
+			KeyName: "state",
+		}
+
+		data, err := Seal(context.Background(), provider, []byte("secret"))
+		Expect(err).NotTo(HaveOccurred())
+
+		plaintext, err := Open(context.Background(), provider, data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(plaintext)).To(Equal("secret"))
+	})
+
+	It("should report API errors", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+		}))
+		defer server.Close()
+
+		provider := &VaultTransitProvider{Address: server.URL, KeyName: "state"}
+		_, err := Seal(context.Background(), provider, []byte("secret"))
+		Expect(err).To(MatchError(ContainSubstring("permission denied")))
+	})
+})
+
+var _ = Describe("GCPKMSProvider", func() {
+	It("should wrap keys with the KMS API", func() {
+		keyName := "projects/p/locations/global/keyRings/r/cryptoKeys/k"
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var req map[string]string
+			Expect(json.NewDecoder(r.Body).Decode(&req)).To(Succeed())
+
+			switch r.URL.Path {
+			case "/v1/" + keyName + ":encrypt":
+				raw, _ := base64.StdEncoding.DecodeString(req["plaintext"])
+				json.NewEncoder(w).Encode(map[string]string{
+					"ciphertext": base64.StdEncoding.EncodeToString(append([]byte("kms:"), raw...)),
+				})
+			case "/v1/" + keyName + ":decrypt":
+				raw, _ := base64.StdEncoding.DecodeString(req["ciphertext"])
+				json.NewEncoder(w).Encode(map[string]string{
+					"plaintext": base64.StdEncoding.EncodeToString(raw[len("kms:"):]),
+				})
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		defer server.Close()
+
+		provider := &GCPKMSProvider{
+			KeyName:  keyName,
+			Endpoint: server.URL,
+		}
+
+		data, err := Seal(context.Background(), provider, []byte("secret"))
+		Expect(err).NotTo(HaveOccurred())
+
+		plaintext, err := Open(context.Background(), provider, data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(plaintext)).To(Equal("secret"))
+	})
+})
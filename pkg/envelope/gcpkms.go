@@ -0,0 +1,95 @@
+package envelope
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	gcpKMSEndpoint = "https://cloudkms.googleapis.com"
+	gcpKMSScope    = "https://www.googleapis.com/auth/cloudkms"
+)
+
+// GCPKMSProvider wraps data keys with a Google Cloud KMS key.
+type GCPKMSProvider struct {
+	// KeyName is the resource name of the key, of the form
+	// projects/*/locations/*/keyRings/*/cryptoKeys/*.
+	KeyName string
+
+	// Endpoint overrides the KMS API endpoint.
+	Endpoint   string
+	HTTPClient *http.Client
+}
+
+var _ KeyProvider = (*GCPKMSProvider)(nil)
+
+// NewGCPKMSProvider creates a GCPKMSProvider using the application default
+// credentials.
+func NewGCPKMSProvider(ctx context.Context, keyName string) (*GCPKMSProvider, error) {
+	client, err := google.DefaultClient(ctx, gcpKMSScope)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GCPKMSProvider{
+		KeyName:    keyName,
+		HTTPClient: client,
+	}, nil
+}
+
+// Name implements KeyProvider
+func (p *GCPKMSProvider) Name() string {
+	return "gcp-kms"
+}
+
+// WrapKey implements KeyProvider
+func (p *GCPKMSProvider) WrapKey(ctx context.Context, key []byte) ([]byte, error) {
+	var resp struct {
+		Ciphertext string `json:"ciphertext"`
+	}
+
+	req := map[string]string{
+		"plaintext": base64.StdEncoding.EncodeToString(key),
+	}
+	if err := p.call(ctx, "encrypt", req, &resp); err != nil {
+		return nil, err
+	}
+
+	return base64.StdEncoding.DecodeString(resp.Ciphertext)
+}
+
+// UnwrapKey implements KeyProvider
+func (p *GCPKMSProvider) UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	var resp struct {
+		Plaintext string `json:"plaintext"`
+	}
+
+	req := map[string]string{
+		"ciphertext": base64.StdEncoding.EncodeToString(wrapped),
+	}
+	if err := p.call(ctx, "decrypt", req, &resp); err != nil {
+		return nil, err
+	}
+
+	return base64.StdEncoding.DecodeString(resp.Plaintext)
+}
+
+func (p *GCPKMSProvider) call(ctx context.Context, op string, body, out interface{}) error {
+	endpoint := p.Endpoint
+	if endpoint == "" {
+		endpoint = gcpKMSEndpoint
+	}
+
+	url := fmt.Sprintf("%s/v1/%s:%s", strings.TrimSuffix(endpoint, "/"), p.KeyName, op)
+	req, err := newJSONRequest(ctx, url, body)
+	if err != nil {
+		return err
+	}
+
+	return doJSON(p.HTTPClient, req, out)
+}
@@ -0,0 +1,53 @@
+package envelope
+
+import (
+	"context"
+	"fmt"
+)
+
+// LocalKeyProvider wraps data keys with a key held in memory, such as one
+// loaded from a Kubernetes Secret.
+type LocalKeyProvider struct {
+	Key []byte
+}
+
+var _ KeyProvider = (*LocalKeyProvider)(nil)
+
+// NewLocalKeyProvider creates a LocalKeyProvider from a 32 byte key.
+func NewLocalKeyProvider(key []byte) (*LocalKeyProvider, error) {
+	if len(key) != dataKeySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", dataKeySize, len(key))
+	}
+
+	return &LocalKeyProvider{Key: key}, nil
+}
+
+// Name implements KeyProvider
+func (p *LocalKeyProvider) Name() string {
+	return "local"
+}
+
+// WrapKey implements KeyProvider
+func (p *LocalKeyProvider) WrapKey(ctx context.Context, key []byte) ([]byte, error) {
+	nonce, ciphertext, err := encrypt(p.Key, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(nonce, ciphertext...), nil
+}
+
+// UnwrapKey implements KeyProvider
+func (p *LocalKeyProvider) UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	aead, err := newAEAD(p.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	n := aead.NonceSize()
+	if len(wrapped) < n {
+		return nil, fmt.Errorf("wrapped key too short")
+	}
+
+	return aead.Open(nil, wrapped[:n], wrapped[n:], nil)
+}
@@ -0,0 +1,119 @@
+package envelope
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// VaultTransitProvider wraps data keys with a HashiCorp Vault transit key.
+type VaultTransitProvider struct {
+	Address string
+	Token   string
+	Mount   string
+	KeyName string
+
+	HTTPClient *http.Client
+}
+
+var _ KeyProvider = (*VaultTransitProvider)(nil)
+
+// Name implements KeyProvider
+func (p *VaultTransitProvider) Name() string {
+	return "vault-transit"
+}
+
+// WrapKey implements KeyProvider
+func (p *VaultTransitProvider) WrapKey(ctx context.Context, key []byte) ([]byte, error) {
+	var resp struct {
+		Data struct {
+			Ciphertext string `json:"ciphertext"`
+		} `json:"data"`
+	}
+
+	req := map[string]string{
+		"plaintext": base64.StdEncoding.EncodeToString(key),
+	}
+	if err := p.call(ctx, "encrypt", req, &resp); err != nil {
+		return nil, err
+	}
+
+	return []byte(resp.Data.Ciphertext), nil
+}
+
+// UnwrapKey implements KeyProvider
+func (p *VaultTransitProvider) UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	var resp struct {
+		Data struct {
+			Plaintext string `json:"plaintext"`
+		} `json:"data"`
+	}
+
+	req := map[string]string{
+		"ciphertext": string(wrapped),
+	}
+	if err := p.call(ctx, "decrypt", req, &resp); err != nil {
+		return nil, err
+	}
+
+	return base64.StdEncoding.DecodeString(resp.Data.Plaintext)
+}
+
+func (p *VaultTransitProvider) call(ctx context.Context, op string, body, out interface{}) error {
+	mount := p.Mount
+	if mount == "" {
+		mount = "transit"
+	}
+
+	url := fmt.Sprintf("%s/v1/%s/%s/%s", strings.TrimSuffix(p.Address, "/"), mount, op, p.KeyName)
+	req, err := newJSONRequest(ctx, url, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", p.Token)
+
+	return doJSON(p.HTTPClient, req, out)
+}
+
+func newJSONRequest(ctx context.Context, url string, body interface{}) (*http.Request, error) {
+	by, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(by))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	return req.WithContext(ctx), nil
+}
+
+func doJSON(client *http.Client, req *http.Request, out interface{}) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	by, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s: %s: %s", req.Method, req.URL, resp.Status, strings.TrimSpace(string(by)))
+	}
+
+	return json.Unmarshal(by, out)
+}
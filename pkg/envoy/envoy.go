@@ -0,0 +1,298 @@
+// Package envoy contains the subset of the envoy v3 API needed to write
+// static bootstrap and SDS files. Values marshal to the JSON representation
+// envoy accepts for its configuration files.
+package envoy
+
+import (
+	"strconv"
+	"time"
+)
+
+// Type URLs of the typed configs used by this package.
+const (
+	TypeTCPProxy              = "type.googleapis.com/envoy.extensions.filters.network.tcp_proxy.v3.TcpProxy"
+	TypeDownstreamTLSContext  = "type.googleapis.com/envoy.extensions.transport_sockets.tls.v3.DownstreamTlsContext"
+	TypeStdoutAccessLog       = "type.googleapis.com/envoy.extensions.access_loggers.stream.v3.StdoutAccessLog"
+	TypeSecret                = "type.googleapis.com/envoy.extensions.transport_sockets.tls.v3.Secret"
+	TypeHTTPConnectionManager = "type.googleapis.com/envoy.extensions.filters.network.http_connection_manager.v3.HttpConnectionManager"
+	TypeRouter                = "type.googleapis.com/envoy.extensions.filters.http.router.v3.Router"
+)
+
+// TLS protocol versions understood by TLSParameters.
+const (
+	TLSv1_2 = "TLSv1_2"
+	TLSv1_3 = "TLSv1_3"
+)
+
+// Duration formats a duration the way protobuf JSON encodes it.
+func Duration(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "s"
+}
+
+// Bootstrap is the root of an envoy configuration file.
+type Bootstrap struct {
+	Admin           *Admin          `json:"admin,omitempty"`
+	StaticResources StaticResources `json:"static_resources"`
+}
+
+// Admin configures the admin interface.
+type Admin struct {
+	AccessLog []AccessLog `json:"access_log,omitempty"`
+	Address   Address     `json:"address"`
+}
+
+// StaticResources contains resources which are not discovered.
+type StaticResources struct {
+	Listeners []Listener `json:"listeners,omitempty"`
+	Clusters  []Cluster  `json:"clusters,omitempty"`
+}
+
+// Address is a network address.
+type Address struct {
+	SocketAddress SocketAddress `json:"socket_address"`
+}
+
+// SocketAddress is an IP address and port.
+type SocketAddress struct {
+	Address   string `json:"address"`
+	PortValue int    `json:"port_value"`
+}
+
+// Listener accepts downstream connections.
+type Listener struct {
+	Name         string        `json:"name"`
+	Address      Address       `json:"address"`
+	FilterChains []FilterChain `json:"filter_chains"`
+}
+
+// FilterChain is a set of network filters and the transport socket they
+// run over.
+type FilterChain struct {
+	Filters         []Filter         `json:"filters"`
+	TransportSocket *TransportSocket `json:"transport_socket,omitempty"`
+}
+
+// Filter is a network filter.
+type Filter struct {
+	Name        string      `json:"name"`
+	TypedConfig interface{} `json:"typed_config"`
+}
+
+// TransportSocket configures how bytes are carried, such as over TLS.
+type TransportSocket struct {
+	Name        string      `json:"name"`
+	TypedConfig interface{} `json:"typed_config"`
+}
+
+// AccessLog configures an access logger.
+type AccessLog struct {
+	Name        string      `json:"name"`
+	TypedConfig interface{} `json:"typed_config"`
+}
+
+// StdoutAccessLog writes access logs to stdout.
+type StdoutAccessLog struct {
+	Type string `json:"@type"`
+}
+
+// NewStdoutAccessLog returns an access logger which writes to stdout.
+func NewStdoutAccessLog() AccessLog {
+	return AccessLog{
+		Name:        "envoy.access_loggers.stdout",
+		TypedConfig: StdoutAccessLog{Type: TypeStdoutAccessLog},
+	}
+}
+
+// TCPProxy proxies connections to a cluster.
+type TCPProxy struct {
+	Type               string      `json:"@type"`
+	StatPrefix         string      `json:"stat_prefix"`
+	Cluster            string      `json:"cluster"`
+	IdleTimeout        string      `json:"idle_timeout,omitempty"`
+	MaxConnectAttempts int         `json:"max_connect_attempts,omitempty"`
+	AccessLog          []AccessLog `json:"access_log,omitempty"`
+}
+
+// HTTPConnectionManager routes HTTP requests.
+type HTTPConnectionManager struct {
+	Type        string             `json:"@type"`
+	StatPrefix  string             `json:"stat_prefix"`
+	RouteConfig RouteConfiguration `json:"route_config"`
+	HTTPFilters []HTTPFilter       `json:"http_filters"`
+}
+
+// RouteConfiguration is a static set of virtual hosts.
+type RouteConfiguration struct {
+	Name         string        `json:"name"`
+	VirtualHosts []VirtualHost `json:"virtual_hosts"`
+}
+
+// VirtualHost matches requests by domain.
+type VirtualHost struct {
+	Name    string   `json:"name"`
+	Domains []string `json:"domains"`
+	Routes  []Route  `json:"routes"`
+}
+
+// Route sends matching requests to a cluster.
+type Route struct {
+	Match RouteMatch  `json:"match"`
+	Route RouteAction `json:"route"`
+}
+
+// RouteMatch matches requests by path.
+type RouteMatch struct {
+	Path   string `json:"path,omitempty"`
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// RouteAction is where a matching request is sent.
+type RouteAction struct {
+	Cluster string `json:"cluster"`
+}
+
+// HTTPFilter is an HTTP filter.
+type HTTPFilter struct {
+	Name        string      `json:"name"`
+	TypedConfig interface{} `json:"typed_config"`
+}
+
+// Router is the terminal HTTP filter which forwards requests upstream.
+type Router struct {
+	Type string `json:"@type"`
+}
+
+// NewRouter returns the router HTTP filter.
+func NewRouter() HTTPFilter {
+	return HTTPFilter{
+		Name:        "envoy.filters.http.router",
+		TypedConfig: Router{Type: TypeRouter},
+	}
+}
+
+// DownstreamTLSContext terminates TLS from clients.
+type DownstreamTLSContext struct {
+	Type             string           `json:"@type"`
+	CommonTLSContext CommonTLSContext `json:"common_tls_context"`
+}
+
+// CommonTLSContext holds the certificates and parameters of a TLS context.
+type CommonTLSContext struct {
+	TLSParams                      *TLSParameters    `json:"tls_params,omitempty"`
+	TLSCertificateSDSSecretConfigs []SDSSecretConfig `json:"tls_certificate_sds_secret_configs,omitempty"`
+}
+
+// TLSParameters restricts TLS versions and ciphers.
+type TLSParameters struct {
+	TLSMinimumProtocolVersion string   `json:"tls_minimum_protocol_version,omitempty"`
+	TLSMaximumProtocolVersion string   `json:"tls_maximum_protocol_version,omitempty"`
+	CipherSuites              []string `json:"cipher_suites,omitempty"`
+}
+
+// SDSSecretConfig refers to a secret delivered by SDS.
+type SDSSecretConfig struct {
+	Name      string        `json:"name"`
+	SDSConfig *ConfigSource `json:"sds_config,omitempty"`
+}
+
+// ConfigSource locates dynamically discovered resources.
+type ConfigSource struct {
+	PathConfigSource   *PathConfigSource `json:"path_config_source,omitempty"`
+	ResourceAPIVersion string            `json:"resource_api_version,omitempty"`
+}
+
+// PathConfigSource reads resources from a file, reloading it when it is
+// replaced.
+type PathConfigSource struct {
+	Path             string            `json:"path"`
+	WatchedDirectory *WatchedDirectory `json:"watched_directory,omitempty"`
+}
+
+// WatchedDirectory is a directory watched for moves, which is how
+// Kubernetes atomically updates mounted ConfigMaps and Secrets.
+type WatchedDirectory struct {
+	Path string `json:"path"`
+}
+
+// Secret is an SDS secret.
+type Secret struct {
+	Type           string          `json:"@type"`
+	Name           string          `json:"name"`
+	TLSCertificate *TLSCertificate `json:"tls_certificate,omitempty"`
+}
+
+// TLSCertificate is a certificate chain and private key.
+type TLSCertificate struct {
+	CertificateChain DataSource        `json:"certificate_chain"`
+	PrivateKey       DataSource        `json:"private_key"`
+	WatchedDirectory *WatchedDirectory `json:"watched_directory,omitempty"`
+}
+
+// DataSource is a local file.
+type DataSource struct {
+	Filename string `json:"filename"`
+}
+
+// DiscoveryResponse is the contents of a file read by a PathConfigSource.
+type DiscoveryResponse struct {
+	Resources []interface{} `json:"resources"`
+}
+
+// Cluster is an upstream cluster.
+type Cluster struct {
+	Name           string         `json:"name"`
+	ConnectTimeout string         `json:"connect_timeout,omitempty"`
+	Type           string         `json:"type"`
+	LBPolicy       string         `json:"lb_policy,omitempty"`
+	LoadAssignment LoadAssignment `json:"load_assignment"`
+}
+
+// LoadAssignment lists the endpoints of a cluster.
+type LoadAssignment struct {
+	ClusterName string             `json:"cluster_name"`
+	Endpoints   []LocalityEndpoint `json:"endpoints"`
+}
+
+// LocalityEndpoint is a group of endpoints.
+type LocalityEndpoint struct {
+	LBEndpoints []LBEndpoint `json:"lb_endpoints"`
+}
+
+// LBEndpoint is a single upstream endpoint.
+type LBEndpoint struct {
+	Endpoint Endpoint `json:"endpoint"`
+}
+
+// Endpoint is the address of an upstream host.
+type Endpoint struct {
+	Address Address `json:"address"`
+}
+
+// NewStaticCluster returns a cluster with a single static endpoint.
+func NewStaticCluster(name, address string, port int, connectTimeout time.Duration) Cluster {
+	return Cluster{
+		Name:           name,
+		ConnectTimeout: Duration(connectTimeout),
+		Type:           "STATIC",
+		LBPolicy:       "ROUND_ROBIN",
+		LoadAssignment: LoadAssignment{
+			ClusterName: name,
+			Endpoints: []LocalityEndpoint{
+				{
+					LBEndpoints: []LBEndpoint{
+						{
+							Endpoint: Endpoint{
+								Address: Address{
+									SocketAddress: SocketAddress{
+										Address:   address,
+										PortValue: port,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
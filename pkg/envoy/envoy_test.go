@@ -0,0 +1,68 @@
+package envoy
+
+import (
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Duration", func() {
+	It("should format whole seconds", func() {
+		Expect(Duration(2 * time.Minute)).To(Equal("120s"))
+	})
+
+	It("should format fractional seconds", func() {
+		Expect(Duration(1500 * time.Millisecond)).To(Equal("1.5s"))
+	})
+})
+
+var _ = Describe("Bootstrap", func() {
+	It("should marshal typed configs with their type URL", func() {
+		filter := Filter{
+			Name: "envoy.filters.network.tcp_proxy",
+			TypedConfig: TCPProxy{
+				Type:        TypeTCPProxy,
+				StatPrefix:  "ingress_tcp",
+				Cluster:     "service",
+				IdleTimeout: Duration(time.Hour),
+			},
+		}
+
+		data, err := json.Marshal(filter)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(MatchJSON(`{
+			"name": "envoy.filters.network.tcp_proxy",
+			"typed_config": {
+				"@type": "type.googleapis.com/envoy.extensions.filters.network.tcp_proxy.v3.TcpProxy",
+				"stat_prefix": "ingress_tcp",
+				"cluster": "service",
+				"idle_timeout": "3600s"
+			}
+		}`))
+	})
+
+	It("should marshal a static cluster", func() {
+		data, err := json.Marshal(NewStaticCluster("service", "127.0.0.1", 80, 5*time.Second))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(MatchJSON(`{
+			"name": "service",
+			"connect_timeout": "5s",
+			"type": "STATIC",
+			"lb_policy": "ROUND_ROBIN",
+			"load_assignment": {
+				"cluster_name": "service",
+				"endpoints": [{
+					"lb_endpoints": [{
+						"endpoint": {
+							"address": {
+								"socket_address": {"address": "127.0.0.1", "port_value": 80}
+							}
+						}
+					}]
+				}]
+			}
+		}`))
+	})
+})
@@ -0,0 +1,142 @@
+package tcclient
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const authService = "auth"
+
+// Role is a TaskCluster role.
+type Role struct {
+	RoleID         string    `json:"roleId"`
+	Description    string    `json:"description"`
+	Scopes         []string  `json:"scopes"`
+	ExpandedScopes []string  `json:"expandedScopes,omitempty"`
+	Created        time.Time `json:"created,omitempty"`
+	LastModified   time.Time `json:"lastModified,omitempty"`
+}
+
+// RoleRequest is the body used to create or update a role.
+type RoleRequest struct {
+	Description string   `json:"description"`
+	Scopes      []string `json:"scopes"`
+}
+
+func rolePath(roleID string) string {
+	return "/roles/" + url.PathEscape(roleID)
+}
+
+// Role fetches a role.
+func (c *Client) Role(ctx context.Context, roleID string) (*Role, error) {
+	var role Role
+	if err := c.Request(ctx, authService, http.MethodGet, rolePath(roleID), nil, &role); err != nil {
+		return nil, err
+	}
+
+	return &role, nil
+}
+
+// CreateRole creates a role.
+func (c *Client) CreateRole(ctx context.Context, roleID string, req *RoleRequest) (*Role, error) {
+	var role Role
+	if err := c.Request(ctx, authService, http.MethodPut, rolePath(roleID), req, &role); err != nil {
+		return nil, err
+	}
+
+	return &role, nil
+}
+
+// UpdateRole replaces the description and scopes of a role.
+func (c *Client) UpdateRole(ctx context.Context, roleID string, req *RoleRequest) (*Role, error) {
+	var role Role
+	if err := c.Request(ctx, authService, http.MethodPost, rolePath(roleID), req, &role); err != nil {
+		return nil, err
+	}
+
+	return &role, nil
+}
+
+// DeleteRole deletes a role. Deleting a role which does not exist succeeds.
+func (c *Client) DeleteRole(ctx context.Context, roleID string) error {
+	return c.Request(ctx, authService, http.MethodDelete, rolePath(roleID), nil, nil)
+}
+
+// AuthClient is a client registered with the auth service.
+type AuthClient struct {
+	ClientID       string    `json:"clientId"`
+	Description    string    `json:"description"`
+	Expires        time.Time `json:"expires"`
+	Disabled       bool      `json:"disabled,omitempty"`
+	Scopes         []string  `json:"scopes"`
+	ExpandedScopes []string  `json:"expandedScopes,omitempty"`
+	Created        time.Time `json:"created,omitempty"`
+	LastModified   time.Time `json:"lastModified,omitempty"`
+	LastRotated    time.Time `json:"lastRotated,omitempty"`
+
+	// AccessToken is only returned when a client is created or its access
+	// token is reset.
+	AccessToken string `json:"accessToken,omitempty"`
+}
+
+// AuthClientRequest is the body used to create or update a client.
+type AuthClientRequest struct {
+	Description        string    `json:"description"`
+	Expires            time.Time `json:"expires"`
+	Scopes             []string  `json:"scopes"`
+	DeleteOnExpiration bool      `json:"deleteOnExpiration,omitempty"`
+}
+
+func authClientPath(clientID string) string {
+	return "/clients/" + url.PathEscape(clientID)
+}
+
+// AuthClient fetches a client.
+func (c *Client) AuthClient(ctx context.Context, clientID string) (*AuthClient, error) {
+	var client AuthClient
+	if err := c.Request(ctx, authService, http.MethodGet, authClientPath(clientID), nil, &client); err != nil {
+		return nil, err
+	}
+
+	return &client, nil
+}
+
+// CreateAuthClient creates a client. The returned client holds its access
+// token.
+func (c *Client) CreateAuthClient(ctx context.Context, clientID string, req *AuthClientRequest) (*AuthClient, error) {
+	var client AuthClient
+	if err := c.Request(ctx, authService, http.MethodPut, authClientPath(clientID), req, &client); err != nil {
+		return nil, err
+	}
+
+	return &client, nil
+}
+
+// UpdateAuthClient replaces the description, expiry and scopes of a client.
+func (c *Client) UpdateAuthClient(ctx context.Context, clientID string, req *AuthClientRequest) (*AuthClient, error) {
+	var client AuthClient
+	if err := c.Request(ctx, authService, http.MethodPost, authClientPath(clientID), req, &client); err != nil {
+		return nil, err
+	}
+
+	return &client, nil
+}
+
+// ResetAccessToken issues a new access token for a client, invalidating the
+// previous one.
+func (c *Client) ResetAccessToken(ctx context.Context, clientID string) (*AuthClient, error) {
+	var client AuthClient
+	if err := c.Request(ctx, authService, http.MethodPost, authClientPath(clientID)+"/reset", nil, &client); err != nil {
+		return nil, err
+	}
+
+	return &client, nil
+}
+
+// DeleteAuthClient deletes a client. Deleting a client which does not exist
+// succeeds.
+func (c *Client) DeleteAuthClient(ctx context.Context, clientID string) error {
+	return c.Request(ctx, authService, http.MethodDelete, authClientPath(clientID), nil, nil)
+}
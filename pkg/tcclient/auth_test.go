@@ -0,0 +1,78 @@
+package tcclient_test
+
+import (
+	"context"
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/wellplayedgames/taskcluster-operator/pkg/tcclient"
+	"github.com/wellplayedgames/taskcluster-operator/pkg/tcclient/tcclienttest"
+)
+
+var _ = Describe("Roles", func() {
+	ctx := context.Background()
+
+	var auth *tcclienttest.Auth
+	var client *tcclient.Client
+
+	BeforeEach(func() {
+		auth = tcclienttest.NewAuth(&tcclient.Credentials{
+			ClientID:    "static/taskcluster/root",
+			AccessToken: "secret-token",
+		})
+		client = tcclient.New(auth.URL+"/", auth.Credentials)
+	})
+
+	AfterEach(func() {
+		auth.Close()
+	})
+
+	It("should create, update and delete a role", func() {
+		roleID := "repo:github.com/org/*"
+
+		_, err := client.Role(ctx, roleID)
+		Expect(tcclient.IsNotFound(err)).To(BeTrue())
+
+		role, err := client.CreateRole(ctx, roleID, &tcclient.RoleRequest{
+			Description: "Org repos",
+			Scopes:      []string{"queue:create-task:*"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(role.ExpandedScopes).To(ContainElement("assume:" + roleID))
+
+		_, err = client.CreateRole(ctx, roleID, &tcclient.RoleRequest{})
+		Expect(tcclient.IsConflict(err)).To(BeTrue())
+
+		_, err = client.UpdateRole(ctx, roleID, &tcclient.RoleRequest{
+			Description: "Org repos",
+			Scopes:      []string{"secrets:get:*"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		role, err = client.Role(ctx, roleID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(role.Scopes).To(Equal([]string{"secrets:get:*"}))
+
+		Expect(client.DeleteRole(ctx, roleID)).To(Succeed())
+		Expect(auth.Role(roleID)).To(BeNil())
+		Expect(auth.Requests()).To(Equal([]string{
+			"GET /api/auth/v1/roles/repo:github.com%2Forg%2F%2A",
+			"PUT /api/auth/v1/roles/repo:github.com%2Forg%2F%2A",
+			"PUT /api/auth/v1/roles/repo:github.com%2Forg%2F%2A",
+			"POST /api/auth/v1/roles/repo:github.com%2Forg%2F%2A",
+			"GET /api/auth/v1/roles/repo:github.com%2Forg%2F%2A",
+			"DELETE /api/auth/v1/roles/repo:github.com%2Forg%2F%2A",
+		}))
+	})
+
+	It("should fail with the wrong access token", func() {
+		client.Credentials = &tcclient.Credentials{ClientID: auth.Credentials.ClientID, AccessToken: "wrong"}
+
+		_, err := client.Role(ctx, "anything")
+		Expect(err).To(HaveOccurred())
+		Expect(err.(*tcclient.APIError).StatusCode).To(Equal(http.StatusUnauthorized))
+		Expect(auth.Requests()).To(BeEmpty())
+	})
+})
@@ -0,0 +1,132 @@
+// Package tcclient is a small client for the TaskCluster REST APIs. Requests
+// are authenticated with Hawk using a client ID and access token.
+package tcclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Credentials authenticate requests as a TaskCluster client.
+type Credentials struct {
+	ClientID    string
+	AccessToken string
+}
+
+// Client calls the APIs of a TaskCluster deployment.
+type Client struct {
+	RootURL     string
+	Credentials *Credentials
+
+	HTTPClient *http.Client
+}
+
+// New returns a client for the deployment at rootURL.
+func New(rootURL string, credentials *Credentials) *Client {
+	return &Client{
+		RootURL:     strings.TrimSuffix(rootURL, "/"),
+		Credentials: credentials,
+	}
+}
+
+// APIError is returned when a TaskCluster API responds with an error.
+type APIError struct {
+	StatusCode int
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("taskcluster: %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// IsNotFound returns true if err is an API error for a missing resource.
+func IsNotFound(err error) bool {
+	return statusCode(err) == http.StatusNotFound
+}
+
+// IsConflict returns true if err is an API error for a resource which
+// already exists with different contents.
+func IsConflict(err error) bool {
+	return statusCode(err) == http.StatusConflict
+}
+
+func statusCode(err error) int {
+	if apiErr, ok := err.(*APIError); ok {
+		return apiErr.StatusCode
+	}
+
+	return 0
+}
+
+// Request calls method on path of the v1 API of a service, encoding in as
+// the JSON body if it is not nil and decoding the response into out if it
+// is not nil. Path segments must already be escaped.
+func (c *Client) Request(ctx context.Context, service, method, path string, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		body, err = json.Marshal(in)
+		if err != nil {
+			return err
+		}
+	}
+
+	url := fmt.Sprintf("%s/api/%s/v1%s", c.RootURL, service, path)
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+
+	contentType := ""
+	if in != nil {
+		contentType = "application/json"
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	if creds := c.Credentials; creds != nil {
+		auth, err := hawkHeader(creds, req, contentType, body, time.Now())
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", auth)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	by, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		if json.Unmarshal(by, apiErr) != nil || apiErr.Message == "" {
+			apiErr.Message = strings.TrimSpace(string(by))
+		}
+		return apiErr
+	}
+
+	if out == nil || len(by) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(by, out)
+}
@@ -0,0 +1,82 @@
+package tcclient
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// hawkHeader returns the Hawk Authorization header for a request.
+func hawkHeader(creds *Credentials, req *http.Request, contentType string, body []byte, now time.Time) (string, error) {
+	nonce := make([]byte, 8)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	return hawkHeaderWithNonce(creds, req, contentType, body, now, base64.RawURLEncoding.EncodeToString(nonce)), nil
+}
+
+func hawkHeaderWithNonce(creds *Credentials, req *http.Request, contentType string, body []byte, now time.Time, nonce string) string {
+	ts := strconv.FormatInt(now.Unix(), 10)
+
+	host := req.URL.Hostname()
+	port := req.URL.Port()
+	if port == "" {
+		port = "443"
+		if req.URL.Scheme == "http" {
+			port = "80"
+		}
+	}
+
+	resource := req.URL.EscapedPath()
+	if req.URL.RawQuery != "" {
+		resource += "?" + req.URL.RawQuery
+	}
+
+	hash := ""
+	if body != nil {
+		hash = hawkPayloadHash(contentType, body)
+	}
+
+	normalized := strings.Join([]string{
+		"hawk.1.header",
+		ts,
+		nonce,
+		strings.ToUpper(req.Method),
+		resource,
+		strings.ToLower(host),
+		port,
+		hash,
+		"",
+	}, "\n") + "\n"
+
+	mac := hmac.New(sha256.New, []byte(creds.AccessToken))
+	mac.Write([]byte(normalized))
+	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	header := fmt.Sprintf(`Hawk id="%s", ts="%s", nonce="%s"`, creds.ClientID, ts, nonce)
+	if hash != "" {
+		header += fmt.Sprintf(`, hash="%s"`, hash)
+	}
+	return header + fmt.Sprintf(`, mac="%s"`, signature)
+}
+
+func hawkPayloadHash(contentType string, body []byte) string {
+	if idx := strings.Index(contentType, ";"); idx >= 0 {
+		contentType = contentType[:idx]
+	}
+
+	h := sha256.New()
+	h.Write([]byte("hawk.1.payload\n"))
+	h.Write([]byte(strings.ToLower(strings.TrimSpace(contentType))))
+	h.Write([]byte("\n"))
+	h.Write(body)
+	h.Write([]byte("\n"))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
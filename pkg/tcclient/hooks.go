@@ -0,0 +1,115 @@
+package tcclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const hooksService = "hooks"
+
+// HookMetadata describes a hook.
+type HookMetadata struct {
+	Name         string `json:"name"`
+	Description  string `json:"description"`
+	Owner        string `json:"owner"`
+	EmailOnError bool   `json:"emailOnError"`
+}
+
+// HookBinding is a pulse binding which triggers a hook.
+type HookBinding struct {
+	Exchange          string `json:"exchange"`
+	RoutingKeyPattern string `json:"routingKeyPattern"`
+}
+
+// Hook is the definition of a hook.
+type Hook struct {
+	HookGroupID   string          `json:"hookGroupId,omitempty"`
+	HookID        string          `json:"hookId,omitempty"`
+	Metadata      HookMetadata    `json:"metadata"`
+	Schedule      []string        `json:"schedule"`
+	Task          json.RawMessage `json:"task"`
+	TriggerSchema json.RawMessage `json:"triggerSchema"`
+	Bindings      []HookBinding   `json:"bindings"`
+}
+
+// HookLastFire describes the last time a hook fired.
+type HookLastFire struct {
+	Result string    `json:"result"`
+	TaskID string    `json:"taskId,omitempty"`
+	Time   time.Time `json:"time,omitempty"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// HookStatus describes when a hook last fired and will next fire.
+type HookStatus struct {
+	LastFire          HookLastFire `json:"lastFire"`
+	NextScheduledDate *time.Time   `json:"nextScheduledDate,omitempty"`
+}
+
+func hookPath(hookGroupID, hookID string) string {
+	return "/hooks/" + url.PathEscape(hookGroupID) + "/" + url.PathEscape(hookID)
+}
+
+// Hook fetches the definition of a hook.
+func (c *Client) Hook(ctx context.Context, hookGroupID, hookID string) (*Hook, error) {
+	var hook Hook
+	if err := c.Request(ctx, hooksService, http.MethodGet, hookPath(hookGroupID, hookID), nil, &hook); err != nil {
+		return nil, err
+	}
+
+	return &hook, nil
+}
+
+// CreateHook creates a hook.
+func (c *Client) CreateHook(ctx context.Context, hookGroupID, hookID string, hook *Hook) (*Hook, error) {
+	var result Hook
+	if err := c.Request(ctx, hooksService, http.MethodPut, hookPath(hookGroupID, hookID), hook, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// UpdateHook replaces the definition of a hook.
+func (c *Client) UpdateHook(ctx context.Context, hookGroupID, hookID string, hook *Hook) (*Hook, error) {
+	var result Hook
+	if err := c.Request(ctx, hooksService, http.MethodPost, hookPath(hookGroupID, hookID), hook, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// DeleteHook deletes a hook.
+func (c *Client) DeleteHook(ctx context.Context, hookGroupID, hookID string) error {
+	return c.Request(ctx, hooksService, http.MethodDelete, hookPath(hookGroupID, hookID), nil, nil)
+}
+
+// HookStatus fetches when a hook last fired and will next fire.
+func (c *Client) HookStatus(ctx context.Context, hookGroupID, hookID string) (*HookStatus, error) {
+	var status HookStatus
+	if err := c.Request(ctx, hooksService, http.MethodGet, hookPath(hookGroupID, hookID)+"/status", nil, &status); err != nil {
+		return nil, err
+	}
+
+	return &status, nil
+}
+
+// TriggerHook fires a hook with the given payload and returns the ID of the
+// task created, if any.
+func (c *Client) TriggerHook(ctx context.Context, hookGroupID, hookID string, payload interface{}) (string, error) {
+	var resp struct {
+		Status struct {
+			TaskID string `json:"taskId"`
+		} `json:"status"`
+	}
+
+	if err := c.Request(ctx, hooksService, http.MethodPost, hookPath(hookGroupID, hookID)+"/trigger", payload, &resp); err != nil {
+		return "", err
+	}
+
+	return resp.Status.TaskID, nil
+}
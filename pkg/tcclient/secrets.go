@@ -0,0 +1,40 @@
+package tcclient
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const secretsService = "secrets"
+
+// Secret is a secret stored in the TaskCluster secrets service.
+type Secret struct {
+	Secret  map[string]interface{} `json:"secret"`
+	Expires time.Time              `json:"expires"`
+}
+
+func secretPath(name string) string {
+	return "/secret/" + url.PathEscape(name)
+}
+
+// Secret fetches a secret.
+func (c *Client) Secret(ctx context.Context, name string) (*Secret, error) {
+	var secret Secret
+	if err := c.Request(ctx, secretsService, http.MethodGet, secretPath(name), nil, &secret); err != nil {
+		return nil, err
+	}
+
+	return &secret, nil
+}
+
+// SetSecret creates or replaces a secret.
+func (c *Client) SetSecret(ctx context.Context, name string, secret *Secret) error {
+	return c.Request(ctx, secretsService, http.MethodPut, secretPath(name), secret, nil)
+}
+
+// RemoveSecret deletes a secret.
+func (c *Client) RemoveSecret(ctx context.Context, name string) error {
+	return c.Request(ctx, secretsService, http.MethodDelete, secretPath(name), nil, nil)
+}
@@ -0,0 +1,204 @@
+package tcclient
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var testCredentials = &Credentials{
+	ClientID:    "static/taskcluster/root",
+	AccessToken: "secret-token",
+}
+
+var _ = Describe("Hawk", func() {
+	It("should hash payloads as described by the Hawk specification", func() {
+		Expect(hawkPayloadHash("text/plain", []byte("Thank you for flying Hawk"))).
+			To(Equal("Yi9LfIIFRtBEPt74PVmbTF/xVAwPn7ub15ePICfgnuY="))
+	})
+
+	It("should sign requests as described by the Hawk specification", func() {
+		// The specification's example POST, without the ext attribute which
+		// TaskCluster does not use.
+		creds := &Credentials{
+			ClientID:    "dh37fgj492je",
+			AccessToken: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn",
+		}
+		req, err := http.NewRequest(http.MethodPost, "http://example.com:8000/resource/1?b=1&a=2", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		header := hawkHeaderWithNonce(creds, req, "text/plain", []byte("Thank you for flying Hawk"), time.Unix(1353832234, 0), "j4h3g2")
+		Expect(header).To(Equal(`Hawk id="dh37fgj492je", ts="1353832234", nonce="j4h3g2", ` +
+			`hash="Yi9LfIIFRtBEPt74PVmbTF/xVAwPn7ub15ePICfgnuY=", mac="xMQacUaeJiezHpLu67V4Zc90BK53KGSS4VNYp2M3E3o="`))
+	})
+})
+
+var _ = Describe("WorkerPools", func() {
+	ctx := context.Background()
+
+	It("should escape worker pool IDs and decode errors", func() {
+		var paths []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			paths = append(paths, r.URL.EscapedPath())
+			if strings.HasPrefix(r.URL.Path, "/api/worker-manager/v1/worker-pool-errors/") {
+				w.Write([]byte(`{"workerPoolErrors":[{"errorId":"e1","kind":"creation-error","title":"Quota exceeded"}]}`))
+				return
+			}
+
+			w.Write([]byte(`{"workerPoolId":"proj/ci","providerId":"gcp","config":{"maxCapacity":10},"currentCapacity":3}`))
+		}))
+		defer server.Close()
+
+		client := New(server.URL, testCredentials)
+
+		pool, err := client.WorkerPool(ctx, "proj/ci")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pool.CurrentCapacity).To(Equal(3))
+		Expect(pool.Config).To(MatchJSON(`{"maxCapacity":10}`))
+
+		errors, err := client.WorkerPoolErrors(ctx, "proj/ci")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(errors).To(HaveLen(1))
+		Expect(errors[0].Title).To(Equal("Quota exceeded"))
+
+		Expect(paths).To(Equal([]string{
+			"/api/worker-manager/v1/worker-pool/proj%2Fci",
+			"/api/worker-manager/v1/worker-pool-errors/proj%2Fci",
+		}))
+	})
+})
+
+var _ = Describe("Hooks", func() {
+	ctx := context.Background()
+
+	It("should trigger a hook and read its status", func() {
+		var triggered map[string]interface{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.EscapedPath() {
+			case "/api/hooks/v1/hooks/project-ci/nightly/trigger":
+				json.NewDecoder(r.Body).Decode(&triggered)
+				w.Write([]byte(`{"status":{"taskId":"abc123"}}`))
+			case "/api/hooks/v1/hooks/project-ci/nightly/status":
+				w.Write([]byte(`{"lastFire":{"result":"success","taskId":"abc123","time":"2020-06-01T00:00:00Z"},"nextScheduledDate":"2020-06-02T00:00:00Z"}`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		defer server.Close()
+
+		client := New(server.URL, testCredentials)
+
+		taskID, err := client.TriggerHook(ctx, "project-ci", "nightly", map[string]interface{}{"reason": "manual"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(taskID).To(Equal("abc123"))
+		Expect(triggered).To(HaveKeyWithValue("reason", "manual"))
+
+		status, err := client.HookStatus(ctx, "project-ci", "nightly")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.LastFire.Result).To(Equal("success"))
+		Expect(status.NextScheduledDate).NotTo(BeNil())
+	})
+})
+
+var _ = Describe("Secrets", func() {
+	ctx := context.Background()
+
+	It("should write, read and remove a secret", func() {
+		secrets := map[string][]byte{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path := r.URL.EscapedPath()
+			switch r.Method {
+			case http.MethodPut:
+				body, _ := ioutil.ReadAll(r.Body)
+				secrets[path] = body
+				w.Write([]byte(`{}`))
+			case http.MethodGet:
+				if body, ok := secrets[path]; ok {
+					w.Write(body)
+					return
+				}
+				w.WriteHeader(http.StatusNotFound)
+			case http.MethodDelete:
+				delete(secrets, path)
+				w.Write([]byte(`{}`))
+			}
+		}))
+		defer server.Close()
+
+		client := New(server.URL, testCredentials)
+		expires := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		err := client.SetSecret(ctx, "project/my-org/deploy", &Secret{
+			Secret:  map[string]interface{}{"sshKey": "key"},
+			Expires: expires,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(secrets).To(HaveKey("/api/secrets/v1/secret/project%2Fmy-org%2Fdeploy"))
+
+		secret, err := client.Secret(ctx, "project/my-org/deploy")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(secret.Secret).To(HaveKeyWithValue("sshKey", "key"))
+		Expect(secret.Expires).To(BeTemporally("==", expires))
+
+		Expect(client.RemoveSecret(ctx, "project/my-org/deploy")).To(Succeed())
+		_, err = client.Secret(ctx, "project/my-org/deploy")
+		Expect(IsNotFound(err)).To(BeTrue())
+	})
+})
+
+var _ = Describe("AuthClients", func() {
+	ctx := context.Background()
+
+	It("should create a client and reset its access token", func() {
+		var created AuthClientRequest
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method + " " + r.URL.EscapedPath() {
+			case "PUT /api/auth/v1/clients/project%2Fci%2Fdeploy":
+				json.NewDecoder(r.Body).Decode(&created)
+				w.Write([]byte(`{"clientId":"project/ci/deploy","accessToken":"first"}`))
+			case "POST /api/auth/v1/clients/project%2Fci%2Fdeploy/reset":
+				w.Write([]byte(`{"clientId":"project/ci/deploy","accessToken":"second"}`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		defer server.Close()
+
+		client := New(server.URL, testCredentials)
+
+		authClient, err := client.CreateAuthClient(ctx, "project/ci/deploy", &AuthClientRequest{
+			Description: "Deploys",
+			Scopes:      []string{"secrets:get:project/ci/*"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(authClient.AccessToken).To(Equal("first"))
+		Expect(created.Scopes).To(ConsistOf("secrets:get:project/ci/*"))
+
+		authClient, err = client.ResetAccessToken(ctx, "project/ci/deploy")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(authClient.AccessToken).To(Equal("second"))
+	})
+})
+
+var _ = Describe("Validation", func() {
+	It("should validate client IDs", func() {
+		Expect(ValidateClientID("project/ci/deploy")).To(Succeed())
+		Expect(ValidateClientID("static/taskcluster/root")).To(Succeed())
+		Expect(ValidateClientID("")).NotTo(Succeed())
+		Expect(ValidateClientID("project ci")).NotTo(Succeed())
+	})
+
+	It("should validate scopes", func() {
+		Expect(ValidateScope("secrets:get:project/ci/*")).To(Succeed())
+		Expect(ValidateScope("")).NotTo(Succeed())
+		Expect(ValidateScope("queue:create-task:é")).NotTo(Succeed())
+		Expect(ValidateScope("line\nbreak")).NotTo(Succeed())
+	})
+})
@@ -0,0 +1,372 @@
+// Package tcclienttest provides a local stand-in for the TaskCluster auth,
+// hooks and secrets services, for testing code which uses tcclient.
+package tcclienttest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/wellplayedgames/taskcluster-operator/pkg/tcclient"
+)
+
+var hawkAttribute = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// Auth is a fake auth service which keeps roles and clients in memory. It
+// also serves the hooks and secrets services. Requests must be signed with
+// Credentials.
+type Auth struct {
+	*httptest.Server
+	Credentials *tcclient.Credentials
+
+	mu           sync.Mutex
+	roles        map[string]*tcclient.Role
+	clients      map[string]*tcclient.AuthClient
+	hooks        map[string]*tcclient.Hook
+	hookStatuses map[string]*tcclient.HookStatus
+	secrets      map[string]*tcclient.Secret
+	requests     []string
+	tokens       int
+	tasks        int
+}
+
+// NewAuth starts a fake auth service accepting the given credentials.
+func NewAuth(credentials *tcclient.Credentials) *Auth {
+	a := &Auth{
+		Credentials:  credentials,
+		roles:        map[string]*tcclient.Role{},
+		clients:      map[string]*tcclient.AuthClient{},
+		hooks:        map[string]*tcclient.Hook{},
+		hookStatuses: map[string]*tcclient.HookStatus{},
+		secrets:      map[string]*tcclient.Secret{},
+	}
+	a.Server = httptest.NewServer(a)
+	return a
+}
+
+// Client returns a client for the fake service using its credentials.
+func (a *Auth) Client() *tcclient.Client {
+	return tcclient.New(a.URL, a.Credentials)
+}
+
+// Role returns a copy of a role, or nil if it does not exist.
+func (a *Auth) Role(roleID string) *tcclient.Role {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if role, ok := a.roles[roleID]; ok {
+		copy := *role
+		return &copy
+	}
+
+	return nil
+}
+
+// SetRole replaces a role, as if it had been changed outside the operator.
+func (a *Auth) SetRole(role tcclient.Role) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.roles[role.RoleID] = &role
+}
+
+// AuthClient returns a copy of a client including its current access token,
+// or nil if it does not exist.
+func (a *Auth) AuthClient(clientID string) *tcclient.AuthClient {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if client, ok := a.clients[clientID]; ok {
+		copy := *client
+		return &copy
+	}
+
+	return nil
+}
+
+// SetAuthClient replaces a client, as if it had been changed outside the
+// operator.
+func (a *Auth) SetAuthClient(client tcclient.AuthClient) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.clients[client.ClientID] = &client
+}
+
+// Requests returns the method and escaped path of every authenticated
+// request served, and clears the list.
+func (a *Auth) Requests() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	requests := a.requests
+	a.requests = nil
+	return requests
+}
+
+func (a *Auth) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	body, _ := ioutil.ReadAll(r.Body)
+	if err := a.verify(r, body); err != nil {
+		writeError(w, http.StatusUnauthorized, "AuthenticationFailed", err.Error())
+		return
+	}
+
+	path := r.URL.EscapedPath()
+	a.requests = append(a.requests, r.Method+" "+path)
+
+	const (
+		rolesPrefix   = "/api/auth/v1/roles/"
+		clientsPrefix = "/api/auth/v1/clients/"
+		hooksPrefix   = "/api/hooks/v1/hooks/"
+		secretsPrefix = "/api/secrets/v1/secret/"
+	)
+
+	switch {
+	case strings.HasPrefix(path, rolesPrefix):
+		id, err := url.PathUnescape(strings.TrimPrefix(path, rolesPrefix))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "InvalidRequestArguments", err.Error())
+			return
+		}
+		a.serveRole(w, r.Method, id, body)
+
+	case strings.HasPrefix(path, clientsPrefix):
+		rest := strings.TrimPrefix(path, clientsPrefix)
+		reset := strings.HasSuffix(rest, "/reset")
+		id, err := url.PathUnescape(strings.TrimSuffix(rest, "/reset"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "InvalidRequestArguments", err.Error())
+			return
+		}
+		a.serveClient(w, r.Method, id, reset, body)
+
+	case strings.HasPrefix(path, hooksPrefix):
+		parts := strings.Split(strings.TrimPrefix(path, hooksPrefix), "/")
+		if len(parts) < 2 || len(parts) > 3 {
+			writeError(w, http.StatusNotFound, "ResourceNotFound", "no such endpoint")
+			return
+		}
+		for idx, part := range parts {
+			unescaped, err := url.PathUnescape(part)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "InvalidRequestArguments", err.Error())
+				return
+			}
+			parts[idx] = unescaped
+		}
+		action := ""
+		if len(parts) == 3 {
+			action = parts[2]
+		}
+		a.serveHook(w, r.Method, parts[0], parts[1], action, body)
+
+	case strings.HasPrefix(path, secretsPrefix):
+		name, err := url.PathUnescape(strings.TrimPrefix(path, secretsPrefix))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "InvalidRequestArguments", err.Error())
+			return
+		}
+		a.serveSecret(w, r.Method, name, body)
+
+	default:
+		writeError(w, http.StatusNotFound, "ResourceNotFound", "no such endpoint")
+	}
+}
+
+func (a *Auth) serveRole(w http.ResponseWriter, method, roleID string, body []byte) {
+	existing := a.roles[roleID]
+
+	switch method {
+	case http.MethodGet:
+		if existing == nil {
+			writeError(w, http.StatusNotFound, "ResourceNotFound", "role not found")
+			return
+		}
+		json.NewEncoder(w).Encode(existing)
+
+	case http.MethodPut, http.MethodPost:
+		if method == http.MethodPut && existing != nil {
+			writeError(w, http.StatusConflict, "RequestConflict", "role exists")
+			return
+		}
+		if method == http.MethodPost && existing == nil {
+			writeError(w, http.StatusNotFound, "ResourceNotFound", "role not found")
+			return
+		}
+
+		var req tcclient.RoleRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			writeError(w, http.StatusBadRequest, "InvalidRequestArguments", err.Error())
+			return
+		}
+
+		role := &tcclient.Role{
+			RoleID:         roleID,
+			Description:    req.Description,
+			Scopes:         req.Scopes,
+			ExpandedScopes: append([]string{"assume:" + roleID}, req.Scopes...),
+		}
+		a.roles[roleID] = role
+		json.NewEncoder(w).Encode(role)
+
+	case http.MethodDelete:
+		delete(a.roles, roleID)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "InvalidRequestArguments", "unsupported method")
+	}
+}
+
+func (a *Auth) serveClient(w http.ResponseWriter, method, clientID string, reset bool, body []byte) {
+	existing := a.clients[clientID]
+
+	if reset {
+		if method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "InvalidRequestArguments", "unsupported method")
+			return
+		}
+		if existing == nil {
+			writeError(w, http.StatusNotFound, "ResourceNotFound", "client not found")
+			return
+		}
+
+		existing.AccessToken = a.newAccessToken()
+		json.NewEncoder(w).Encode(existing)
+		return
+	}
+
+	switch method {
+	case http.MethodGet:
+		if existing == nil {
+			writeError(w, http.StatusNotFound, "ResourceNotFound", "client not found")
+			return
+		}
+
+		// Access tokens are only returned when they are issued.
+		client := *existing
+		client.AccessToken = ""
+		json.NewEncoder(w).Encode(&client)
+
+	case http.MethodPut, http.MethodPost:
+		if method == http.MethodPut && existing != nil {
+			writeError(w, http.StatusConflict, "RequestConflict", "client exists")
+			return
+		}
+		if method == http.MethodPost && existing == nil {
+			writeError(w, http.StatusNotFound, "ResourceNotFound", "client not found")
+			return
+		}
+
+		var req tcclient.AuthClientRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			writeError(w, http.StatusBadRequest, "InvalidRequestArguments", err.Error())
+			return
+		}
+
+		client := &tcclient.AuthClient{
+			ClientID:    clientID,
+			Description: req.Description,
+			Expires:     req.Expires,
+			Scopes:      req.Scopes,
+		}
+		if existing != nil {
+			client.AccessToken = existing.AccessToken
+		} else {
+			client.AccessToken = a.newAccessToken()
+		}
+		a.clients[clientID] = client
+
+		response := *client
+		if method == http.MethodPost {
+			response.AccessToken = ""
+		}
+		json.NewEncoder(w).Encode(&response)
+
+	case http.MethodDelete:
+		delete(a.clients, clientID)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "InvalidRequestArguments", "unsupported method")
+	}
+}
+
+func (a *Auth) newAccessToken() string {
+	a.tokens++
+	return fmt.Sprintf("token-%d", a.tokens)
+}
+
+// verify checks the Hawk signature of a request as described by the Hawk
+// specification, independently of the signing code in tcclient.
+func (a *Auth) verify(r *http.Request, body []byte) error {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Hawk ") {
+		return fmt.Errorf("missing Hawk authorization")
+	}
+
+	attrs := map[string]string{}
+	for _, m := range hawkAttribute.FindAllStringSubmatch(header, -1) {
+		attrs[m[1]] = m[2]
+	}
+
+	if attrs["id"] != a.Credentials.ClientID {
+		return fmt.Errorf("unknown client %q", attrs["id"])
+	}
+
+	if len(body) > 0 {
+		contentType := strings.TrimSpace(strings.SplitN(r.Header.Get("Content-Type"), ";", 2)[0])
+		payload := sha256.Sum256([]byte("hawk.1.payload\n" + strings.ToLower(contentType) + "\n" + string(body) + "\n"))
+		if attrs["hash"] != base64.StdEncoding.EncodeToString(payload[:]) {
+			return fmt.Errorf("payload hash mismatch")
+		}
+	}
+
+	host, port, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		return err
+	}
+
+	resource := r.URL.EscapedPath()
+	if r.URL.RawQuery != "" {
+		resource += "?" + r.URL.RawQuery
+	}
+
+	normalized := "hawk.1.header\n" +
+		attrs["ts"] + "\n" +
+		attrs["nonce"] + "\n" +
+		r.Method + "\n" +
+		resource + "\n" +
+		strings.ToLower(host) + "\n" +
+		port + "\n" +
+		attrs["hash"] + "\n" +
+		attrs["ext"] + "\n"
+
+	mac := hmac.New(sha256.New, []byte(a.Credentials.AccessToken))
+	mac.Write([]byte(normalized))
+	expected := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(attrs["mac"])) {
+		return fmt.Errorf("bad signature")
+	}
+
+	return nil
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"code": code, "message": message})
+}
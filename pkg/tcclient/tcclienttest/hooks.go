@@ -0,0 +1,116 @@
+package tcclienttest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/wellplayedgames/taskcluster-operator/pkg/tcclient"
+)
+
+func hookKey(hookGroupID, hookID string) string {
+	return hookGroupID + "/" + hookID
+}
+
+// Hook returns a copy of a hook, or nil if it does not exist.
+func (a *Auth) Hook(hookGroupID, hookID string) *tcclient.Hook {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if hook, ok := a.hooks[hookKey(hookGroupID, hookID)]; ok {
+		copy := *hook
+		return &copy
+	}
+
+	return nil
+}
+
+// SetHook replaces a hook, as if it had been changed outside the operator.
+func (a *Auth) SetHook(hook tcclient.Hook) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.hooks[hookKey(hook.HookGroupID, hook.HookID)] = &hook
+}
+
+// SetHookStatus sets the status reported for a hook.
+func (a *Auth) SetHookStatus(hookGroupID, hookID string, status tcclient.HookStatus) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.hookStatuses[hookKey(hookGroupID, hookID)] = &status
+}
+
+func (a *Auth) serveHook(w http.ResponseWriter, method, hookGroupID, hookID, action string, body []byte) {
+	key := hookKey(hookGroupID, hookID)
+	existing := a.hooks[key]
+
+	switch {
+	case action == "status" && method == http.MethodGet:
+		if existing == nil {
+			writeError(w, http.StatusNotFound, "ResourceNotFound", "hook not found")
+			return
+		}
+
+		status := a.hookStatuses[key]
+		if status == nil {
+			status = &tcclient.HookStatus{LastFire: tcclient.HookLastFire{Result: "no-fire"}}
+		}
+		json.NewEncoder(w).Encode(status)
+
+	case action == "trigger" && method == http.MethodPost:
+		if existing == nil {
+			writeError(w, http.StatusNotFound, "ResourceNotFound", "hook not found")
+			return
+		}
+
+		a.tasks++
+		taskID := fmt.Sprintf("task-%d", a.tasks)
+		a.hookStatuses[key] = &tcclient.HookStatus{
+			LastFire: tcclient.HookLastFire{Result: "success", TaskID: taskID, Time: time.Now().UTC()},
+		}
+
+		response := map[string]interface{}{"status": map[string]string{"taskId": taskID}}
+		json.NewEncoder(w).Encode(response)
+
+	case action != "":
+		writeError(w, http.StatusNotFound, "ResourceNotFound", "no such endpoint")
+
+	case method == http.MethodGet:
+		if existing == nil {
+			writeError(w, http.StatusNotFound, "ResourceNotFound", "hook not found")
+			return
+		}
+		json.NewEncoder(w).Encode(existing)
+
+	case method == http.MethodPut || method == http.MethodPost:
+		if method == http.MethodPut && existing != nil {
+			writeError(w, http.StatusConflict, "RequestConflict", "hook exists")
+			return
+		}
+		if method == http.MethodPost && existing == nil {
+			writeError(w, http.StatusNotFound, "ResourceNotFound", "hook not found")
+			return
+		}
+
+		var hook tcclient.Hook
+		if err := json.Unmarshal(body, &hook); err != nil {
+			writeError(w, http.StatusBadRequest, "InvalidRequestArguments", err.Error())
+			return
+		}
+
+		hook.HookGroupID = hookGroupID
+		hook.HookID = hookID
+		a.hooks[key] = &hook
+		json.NewEncoder(w).Encode(&hook)
+
+	case method == http.MethodDelete:
+		delete(a.hooks, key)
+		delete(a.hookStatuses, key)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "InvalidRequestArguments", "unsupported method")
+	}
+}
@@ -0,0 +1,65 @@
+package tcclienttest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/wellplayedgames/taskcluster-operator/pkg/tcclient"
+)
+
+// Secret returns a copy of a secret, or nil if it does not exist.
+func (a *Auth) Secret(name string) *tcclient.Secret {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if secret, ok := a.secrets[name]; ok {
+		copy := *secret
+		return &copy
+	}
+
+	return nil
+}
+
+// SetSecret replaces a secret, as if it had been changed outside the
+// operator.
+func (a *Auth) SetSecret(name string, secret tcclient.Secret) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.secrets[name] = &secret
+}
+
+func (a *Auth) serveSecret(w http.ResponseWriter, method, name string, body []byte) {
+	existing := a.secrets[name]
+
+	switch method {
+	case http.MethodGet:
+		if existing == nil {
+			writeError(w, http.StatusNotFound, "ResourceNotFound", "secret not found")
+			return
+		}
+		json.NewEncoder(w).Encode(existing)
+
+	case http.MethodPut:
+		var secret tcclient.Secret
+		if err := json.Unmarshal(body, &secret); err != nil {
+			writeError(w, http.StatusBadRequest, "InvalidRequestArguments", err.Error())
+			return
+		}
+
+		a.secrets[name] = &secret
+		w.Write([]byte("{}"))
+
+	case http.MethodDelete:
+		if existing == nil {
+			writeError(w, http.StatusNotFound, "ResourceNotFound", "secret not found")
+			return
+		}
+
+		delete(a.secrets, name)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "InvalidRequestArguments", "unsupported method")
+	}
+}
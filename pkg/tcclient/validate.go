@@ -0,0 +1,31 @@
+package tcclient
+
+import (
+	"fmt"
+	"regexp"
+)
+
+var (
+	clientIDPattern = regexp.MustCompile(`^[A-Za-z0-9!@/:.+|_-]+$`)
+	scopePattern    = regexp.MustCompile(`^[\x20-\x7e]+$`)
+)
+
+// ValidateClientID returns an error if clientID is not accepted by the auth
+// service.
+func ValidateClientID(clientID string) error {
+	if !clientIDPattern.MatchString(clientID) {
+		return fmt.Errorf("invalid client ID %q: must only contain letters, digits and !@/:.+|_-", clientID)
+	}
+
+	return nil
+}
+
+// ValidateScope returns an error if scope is not a valid TaskCluster scope.
+// Scopes are non-empty strings of printable ASCII characters.
+func ValidateScope(scope string) error {
+	if !scopePattern.MatchString(scope) {
+		return fmt.Errorf("invalid scope %q: must be non-empty printable ASCII", scope)
+	}
+
+	return nil
+}
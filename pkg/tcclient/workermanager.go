@@ -0,0 +1,100 @@
+package tcclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const workerManagerService = "worker-manager"
+
+// WorkerPool is a worker-manager worker pool.
+type WorkerPool struct {
+	WorkerPoolID string          `json:"workerPoolId"`
+	ProviderID   string          `json:"providerId"`
+	Description  string          `json:"description"`
+	Config       json.RawMessage `json:"config"`
+	Owner        string          `json:"owner"`
+	EmailOnError bool            `json:"emailOnError"`
+	Created      time.Time       `json:"created,omitempty"`
+	LastModified time.Time       `json:"lastModified,omitempty"`
+
+	CurrentCapacity   int `json:"currentCapacity,omitempty"`
+	RequestedCapacity int `json:"requestedCapacity,omitempty"`
+	RunningCapacity   int `json:"runningCapacity,omitempty"`
+	StoppingCapacity  int `json:"stoppingCapacity,omitempty"`
+}
+
+// WorkerPoolRequest is the body used to create or update a worker pool.
+type WorkerPoolRequest struct {
+	ProviderID   string          `json:"providerId"`
+	Description  string          `json:"description"`
+	Config       json.RawMessage `json:"config"`
+	Owner        string          `json:"owner"`
+	EmailOnError bool            `json:"emailOnError"`
+}
+
+// WorkerPoolError is an error reported while provisioning a worker pool.
+type WorkerPoolError struct {
+	ErrorID     string    `json:"errorId"`
+	Reported    time.Time `json:"reported"`
+	Kind        string    `json:"kind"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+}
+
+func workerPoolPath(workerPoolID string) string {
+	return "/worker-pool/" + url.PathEscape(workerPoolID)
+}
+
+// WorkerPool fetches a worker pool.
+func (c *Client) WorkerPool(ctx context.Context, workerPoolID string) (*WorkerPool, error) {
+	var pool WorkerPool
+	if err := c.Request(ctx, workerManagerService, http.MethodGet, workerPoolPath(workerPoolID), nil, &pool); err != nil {
+		return nil, err
+	}
+
+	return &pool, nil
+}
+
+// CreateWorkerPool creates a worker pool.
+func (c *Client) CreateWorkerPool(ctx context.Context, workerPoolID string, req *WorkerPoolRequest) (*WorkerPool, error) {
+	var pool WorkerPool
+	if err := c.Request(ctx, workerManagerService, http.MethodPut, workerPoolPath(workerPoolID), req, &pool); err != nil {
+		return nil, err
+	}
+
+	return &pool, nil
+}
+
+// UpdateWorkerPool replaces the definition of a worker pool.
+func (c *Client) UpdateWorkerPool(ctx context.Context, workerPoolID string, req *WorkerPoolRequest) (*WorkerPool, error) {
+	var pool WorkerPool
+	if err := c.Request(ctx, workerManagerService, http.MethodPost, workerPoolPath(workerPoolID), req, &pool); err != nil {
+		return nil, err
+	}
+
+	return &pool, nil
+}
+
+// DeleteWorkerPool marks a worker pool for deletion. worker-manager removes
+// it once all of its workers have stopped.
+func (c *Client) DeleteWorkerPool(ctx context.Context, workerPoolID string) error {
+	return c.Request(ctx, workerManagerService, http.MethodDelete, workerPoolPath(workerPoolID), nil, nil)
+}
+
+// WorkerPoolErrors lists the errors recently reported for a worker pool.
+func (c *Client) WorkerPoolErrors(ctx context.Context, workerPoolID string) ([]WorkerPoolError, error) {
+	var resp struct {
+		WorkerPoolErrors []WorkerPoolError `json:"workerPoolErrors"`
+	}
+
+	path := "/worker-pool-errors/" + url.PathEscape(workerPoolID)
+	if err := c.Request(ctx, workerManagerService, http.MethodGet, path, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.WorkerPoolErrors, nil
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2020 Well Played Games Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/wellplayedgames/taskcluster-operator/controllers"
+	"github.com/wellplayedgames/taskcluster-operator/pkg/envelope"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const stateUsage = `usage: manager state <export|import> [flags]
+
+Export or import the generated state of an Instance, which includes every
+generated password, crypto key and access token.
+`
+
+// runStateCommand implements the `state` subcommand.
+func runStateCommand(args []string) error {
+	if len(args) < 1 {
+		return errors.New(stateUsage)
+	}
+
+	cmd := args[0]
+	flags := flag.NewFlagSet("state "+cmd, flag.ExitOnError)
+
+	var namespace, name, keyFile, path string
+	var force bool
+	flags.StringVar(&namespace, "namespace", "default", "The namespace of the Instance")
+	flags.StringVar(&name, "name", "", "The name of the Instance")
+	flags.StringVar(&keyFile, "key-file", "", "A file containing a base64 encoded 32 byte key used to encrypt the bundle")
+	flags.StringVar(&path, "file", "-", "The bundle file to write or read")
+	if cmd == "import" {
+		flags.BoolVar(&force, "force", false, "Replace existing state")
+	}
+
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	if name == "" {
+		return fmt.Errorf("-name is required")
+	}
+
+	var bundleKeys envelope.KeyProvider
+	if keyFile != "" {
+		by, err := ioutil.ReadFile(keyFile)
+		if err != nil {
+			return err
+		}
+
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(by)))
+		if err != nil {
+			return fmt.Errorf("key file is not valid base64: %w", err)
+		}
+
+		bundleKeys, err = envelope.NewLocalKeyProvider(key)
+		if err != nil {
+			return err
+		}
+	}
+
+	c, err := client.New(ctrl.GetConfigOrDie(), client.Options{Scheme: scheme})
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	logger := ctrl.Log.WithName("state")
+	instanceName := types.NamespacedName{Namespace: namespace, Name: name}
+
+	switch cmd {
+	case "export":
+		data, err := controllers.ExportState(ctx, logger, c, instanceName, bundleKeys)
+		if err != nil {
+			return err
+		}
+
+		if path == "-" {
+			_, err = os.Stdout.Write(data)
+			return err
+		}
+
+		return ioutil.WriteFile(path, data, 0600)
+
+	case "import":
+		var data []byte
+		if path == "-" {
+			data, err = ioutil.ReadAll(os.Stdin)
+		} else {
+			data, err = ioutil.ReadFile(path)
+		}
+		if err != nil {
+			return err
+		}
+
+		return controllers.ImportState(ctx, logger, c, instanceName, data, bundleKeys, force)
+	}
+
+	return errors.New(stateUsage)
+}